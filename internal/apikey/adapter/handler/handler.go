@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/core-go/core"
+	"github.com/gorilla/mux"
+
+	"go-service/internal/apikey/domain"
+	"go-service/internal/apikey/service"
+	"go-service/pkg/problem"
+)
+
+const InternalServerError = "Internal Server Error"
+
+func NewApiKeyHandler(service service.ApiKeyService, validate func(context.Context, interface{}) ([]core.ErrorMessage, error), logError func(context.Context, string, ...map[string]interface{})) *ApiKeyHandler {
+	return &ApiKeyHandler{service: service, Validate: validate, LogError: logError}
+}
+
+type ApiKeyHandler struct {
+	service  service.ApiKeyService
+	Validate func(context.Context, interface{}) ([]core.ErrorMessage, error)
+	LogError func(context.Context, string, ...map[string]interface{})
+}
+
+func (h *ApiKeyHandler) All(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.All(r.Context())
+	if err != nil {
+		h.LogError(r.Context(), err.Error())
+		problem.Error(w, r, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	if keys == nil {
+		keys = []domain.ApiKey{}
+	}
+	JSON(w, http.StatusOK, keys)
+}
+func (h *ApiKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var key domain.ApiKey
+	er1 := json.NewDecoder(r.Body).Decode(&key)
+	defer r.Body.Close()
+	if er1 != nil {
+		problem.Error(w, r, er1.Error(), http.StatusBadRequest)
+		return
+	}
+	errors, er2 := h.Validate(r.Context(), &key)
+	if er2 != nil {
+		h.LogError(r.Context(), er2.Error())
+		problem.Error(w, r, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	if len(errors) > 0 {
+		JSON(w, http.StatusUnprocessableEntity, errors)
+		return
+	}
+	issued, er3 := h.service.Create(r.Context(), &key)
+	if er3 != nil {
+		h.LogError(r.Context(), er3.Error())
+		problem.Error(w, r, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	JSON(w, http.StatusCreated, issued)
+}
+func (h *ApiKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if len(id) == 0 {
+		problem.Error(w, r, "Id cannot be empty", http.StatusBadRequest)
+		return
+	}
+	res, err := h.service.Revoke(r.Context(), id)
+	if err != nil {
+		h.LogError(r.Context(), err.Error())
+		problem.Error(w, r, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	JSON(w, GetStatus(res), res)
+}
+func (h *ApiKeyHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if len(id) == 0 {
+		problem.Error(w, r, "Id cannot be empty", http.StatusBadRequest)
+		return
+	}
+	issued, err := h.service.Rotate(r.Context(), id)
+	if err != nil {
+		h.LogError(r.Context(), err.Error())
+		problem.Error(w, r, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	if issued == nil {
+		JSON(w, http.StatusNotFound, 0)
+		return
+	}
+	JSON(w, http.StatusOK, issued)
+}
+
+func JSON(w http.ResponseWriter, code int, res interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	return json.NewEncoder(w).Encode(res)
+}
+func GetStatus(status int64) int {
+	if status <= 0 {
+		return http.StatusNotFound
+	}
+	return http.StatusOK
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"go-service/internal/apikey/domain"
+)
+
+func NewApiKeyAdapter(db *sql.DB) *ApiKeyAdapter {
+	return &ApiKeyAdapter{DB: db}
+}
+
+type ApiKeyAdapter struct {
+	DB *sql.DB
+}
+
+const selectApiKey = `
+		select
+			id,
+			name,
+			prefix,
+			hash,
+			scopes,
+			created_at,
+			expires_at,
+			revoked_at,
+			last_used_at
+		from api_keys`
+
+func (r *ApiKeyAdapter) Load(ctx context.Context, id string) (*domain.ApiKey, error) {
+	rows, err := r.DB.QueryContext(ctx, selectApiKey+" where id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys, err := scan(rows)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+	return &keys[0], nil
+}
+func (r *ApiKeyAdapter) LoadByHash(ctx context.Context, hash string) (*domain.ApiKey, error) {
+	rows, err := r.DB.QueryContext(ctx, selectApiKey+" where hash = $1", hash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys, err := scan(rows)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+	return &keys[0], nil
+}
+func (r *ApiKeyAdapter) All(ctx context.Context) ([]domain.ApiKey, error) {
+	rows, err := r.DB.QueryContext(ctx, selectApiKey+" order by created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scan(rows)
+}
+func (r *ApiKeyAdapter) Create(ctx context.Context, key *domain.ApiKey) (int64, error) {
+	query := `
+		insert into api_keys (
+			id,
+			name,
+			prefix,
+			hash,
+			scopes,
+			created_at,
+			expires_at)
+		values (
+			$1,
+			$2,
+			$3,
+			$4,
+			$5,
+			$6,
+			$7)`
+	tx := GetTx(ctx)
+	res, err := tx.ExecContext(ctx, query,
+		key.Id,
+		key.Name,
+		key.Prefix,
+		key.Hash,
+		strings.Join(key.Scopes, ","),
+		key.CreatedAt,
+		key.ExpiresAt)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
+func (r *ApiKeyAdapter) UpdateHash(ctx context.Context, id string, prefix string, hash string) (int64, error) {
+	query := "update api_keys set prefix = $1, hash = $2 where id = $3 and revoked_at is null"
+	tx := GetTx(ctx)
+	res, err := tx.ExecContext(ctx, query, prefix, hash, id)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
+func (r *ApiKeyAdapter) Revoke(ctx context.Context, id string, at time.Time) (int64, error) {
+	query := "update api_keys set revoked_at = $1 where id = $2 and revoked_at is null"
+	tx := GetTx(ctx)
+	res, err := tx.ExecContext(ctx, query, at, id)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
+func (r *ApiKeyAdapter) Touch(ctx context.Context, id string, at time.Time) (int64, error) {
+	res, err := r.DB.ExecContext(ctx, "update api_keys set last_used_at = $1 where id = $2", at, id)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
+
+func scan(rows *sql.Rows) ([]domain.ApiKey, error) {
+	var keys []domain.ApiKey
+	for rows.Next() {
+		var key domain.ApiKey
+		var scopes sql.NullString
+		err := rows.Scan(
+			&key.Id,
+			&key.Name,
+			&key.Prefix,
+			&key.Hash,
+			&scopes,
+			&key.CreatedAt,
+			&key.ExpiresAt,
+			&key.RevokedAt,
+			&key.LastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+		if len(scopes.String) > 0 {
+			key.Scopes = strings.Split(scopes.String, ",")
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func GetTx(ctx context.Context) *sql.Tx {
+	t := ctx.Value("tx")
+	if t != nil {
+		tx, ok := t.(*sql.Tx)
+		if ok {
+			return tx
+		}
+	}
+	return nil
+}
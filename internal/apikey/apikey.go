@@ -0,0 +1,46 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	v "github.com/core-go/core/v10"
+
+	"go-service/internal/apikey/adapter/handler"
+	"go-service/internal/apikey/adapter/repository"
+	"go-service/internal/apikey/service"
+	"go-service/pkg/auth"
+)
+
+type ApiKeyTransport interface {
+	All(w http.ResponseWriter, r *http.Request)
+	Create(w http.ResponseWriter, r *http.Request)
+	Revoke(w http.ResponseWriter, r *http.Request)
+	Rotate(w http.ResponseWriter, r *http.Request)
+}
+
+func NewApiKeyHandler(db *sql.DB, logError func(context.Context, string, ...map[string]interface{})) (ApiKeyTransport, service.ApiKeyService, error) {
+	validator, err := v.NewValidator()
+	if err != nil {
+		return nil, nil, err
+	}
+	apiKeyRepository := repository.NewApiKeyAdapter(db)
+	apiKeyService := service.NewApiKeyService(db, apiKeyRepository, logError)
+	apiKeyHandler := handler.NewApiKeyHandler(apiKeyService, validator.Validate, logError)
+	return apiKeyHandler, apiKeyService, nil
+}
+
+func Verify(apiKeyService service.ApiKeyService) func(context.Context, string) (*auth.Principal, error) {
+	return func(ctx context.Context, key string) (*auth.Principal, error) {
+		apiKey, err := apiKeyService.Authenticate(ctx, key)
+		if errors.Is(err, service.ErrInvalidApiKey) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &auth.Principal{Subject: apiKey.Id, Type: auth.PrincipalApiKey, Scopes: apiKey.Scopes}, nil
+	}
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+type ApiKey struct {
+	Id         string     `yaml:"id" mapstructure:"id" json:"id" gorm:"column:id;primary_key" bson:"_id" dynamodbav:"id" firestore:"-" avro:"id"`
+	Name       string     `yaml:"name" mapstructure:"name" json:"name" gorm:"column:name" bson:"name" dynamodbav:"name" firestore:"name" avro:"name" validate:"required,max=100"`
+	Prefix     string     `yaml:"prefix" mapstructure:"prefix" json:"prefix" gorm:"column:prefix" bson:"prefix" dynamodbav:"prefix" firestore:"prefix" avro:"prefix"`
+	Hash       string     `yaml:"-" mapstructure:"-" json:"-" gorm:"column:hash" bson:"hash" dynamodbav:"hash" firestore:"hash" avro:"hash"`
+	Scopes     []string   `yaml:"scopes" mapstructure:"scopes" json:"scopes,omitempty" gorm:"column:scopes" bson:"scopes" dynamodbav:"scopes" firestore:"scopes" avro:"scopes"`
+	CreatedAt  *time.Time `yaml:"created_at" mapstructure:"created_at" json:"createdAt,omitempty" gorm:"column:created_at" bson:"createdAt" dynamodbav:"createdAt" firestore:"createdAt" avro:"createdAt"`
+	ExpiresAt  *time.Time `yaml:"expires_at" mapstructure:"expires_at" json:"expiresAt,omitempty" gorm:"column:expires_at" bson:"expiresAt" dynamodbav:"expiresAt" firestore:"expiresAt" avro:"expiresAt"`
+	RevokedAt  *time.Time `yaml:"revoked_at" mapstructure:"revoked_at" json:"revokedAt,omitempty" gorm:"column:revoked_at" bson:"revokedAt" dynamodbav:"revokedAt" firestore:"revokedAt" avro:"revokedAt"`
+	LastUsedAt *time.Time `yaml:"last_used_at" mapstructure:"last_used_at" json:"lastUsedAt,omitempty" gorm:"column:last_used_at" bson:"lastUsedAt" dynamodbav:"lastUsedAt" firestore:"lastUsedAt" avro:"lastUsedAt"`
+}
+
+func (k *ApiKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+type IssuedApiKey struct {
+	ApiKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,18 @@
+package port
+
+import (
+	"context"
+	"time"
+
+	"go-service/internal/apikey/domain"
+)
+
+type ApiKeyRepository interface {
+	Load(ctx context.Context, id string) (*domain.ApiKey, error)
+	LoadByHash(ctx context.Context, hash string) (*domain.ApiKey, error)
+	All(ctx context.Context) ([]domain.ApiKey, error)
+	Create(ctx context.Context, key *domain.ApiKey) (int64, error)
+	UpdateHash(ctx context.Context, id string, prefix string, hash string) (int64, error)
+	Revoke(ctx context.Context, id string, at time.Time) (int64, error)
+	Touch(ctx context.Context, id string, at time.Time) (int64, error)
+}
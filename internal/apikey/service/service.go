@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	. "go-service/internal/apikey/domain"
+	. "go-service/internal/apikey/port"
+)
+
+const (
+	keyPrefix      = "gsk_"
+	touchInterval  = time.Minute
+	displayedChars = 8
+)
+
+var ErrInvalidApiKey = errors.New("invalid api key")
+
+type ApiKeyService interface {
+	All(ctx context.Context) ([]ApiKey, error)
+	Create(ctx context.Context, key *ApiKey) (*IssuedApiKey, error)
+	Revoke(ctx context.Context, id string) (int64, error)
+	Rotate(ctx context.Context, id string) (*IssuedApiKey, error)
+	Authenticate(ctx context.Context, key string) (*ApiKey, error)
+}
+
+func NewApiKeyService(db *sql.DB, repository ApiKeyRepository, logError func(context.Context, string, ...map[string]interface{})) ApiKeyService {
+	return &ApiKeyUseCase{db: db, repository: repository, logError: logError}
+}
+
+type ApiKeyUseCase struct {
+	db         *sql.DB
+	repository ApiKeyRepository
+	logError   func(context.Context, string, ...map[string]interface{})
+}
+
+func (s *ApiKeyUseCase) All(ctx context.Context) ([]ApiKey, error) {
+	return s.repository.All(ctx)
+}
+func (s *ApiKeyUseCase) Create(ctx context.Context, key *ApiKey) (*IssuedApiKey, error) {
+	id, err := random(9)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := Generate()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	key.Id = id
+	key.Prefix = secret[:len(keyPrefix)+displayedChars]
+	key.Hash = Hash(secret)
+	key.CreatedAt = &now
+	key.RevokedAt = nil
+	key.LastUsedAt = nil
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, "tx", tx)
+	_, err = s.repository.Create(ctx, key)
+	if err != nil {
+		er := tx.Rollback()
+		if er != nil {
+			return nil, er
+		}
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &IssuedApiKey{ApiKey: *key, Key: secret}, nil
+}
+func (s *ApiKeyUseCase) Revoke(ctx context.Context, id string) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return -1, err
+	}
+	ctx = context.WithValue(ctx, "tx", tx)
+	res, err := s.repository.Revoke(ctx, id, time.Now())
+	if err != nil {
+		er := tx.Rollback()
+		if er != nil {
+			return -1, er
+		}
+		return -1, err
+	}
+	err = tx.Commit()
+	return res, err
+}
+func (s *ApiKeyUseCase) Rotate(ctx context.Context, id string) (*IssuedApiKey, error) {
+	secret, err := Generate()
+	if err != nil {
+		return nil, err
+	}
+	prefix := secret[:len(keyPrefix)+displayedChars]
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, "tx", tx)
+	res, err := s.repository.UpdateHash(ctx, id, prefix, Hash(secret))
+	if err != nil || res <= 0 {
+		er := tx.Rollback()
+		if er != nil {
+			return nil, er
+		}
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	key, err := s.repository.Load(ctx, id)
+	if err != nil || key == nil {
+		return nil, err
+	}
+	return &IssuedApiKey{ApiKey: *key, Key: secret}, nil
+}
+func (s *ApiKeyUseCase) Authenticate(ctx context.Context, key string) (*ApiKey, error) {
+	apiKey, err := s.repository.LoadByHash(ctx, Hash(key))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if apiKey == nil || !apiKey.IsActive(now) {
+		return nil, ErrInvalidApiKey
+	}
+	// last_used_at is informational, so a failure to record it does not reject a valid key.
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > touchInterval {
+		if _, err = s.repository.Touch(ctx, apiKey.Id, now); err != nil {
+			if s.logError != nil {
+				s.logError(ctx, "cannot record the use of api key "+apiKey.Id+": "+err.Error())
+			}
+		} else {
+			apiKey.LastUsedAt = &now
+		}
+	}
+	return apiKey, nil
+}
+
+func Generate() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+func Hash(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+func random(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-service/internal/apikey/domain"
+	"go-service/internal/user/usertest"
+)
+
+// repository keeps the keys in memory, and fails Touch when touchErr is set.
+type repository struct {
+	mu       sync.Mutex
+	keys     map[string]domain.ApiKey
+	touched  int
+	touchErr error
+}
+
+func newRepository() *repository {
+	return &repository{keys: make(map[string]domain.ApiKey)}
+}
+
+func (r *repository) Load(ctx context.Context, id string) (*domain.ApiKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if k, ok := r.keys[id]; ok {
+		return &k, nil
+	}
+	return nil, nil
+}
+func (r *repository) LoadByHash(ctx context.Context, hash string) (*domain.ApiKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range r.keys {
+		if k.Hash == hash {
+			return &k, nil
+		}
+	}
+	return nil, nil
+}
+func (r *repository) All(ctx context.Context) ([]domain.ApiKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := make([]domain.ApiKey, 0, len(r.keys))
+	for _, k := range r.keys {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+func (r *repository) Create(ctx context.Context, key *domain.ApiKey) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[key.Id] = *key
+	return 1, nil
+}
+func (r *repository) UpdateHash(ctx context.Context, id string, prefix string, hash string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k, ok := r.keys[id]
+	if !ok || k.RevokedAt != nil {
+		return 0, nil
+	}
+	k.Prefix, k.Hash = prefix, hash
+	r.keys[id] = k
+	return 1, nil
+}
+func (r *repository) Revoke(ctx context.Context, id string, at time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k, ok := r.keys[id]
+	if !ok || k.RevokedAt != nil {
+		return 0, nil
+	}
+	k.RevokedAt = &at
+	r.keys[id] = k
+	return 1, nil
+}
+func (r *repository) Touch(ctx context.Context, id string, at time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.touched++
+	if r.touchErr != nil {
+		return 0, r.touchErr
+	}
+	k := r.keys[id]
+	k.LastUsedAt = &at
+	r.keys[id] = k
+	return 1, nil
+}
+
+func TestGenerateAndHash(t *testing.T) {
+	a, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := Generate()
+	if a == b || !strings.HasPrefix(a, keyPrefix) || len(a) != len(keyPrefix)+43 {
+		t.Errorf("Generate() = %q, %q", a, b)
+	}
+	if Hash(a) != Hash(a) || Hash(a) == Hash(b) || len(Hash(a)) != 64 {
+		t.Errorf("Hash() must be the hex SHA-256 of the key")
+	}
+	if Hash("gsk_test") != "8f1c6e511c4e21b9fbea5ab6696af4f4727a46912adec68513f27e2a864eeed1" {
+		t.Errorf("Hash(gsk_test) = %s", Hash("gsk_test"))
+	}
+}
+
+func TestCreateAndAuthenticate(t *testing.T) {
+	repo := newRepository()
+	s := NewApiKeyService(usertest.NewDB(), repo, nil)
+	ctx := context.Background()
+	issued, err := s.Create(ctx, &domain.ApiKey{Name: "billing", Scopes: []string{"users:read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := repo.Load(ctx, issued.Id)
+	if stored.Hash != Hash(issued.Key) || strings.Contains(stored.Hash, issued.Key) {
+		t.Error("only the hash of the key must be stored")
+	}
+	if stored.Prefix != issued.Key[:len(keyPrefix)+displayedChars] {
+		t.Errorf("prefix = %q, want the start of the key", stored.Prefix)
+	}
+
+	key, err := s.Authenticate(ctx, issued.Key)
+	if err != nil || key.Id != issued.Id || key.LastUsedAt == nil {
+		t.Fatalf("Authenticate() = %+v, %v", key, err)
+	}
+	if _, err := s.Authenticate(ctx, issued.Key); err != nil || repo.touched != 1 {
+		t.Errorf("Authenticate() touched the key %d times within %s, want 1", repo.touched, touchInterval)
+	}
+	for _, k := range []string{"", "gsk_unknown", issued.Key + "x", strings.ToUpper(issued.Key)} {
+		if _, err := s.Authenticate(ctx, k); !errors.Is(err, ErrInvalidApiKey) {
+			t.Errorf("Authenticate(%q) = %v, want ErrInvalidApiKey", k, err)
+		}
+	}
+}
+
+func TestAuthenticateInactive(t *testing.T) {
+	repo := newRepository()
+	s := NewApiKeyService(usertest.NewDB(), repo, nil)
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour)
+	repo.Create(ctx, &domain.ApiKey{Id: "expired", Hash: Hash("gsk_expired"), ExpiresAt: &past})
+	repo.Create(ctx, &domain.ApiKey{Id: "revoked", Hash: Hash("gsk_revoked"), RevokedAt: &past})
+	for _, k := range []string{"gsk_expired", "gsk_revoked"} {
+		if _, err := s.Authenticate(ctx, k); !errors.Is(err, ErrInvalidApiKey) {
+			t.Errorf("Authenticate(%q) = %v, want ErrInvalidApiKey", k, err)
+		}
+	}
+}
+
+func TestAuthenticateWhenTouchFails(t *testing.T) {
+	repo := newRepository()
+	var logged []string
+	s := NewApiKeyService(usertest.NewDB(), repo, func(ctx context.Context, msg string, fields ...map[string]interface{}) {
+		logged = append(logged, msg)
+	})
+	ctx := context.Background()
+	issued, err := s.Create(ctx, &domain.ApiKey{Name: "billing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.touchErr = errors.New("database is read only")
+	key, err := s.Authenticate(ctx, issued.Key)
+	if err != nil || key == nil || key.Id != issued.Id {
+		t.Fatalf("Authenticate() = %+v, %v; want the key although Touch failed", key, err)
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], "database is read only") {
+		t.Errorf("logged %q, want the error of Touch", logged)
+	}
+}
+
+func TestRotateAndRevoke(t *testing.T) {
+	repo := newRepository()
+	s := NewApiKeyService(usertest.NewDB(), repo, nil)
+	ctx := context.Background()
+	issued, err := s.Create(ctx, &domain.ApiKey{Name: "billing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := s.Rotate(ctx, issued.Id)
+	if err != nil || rotated == nil {
+		t.Fatalf("Rotate() = %+v, %v", rotated, err)
+	}
+	if rotated.Id != issued.Id || rotated.Key == issued.Key || rotated.Prefix == issued.Prefix {
+		t.Errorf("Rotate() = %+v, want a new key with the same id", rotated)
+	}
+	if _, err := s.Authenticate(ctx, issued.Key); !errors.Is(err, ErrInvalidApiKey) {
+		t.Errorf("the key before the rotation is accepted: %v", err)
+	}
+	if _, err := s.Authenticate(ctx, rotated.Key); err != nil {
+		t.Errorf("the rotated key is rejected: %v", err)
+	}
+	if res, _ := s.Rotate(ctx, "missing"); res != nil {
+		t.Errorf("Rotate() of a missing key = %+v", res)
+	}
+
+	if res, err := s.Revoke(ctx, issued.Id); res != 1 || err != nil {
+		t.Fatalf("Revoke() = %d, %v", res, err)
+	}
+	if _, err := s.Authenticate(ctx, rotated.Key); !errors.Is(err, ErrInvalidApiKey) {
+		t.Errorf("a revoked key is accepted: %v", err)
+	}
+	if res, _ := s.Rotate(ctx, issued.Id); res != nil {
+		t.Errorf("Rotate() of a revoked key = %+v", res)
+	}
+}
@@ -2,36 +2,290 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
+	"database/sql"
+	"net/http"
+	"time"
 
-	"github.com/core-go/health"
 	"github.com/core-go/log/zap"
 	q "github.com/core-go/sql"
 
+	"go-service/internal/apikey"
 	"go-service/internal/user"
+	userclient "go-service/internal/user/adapter/client"
+	"go-service/internal/user/port"
+	"go-service/pkg/auth"
+	"go-service/pkg/client"
+	"go-service/pkg/feature"
+	"go-service/pkg/lifecycle"
+	"go-service/pkg/mask"
+	"go-service/pkg/metrics"
+	"go-service/pkg/probe"
+	"go-service/pkg/ratelimit"
+	"go-service/pkg/reload"
+	"go-service/pkg/tlsconfig"
+	"go-service/pkg/tracing"
 )
 
 type ApplicationContext struct {
-	Health *health.Handler
-	User   user.UserTransport
+	Health       *probe.Handler
+	User         user.UserTransport
+	ApiKey       apikey.ApiKeyTransport
+	Authenticate func(http.Handler) http.Handler
+	Admin        func(http.Handler) http.Handler
+	RateLimitIP  func(http.Handler) http.Handler
+	RateLimit    func(http.Handler) http.Handler
+	Readiness    *lifecycle.Readiness
+	Metrics      *metrics.Metrics
+	Features     *feature.Flags
+	Reloader     *Reloader
+	AdminServer  *http.Server
+	TLS          *tls.Config
+	Tracing      bool
+	DB           *sql.DB
+	cancel       context.CancelFunc
+	flush        func(context.Context) error
 }
 
-func NewApp(ctx context.Context, cfg Config) (*ApplicationContext, error) {
-	db, err := q.OpenByConfig(cfg.Sql)
+// NewApp masks the logs of the handlers and of pkg/client with masker, and updates it on reload, so that the request log shares its rules.
+func NewApp(ctx context.Context, cfg Config, masker *mask.Reloadable) (*ApplicationContext, error) {
+	var db *sql.DB
+	var err error
+	if cfg.UsesSql() {
+		db, err = q.OpenByConfig(cfg.Sql)
+		if err != nil {
+			return nil, err
+		}
+	}
+	flush := func(context.Context) error { return nil }
+	if cfg.Tracing.Enabled {
+		flush, err = tracing.Initialize(cfg.Tracing)
+		if err != nil {
+			closeDB(db)
+			return nil, err
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	app, err := newApp(ctx, cfg, db, masker)
 	if err != nil {
+		cancel()
+		closeDB(db)
+		flush(context.Background())
 		return nil, err
 	}
+	app.Tracing = cfg.Tracing.Enabled
+	app.DB = db
+	app.cancel = cancel
+	app.flush = flush
+	if cfg.Reload.Enabled {
+		err = reload.Watch(ctx, cfg.Reload, ConfigFiles(), func(ctx context.Context) {
+			app.Reloader.Reload(ctx)
+		}, log.LogError)
+		if err != nil {
+			app.Close()
+			return nil, err
+		}
+	}
+	return app, nil
+}
+
+// Close stops background workers, closes the database and flushes pending spans after the server has drained.
+func (a *ApplicationContext) Close() error {
+	a.cancel()
+	err := closeDB(a.DB)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if er := a.flush(ctx); err == nil {
+		err = er
+	}
+	return err
+}
+
+// closeDB closes db, which is nil when the users are served by the client repository.
+func closeDB(db *sql.DB) error {
+	if db == nil {
+		return nil
+	}
+	return db.Close()
+}
+
+func newApp(ctx context.Context, cfg Config, db *sql.DB, masker *mask.Reloadable) (*ApplicationContext, error) {
 	logError := log.LogError
 
-	userHandler, err := user.NewUserHandler(db, logError)
+	reloader := NewReloader(cfg, LoadConfig, log.LogInfo, log.LogWarn, logError)
+	authenticate := func(next http.Handler) http.Handler { return next }
+	admin := func(next http.Handler) http.Handler { return next }
+	var apiKeyHandler apikey.ApiKeyTransport
+	var policy *auth.Policy
+	if cfg.Auth.Enabled {
+		authenticate = auth.Require
+		if len(cfg.Auth.SecretFile) > 0 || len(cfg.Auth.KeyFiles) > 0 || len(cfg.Auth.Jwks) > 0 {
+			keyStore, err := auth.NewKeyStore(ctx, cfg.Auth, nil, logError)
+			if err != nil {
+				return nil, err
+			}
+			go keyStore.Run(ctx)
+			authenticate = auth.NewAuthenticator(cfg.Auth, keyStore, logError).Authenticate
+		}
+		if cfg.Auth.ApiKeys {
+			handler, apiKeyService, err := apikey.NewApiKeyHandler(db, logError)
+			if err != nil {
+				return nil, err
+			}
+			apiKeyHandler = handler
+			authenticate = chain(auth.NewApiKeyAuthenticator(apikey.Verify(apiKeyService), logError).Authenticate, authenticate)
+		}
+		if cfg.Auth.ClientCerts {
+			authenticate = chain(auth.Certificate, authenticate)
+		}
+		if len(cfg.Auth.SigningKeys) > 0 {
+			signed, err := auth.NewSignatureAuthenticator(cfg.Auth, nil, logError)
+			if err != nil {
+				return nil, err
+			}
+			go signed.Run(ctx)
+			authenticate = chain(signed.Authenticate, authenticate)
+		}
+		adminRole := cfg.Auth.AdminRole
+		if len(adminRole) == 0 {
+			adminRole = "admin"
+		}
+		admin = auth.RequireRole(adminRole)
+		if len(cfg.Auth.PolicyFile) > 0 {
+			var err error
+			policy, err = auth.LoadPolicy(cfg.Auth.PolicyFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var serverTLS *tls.Config
+	if cfg.Server.Secure {
+		c := cfg.ServerTLS
+		c.CertFile, c.KeyFile = cfg.Server.Cert, cfg.Server.Key
+		store, err := tlsconfig.NewStore(c, logError)
+		if err != nil {
+			return nil, err
+		}
+		serverTLS = store.ServerConfig()
+	}
+
+	limiter, err := ratelimit.NewLimiter(cfg.RateLimit, ratelimit.NewMemoryStore(0), logError)
 	if err != nil {
 		return nil, err
 	}
+	reloader.OnReload(func(c Config) error {
+		return limiter.Update(c.RateLimit)
+	})
+
+	var m *metrics.Metrics
+	var observe func(string, string, time.Time, error)
+	if cfg.Metrics.Enabled {
+		m, err = metrics.NewMetrics(cfg.Metrics, db)
+		if err != nil {
+			return nil, err
+		}
+		observe = m.ObserveQuery
+		client.SetObserver(m.ObserveCall)
+	}
+	client.SetCircuitObserver(func(host string, from string, to string) {
+		log.LogWarn(context.Background(), "circuit of "+host+" changed from "+from+" to "+to)
+		if m != nil {
+			m.ObserveCircuit(host, from, to)
+		}
+	})
+
+	reloader.OnReload(func(c Config) error {
+		return masker.Update(c.Mask)
+	})
+	client.SetMask(masker.MaskJSON)
 
-	sqlChecker := q.NewHealthChecker(db)
-	healthHandler := health.NewHandler(sqlChecker)
+	features := feature.NewFlags(cfg.Features)
+	reloader.OnReload(func(c Config) error {
+		features.Set(c.Features)
+		return nil
+	})
+
+	var httpClient *http.Client
+	var header map[string]string
+	var clientLog *client.LogConfig
+	if cfg.User.Repository == user.RepositoryClient || len(cfg.Client.Health) > 0 {
+		client.SetRetryLog(logFields(log.LogWarn))
+		client.SetLog(logFields(logError), logFields(log.LogInfo))
+		httpClient, header, clientLog, err = client.InitializeClient(cfg.Client)
+		if err != nil {
+			return nil, err
+		}
+		var timeout *client.TimeoutTransport
+		httpClient, timeout = client.WithTimeout(httpClient)
+		reloader.OnReload(func(c Config) error {
+			timeout.SetTimeout(0)
+			if c.Client.Endpoint.Timeout != nil {
+				timeout.SetTimeout(*c.Client.Endpoint.Timeout)
+			}
+			return nil
+		})
+	}
+
+	var userRepository port.UserRepository
+	userDB := db
+	if cfg.User.Repository == user.RepositoryClient {
+		userRepository = userclient.NewUserClient(httpClient, cfg.Client.Endpoint.Url, header, clientLog, logFields(logError), logFields(log.LogInfo))
+		// the writes go to another service, so a transaction of the database, opened for the API keys, would do nothing
+		userDB = nil
+	}
+	userHandler, err := user.NewUserHandler(userDB, userRepository, policy, observe, masker.Mask, logError)
+	if err != nil {
+		return nil, err
+	}
+
+	readiness := lifecycle.NewReadiness()
+	checks := []*probe.Check{probe.NewCheck(readiness, 0, 0)}
+	if db != nil {
+		checks = append(checks,
+			probe.NewCheck(q.NewHealthChecker(db), cfg.Health.Timeout, cfg.Health.Cache),
+			probe.NewCheck(probe.NewPoolChecker(db, cfg.Health.MaxInUse), 0, 0),
+		)
+		if len(cfg.Health.Migration.Table) > 0 {
+			checks = append(checks, probe.NewCheck(probe.NewMigrationChecker(db, cfg.Health.Migration), cfg.Health.Timeout, cfg.Health.Cache))
+		}
+	}
+	if len(cfg.Client.Health) > 0 {
+		checks = append(checks, probe.NewCheck(probe.NewHttpChecker("client", cfg.Client.Health, httpClient, header), cfg.Health.Timeout, cfg.Health.Cache))
+	}
+	if httpClient != nil {
+		if breaker, ok := client.Find[*client.Breaker](httpClient); ok {
+			checks = append(checks, probe.NewCheck(probe.NewCircuitChecker("circuit", breaker.States), 0, 0))
+		}
+	}
+	healthHandler := probe.NewHandler(checks...)
 
 	return &ApplicationContext{
-		Health: healthHandler,
-		User:   userHandler,
+		Health:       healthHandler,
+		User:         userHandler,
+		ApiKey:       apiKeyHandler,
+		Authenticate: authenticate,
+		Admin:        admin,
+		RateLimitIP:  limiter.HandleIP,
+		RateLimit:    limiter.Handle,
+		Readiness:    readiness,
+		Metrics:      m,
+		Features:     features,
+		Reloader:     reloader,
+		TLS:          serverTLS,
 	}, nil
 }
+
+// logFields adapts a core-go log function to the log options of pkg/client.
+func logFields(logf func(context.Context, string, ...map[string]interface{})) func(context.Context, string, map[string]interface{}) {
+	return func(ctx context.Context, msg string, fields map[string]interface{}) {
+		logf(ctx, msg, fields)
+	}
+}
+
+func chain(outer func(http.Handler) http.Handler, inner func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return outer(inner(next))
+	}
+}
@@ -1,18 +1,73 @@
 package app
 
 import (
+	"errors"
+	"os"
+	"strings"
+
 	"github.com/core-go/core"
 	mid "github.com/core-go/log/middleware"
 	"github.com/core-go/log/zap"
 	"github.com/core-go/sql"
 
+	"go-service/internal/user"
+	"go-service/pkg/admin"
+	"go-service/pkg/auth"
 	"go-service/pkg/client"
+	"go-service/pkg/envconfig"
+	"go-service/pkg/lifecycle"
+	"go-service/pkg/mask"
+	"go-service/pkg/metrics"
+	"go-service/pkg/probe"
+	"go-service/pkg/ratelimit"
+	"go-service/pkg/reload"
+	"go-service/pkg/tlsconfig"
+	"go-service/pkg/tracing"
 )
 
 type Config struct {
 	Server     core.ServerConf     `mapstructure:"server"`
+	ServerTLS  tlsconfig.Config    `mapstructure:"server_tls"`
 	Sql        sql.Config          `mapstructure:"sql"`
+	User       user.Config         `mapstructure:"user"`
 	Client     client.ClientConfig `mapstructure:"client"`
 	Log        log.Config          `mapstructure:"log"`
 	MiddleWare mid.LogConfig       `mapstructure:"middleware"`
+	Mask       mask.Config         `mapstructure:"mask"`
+	Auth       auth.Config         `mapstructure:"auth"`
+	RateLimit  ratelimit.Config    `mapstructure:"rate_limit"`
+	Shutdown   lifecycle.Config    `mapstructure:"shutdown"`
+	Health     probe.Config        `mapstructure:"health"`
+	Metrics    metrics.Config      `mapstructure:"metrics"`
+	Tracing    tracing.Config      `mapstructure:"tracing"`
+	Reload     reload.Config       `mapstructure:"reload"`
+	Admin      admin.Config        `mapstructure:"admin"`
+	Features   map[string]bool     `mapstructure:"features"`
+}
+
+const (
+	EnvPrefix  = "APP"
+	EnvName    = "APP_ENV"
+	ConfigFile = "configs/config"
+)
+
+// UsesSql reports whether the database is needed: by the SQL repository of users, and by the API keys.
+func (c Config) UsesSql() bool {
+	return c.User.Repository != user.RepositoryClient || (c.Auth.Enabled && c.Auth.ApiKeys)
+}
+
+// LoadConfig loads configs/config.yml, the overlay selected by APP_ENV and the APP_* overrides, then validates the result.
+func LoadConfig() (Config, error) {
+	var cfg Config
+	err := envconfig.Load(EnvPrefix, EnvName, &cfg, ConfigFile)
+	return cfg, errors.Join(err, cfg.Validate())
+}
+
+// ConfigFiles returns the files LoadConfig reads, including the secret files of the APP_*_FILE variables.
+func ConfigFiles() []string {
+	files := []string{ConfigFile + ".yml"}
+	if env := strings.ToLower(os.Getenv(EnvName)); len(env) > 0 {
+		files = append(files, ConfigFile+"."+env+".yml", ConfigFile+"-"+env+".yml")
+	}
+	return append(files, envconfig.Files(EnvPrefix)...)
 }
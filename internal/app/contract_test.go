@@ -0,0 +1,49 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"go-service/internal/user"
+	"go-service/internal/user/domain"
+	"go-service/internal/user/usertest"
+	"go-service/pkg/probe"
+	"go-service/pkg/ratelimit"
+	"go-service/pkg/requestid"
+)
+
+// newUserHandler routes the handler of the service by Handle, with the middlewares of a config without auth, and the users kept by a usertest.Repository.
+func newUserHandler(users ...domain.User) (http.Handler, error) {
+	logError := func(context.Context, string, ...map[string]interface{}) {}
+	transport, err := user.NewUserHandler(usertest.NewDB(), usertest.NewRepository(users...), nil, nil, nil, logError)
+	if err != nil {
+		return nil, err
+	}
+	limiter, err := ratelimit.NewLimiter(ratelimit.Config{}, nil, logError)
+	if err != nil {
+		return nil, err
+	}
+	next := func(h http.Handler) http.Handler { return h }
+	r := mux.NewRouter()
+	r.Use(requestid.Handle)
+	Handle(r, &ApplicationContext{
+		Health:       probe.NewHandler(),
+		User:         transport,
+		Authenticate: next,
+		Admin:        next,
+		RateLimitIP:  limiter.HandleIP,
+		RateLimit:    limiter.Handle,
+	})
+	return r, nil
+}
+
+func TestContract(t *testing.T) {
+	spec, err := usertest.FindSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	usertest.Contract(t, spec, usertest.Target{Name: "handler", Handler: newUserHandler}, usertest.FakeTarget())
+}
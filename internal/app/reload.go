@@ -0,0 +1,160 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/core-go/log/zap"
+
+	"go-service/pkg/envconfig"
+)
+
+// Sections applied without restart. Of the client section, only client.endpoint.timeout is applied.
+var reloadable = map[string]bool{"log": true, "middleware": true, "mask": true, "rate_limit": true, "features": true}
+
+type ReloadResult struct {
+	Applied []string `json:"applied"`
+	Restart []string `json:"restart"`
+}
+
+type Reloader struct {
+	Load     func() (Config, error)
+	LogInfo  func(context.Context, string, ...map[string]interface{})
+	LogWarn  func(context.Context, string, ...map[string]interface{})
+	LogError func(context.Context, string, ...map[string]interface{})
+	mu       sync.Mutex
+	config   atomic.Pointer[Config]
+	applies  []func(Config) error
+}
+
+func NewReloader(cfg Config, load func() (Config, error), logInfo func(context.Context, string, ...map[string]interface{}), logWarn func(context.Context, string, ...map[string]interface{}), logError func(context.Context, string, ...map[string]interface{})) *Reloader {
+	r := &Reloader{Load: load, LogInfo: logInfo, LogWarn: logWarn, LogError: logError}
+	r.config.Store(&cfg)
+	return r
+}
+
+// OnReload registers a function applying the reloadable settings. It is called after the new config has been validated.
+func (r *Reloader) OnReload(apply func(Config) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.applies = append(r.applies, apply)
+}
+
+// Config returns the effective config: the reloadable sections of the last valid file, and the other sections as they were at startup.
+func (r *Reloader) Config() Config {
+	return *r.config.Load()
+}
+
+// Reload loads and validates the config, then applies the reloadable sections. An invalid config is rejected as a whole.
+func (r *Reloader) Reload(ctx context.Context) (*ReloadResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cfg, err := r.Load()
+	if err != nil {
+		r.LogError(ctx, "config reload rejected: "+strings.ReplaceAll(err.Error(), "\n", "; "))
+		return nil, err
+	}
+	old := r.Config()
+	next, res := merge(old, cfg)
+	if len(res.Restart) > 0 {
+		r.LogWarn(ctx, "config changes require a restart: "+strings.Join(res.Restart, ", "))
+	}
+	if len(res.Applied) == 0 {
+		return res, nil
+	}
+	if !reflect.DeepEqual(old.Log, next.Log) {
+		if _, err = log.Initialize(next.Log); err != nil {
+			r.LogError(ctx, "config reload rejected: "+err.Error())
+			return nil, err
+		}
+	}
+	var errs []error
+	for _, apply := range r.applies {
+		if er := apply(next); er != nil {
+			errs = append(errs, er)
+		}
+	}
+	r.config.Store(&next)
+	if err = errors.Join(errs...); err != nil {
+		r.LogError(ctx, "config partially reloaded: "+strings.ReplaceAll(err.Error(), "\n", "; "))
+		return res, err
+	}
+	r.LogInfo(ctx, "config reloaded: "+strings.Join(res.Applied, ", "))
+	return res, nil
+}
+
+func (r *Reloader) LogLevel() string {
+	return r.Config().Log.Level
+}
+
+// SetLogLevel re-initializes the logger with the effective log config and the given level.
+func (r *Reloader) SetLogLevel(level string) error {
+	if !ValidLevel(level) {
+		return fmt.Errorf("log level %q is invalid", level)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.Config()
+	c.Log.Level = strings.ToLower(level)
+	if _, err := log.Initialize(c.Log); err != nil {
+		return err
+	}
+	r.config.Store(&c)
+	return nil
+}
+
+func merge(old Config, cfg Config) (Config, *ReloadResult) {
+	res := &ReloadResult{Applied: []string{}, Restart: []string{}}
+	next := old
+	if !reflect.DeepEqual(old.Client.Endpoint.Timeout, cfg.Client.Endpoint.Timeout) {
+		next.Client.Endpoint.Timeout = cfg.Client.Endpoint.Timeout
+		res.Applied = append(res.Applied, "client.endpoint.timeout")
+	}
+	cfg.Client.Endpoint.Timeout = old.Client.Endpoint.Timeout
+	ov, cv, nv := reflect.ValueOf(old), reflect.ValueOf(cfg), reflect.ValueOf(&next).Elem()
+	for i := 0; i < ov.NumField(); i++ {
+		key, _ := envconfig.Key(ov.Type().Field(i))
+		if reflect.DeepEqual(ov.Field(i).Interface(), cv.Field(i).Interface()) {
+			continue
+		}
+		if reloadable[key] {
+			nv.Field(i).Set(cv.Field(i))
+			res.Applied = append(res.Applied, key)
+		} else {
+			res.Restart = append(res.Restart, key)
+		}
+	}
+	return next, res
+}
+
+// Show responds with the effective config, secrets redacted.
+func (r *Reloader) Show(w http.ResponseWriter, req *http.Request) {
+	JSON(w, http.StatusOK, envconfig.Redact(r.Config()))
+}
+
+// Trigger reloads the config, as SIGHUP does.
+func (r *Reloader) Trigger(w http.ResponseWriter, req *http.Request) {
+	res, err := r.Reload(req.Context())
+	if err != nil && res == nil {
+		JSON(w, http.StatusUnprocessableEntity, map[string][]string{"errors": strings.Split(err.Error(), "\n")})
+		return
+	}
+	if err != nil {
+		JSON(w, http.StatusInternalServerError, res)
+		return
+	}
+	JSON(w, http.StatusOK, res)
+}
+
+func JSON(w http.ResponseWriter, code int, res interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	return json.NewEncoder(w).Encode(res)
+}
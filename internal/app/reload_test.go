@@ -0,0 +1,79 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go-service/pkg/ratelimit"
+)
+
+func TestMerge(t *testing.T) {
+	old := validConfig()
+	old.Log.Level = "info"
+	old.Features = map[string]bool{"user_export": true}
+
+	cfg := old
+	cfg.Log.Level = "debug"
+	cfg.Features = map[string]bool{"user_export": false}
+	cfg.RateLimit = ratelimit.Config{Enabled: true, Default: ratelimit.Limit{Requests: 10, Period: time.Minute}}
+	timeout := 3 * time.Second
+	cfg.Client.Endpoint.Timeout = &timeout
+	cfg.Client.Endpoint.Url = "http://localhost:8081"
+	port := int64(9090)
+	cfg.Server.Port = &port
+
+	next, res := merge(old, cfg)
+	if want := []string{"client.endpoint.timeout", "log", "rate_limit", "features"}; !reflect.DeepEqual(res.Applied, want) {
+		t.Errorf("applied = %v, want %v", res.Applied, want)
+	}
+	if want := []string{"server", "client"}; !reflect.DeepEqual(res.Restart, want) {
+		t.Errorf("restart = %v, want %v", res.Restart, want)
+	}
+	if next.Log.Level != "debug" || next.Features["user_export"] || !next.RateLimit.Enabled || *next.Client.Endpoint.Timeout != timeout {
+		t.Errorf("the reloadable sections are not applied: %+v", next)
+	}
+	if *next.Server.Port != 8080 || len(next.Client.Endpoint.Url) > 0 {
+		t.Errorf("the other sections must be kept until restart: port %d, client url %q", *next.Server.Port, next.Client.Endpoint.Url)
+	}
+}
+
+func TestMergeUnchanged(t *testing.T) {
+	c := validConfig()
+	next, res := merge(c, c)
+	if len(res.Applied) > 0 || len(res.Restart) > 0 || !reflect.DeepEqual(next, c) {
+		t.Errorf("merge() = %+v, %+v", next, res)
+	}
+}
+
+func TestReloadRejectsInvalidConfig(t *testing.T) {
+	nop := func(context.Context, string, ...map[string]interface{}) {}
+	cfg := validConfig()
+	cfg.Features = map[string]bool{"user_export": true}
+	applied := 0
+	r := NewReloader(cfg, func() (Config, error) {
+		return Config{}, errors.New("server.port must be between 1 and 65535")
+	}, nop, nop, nop)
+	r.OnReload(func(Config) error {
+		applied++
+		return nil
+	})
+	if res, err := r.Reload(context.Background()); err == nil || res != nil || applied != 0 {
+		t.Errorf("Reload() = %v, %v; applied %d times", res, err, applied)
+	}
+	if !r.Config().Features["user_export"] {
+		t.Error("the running config must be kept")
+	}
+
+	next := cfg
+	next.Features = map[string]bool{"user_export": false}
+	r.Load = func() (Config, error) { return next, nil }
+	if res, err := r.Reload(context.Background()); err != nil || !reflect.DeepEqual(res.Applied, []string{"features"}) || applied != 1 {
+		t.Errorf("Reload() = %+v, %v; applied %d times", res, err, applied)
+	}
+	if r.Config().Features["user_export"] {
+		t.Error("the features must be reloaded")
+	}
+}
@@ -3,6 +3,10 @@ package app
 import (
 	"context"
 	"github.com/gorilla/mux"
+
+	"go-service/pkg/admin"
+	"go-service/pkg/mask"
+	"go-service/pkg/tracing"
 )
 
 const (
@@ -13,20 +17,67 @@ const (
 	DELETE = "DELETE"
 )
 
-func Route(ctx context.Context, r *mux.Router, cfg Config) error {
-	app, err := NewApp(ctx, cfg)
+func Route(ctx context.Context, r *mux.Router, cfg Config, masker *mask.Reloadable) (*ApplicationContext, error) {
+	app, err := NewApp(ctx, cfg, masker)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if app.Tracing {
+		r.Use(tracing.Handle)
+	}
+	if app.Metrics != nil {
+		r.Use(app.Metrics.Handle)
+		if cfg.Metrics.Port == nil {
+			r.Handle(app.Metrics.Config.Path, app.Metrics.Handler()).Methods(GET)
+		}
 	}
-	r.HandleFunc("/health", app.Health.Check).Methods(GET)
+	Handle(r, app)
 
-	user := "/users"
-	r.HandleFunc(user+"/search", app.User.Search).Methods(GET, POST)
-	r.HandleFunc(user+"/{id}", app.User.Load).Methods(GET)
-	r.HandleFunc(user, app.User.Create).Methods(POST)
-	r.HandleFunc(user+"/{id}", app.User.Update).Methods(PUT)
-	r.HandleFunc(user+"/{id}", app.User.Patch).Methods(PATCH)
-	r.HandleFunc(user+"/{id}", app.User.Delete).Methods(DELETE)
+	// The admin routes are only served by the admin server, never by the main one, and Validate requires auth for them.
+	if cfg.Admin.Enabled {
+		adm := mux.NewRouter()
+		adm.Use(app.Authenticate, app.Admin)
+		adm.HandleFunc("/config", app.Reloader.Show).Methods(GET)
+		adm.HandleFunc("/config/reload", app.Reloader.Trigger).Methods(POST)
+		h := admin.NewHandler(app.DB, app.Reloader.LogLevel, app.Reloader.SetLogLevel)
+		adm.HandleFunc("/build", h.Build).Methods(GET)
+		if app.DB != nil {
+			adm.HandleFunc("/db/stats", h.DBStats).Methods(GET)
+		}
+		adm.HandleFunc("/goroutines", h.Goroutines).Methods(GET)
+		adm.HandleFunc("/log/level", h.LogLevel).Methods(GET)
+		adm.HandleFunc("/log/level", h.SetLogLevel).Methods(PUT)
+		if cfg.Admin.Pprof {
+			admin.Pprof(adm)
+		}
+		app.AdminServer = admin.Server(cfg.Admin, adm)
+	}
 
-	return nil
+	return app, nil
+}
+
+// Handle registers the health, user and API key routes of app, with their middlewares: the IP limit before the authentication,
+// and the limits of each client after it.
+func Handle(r *mux.Router, app *ApplicationContext) {
+	r.HandleFunc("/health", app.Health.Ready).Methods(GET)
+	r.HandleFunc("/health/live", app.Health.Live).Methods(GET)
+	r.HandleFunc("/health/ready", app.Health.Ready).Methods(GET)
+
+	user := r.PathPrefix("/users").Subrouter()
+	user.Use(app.RateLimitIP, app.Authenticate, app.RateLimit)
+	user.HandleFunc("/search", app.User.Search).Methods(GET, POST)
+	user.HandleFunc("/{id}", app.User.Load).Methods(GET)
+	user.HandleFunc("", app.User.Create).Methods(POST)
+	user.HandleFunc("/{id}", app.User.Update).Methods(PUT)
+	user.HandleFunc("/{id}", app.User.Patch).Methods(PATCH)
+	user.HandleFunc("/{id}", app.User.Delete).Methods(DELETE)
+
+	if app.ApiKey != nil {
+		apiKey := r.PathPrefix("/api-keys").Subrouter()
+		apiKey.Use(app.RateLimitIP, app.Authenticate, app.RateLimit, app.Admin)
+		apiKey.HandleFunc("", app.ApiKey.All).Methods(GET)
+		apiKey.HandleFunc("", app.ApiKey.Create).Methods(POST)
+		apiKey.HandleFunc("/{id}", app.ApiKey.Revoke).Methods(DELETE)
+		apiKey.HandleFunc("/{id}/rotate", app.ApiKey.Rotate).Methods(POST)
+	}
 }
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"go-service/pkg/auth"
+	"go-service/pkg/probe"
+	"go-service/pkg/ratelimit"
+)
+
+type users struct{}
+
+func (users) Search(w http.ResponseWriter, r *http.Request) {}
+func (users) Load(w http.ResponseWriter, r *http.Request)   {}
+func (users) Create(w http.ResponseWriter, r *http.Request) {}
+func (users) Update(w http.ResponseWriter, r *http.Request) {}
+func (users) Patch(w http.ResponseWriter, r *http.Request)  {}
+func (users) Delete(w http.ResponseWriter, r *http.Request) {}
+
+func TestHandleRateLimitsEachApiKey(t *testing.T) {
+	logError := func(context.Context, string, ...map[string]interface{}) {}
+	limiter, err := ratelimit.NewLimiter(ratelimit.Config{
+		Enabled: true,
+		IP:      ratelimit.Limit{Requests: 5, Period: time.Minute},
+		Default: ratelimit.Limit{Requests: 2, Period: time.Minute},
+	}, nil, logError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify := func(ctx context.Context, key string) (*auth.Principal, error) {
+		if key == "gsk_billing" || key == "gsk_shipping" {
+			return &auth.Principal{Subject: key[4:], Type: auth.PrincipalApiKey}, nil
+		}
+		return nil, nil
+	}
+	r := mux.NewRouter()
+	Handle(r, &ApplicationContext{
+		Health:       probe.NewHandler(),
+		User:         users{},
+		Authenticate: chain(auth.NewApiKeyAuthenticator(verify, logError).Authenticate, auth.Require),
+		RateLimitIP:  limiter.HandleIP,
+		RateLimit:    limiter.Handle,
+	})
+	serve := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/users/ironman", nil)
+		req.RemoteAddr = "203.0.113.7:5000"
+		req.Header.Set(auth.HeaderApiKey, key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	tests := []struct {
+		key    string
+		status int
+	}{
+		{"gsk_billing", http.StatusOK},
+		{"gsk_billing", http.StatusOK},
+		{"gsk_billing", http.StatusTooManyRequests},
+		{"gsk_shipping", http.StatusOK},
+		{"gsk_unknown", http.StatusUnauthorized},
+		{"gsk_shipping", http.StatusTooManyRequests}, // the IP limit of 5, counting the failed authentication
+	}
+	for i, tt := range tests {
+		if status := serve(tt.key); status != tt.status {
+			t.Errorf("request %d with %s: status %d, want %d", i, tt.key, status, tt.status)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package app
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"strings"
+
+	"go-service/internal/user"
+	"go-service/pkg/client"
+	"go-service/pkg/mask"
+	"go-service/pkg/ratelimit"
+	"go-service/pkg/tlsconfig"
+	"go-service/pkg/tracing"
+)
+
+// Validate checks the settings needed at startup and reports every problem at once.
+func (c Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	if c.Server.Port == nil || *c.Server.Port <= 0 || *c.Server.Port > 65535 {
+		add("server.port must be between 1 and 65535")
+	}
+	if len(c.Server.Cert) > 0 || len(c.Server.Key) > 0 {
+		checkFile(add, "server.cert", c.Server.Cert)
+		checkFile(add, "server.key", c.Server.Key)
+	}
+	if c.Server.Secure {
+		if len(c.Server.Cert) == 0 || len(c.Server.Key) == 0 {
+			add("server.cert and server.key are required when server.secure is set")
+		}
+		checkTLS(add, "server_", c.ServerTLS)
+		if len(c.ServerTLS.CertFile) > 0 || len(c.ServerTLS.KeyFile) > 0 || len(c.ServerTLS.ServerName) > 0 || c.ServerTLS.Insecure {
+			add("server_tls.cert_file, key_file, server_name and insecure are not used: the certificate is server.cert and server.key")
+		}
+	}
+	if c.UsesSql() && !c.Sql.Mock {
+		if len(c.Sql.DataSourceName) == 0 && len(c.Sql.Host) == 0 {
+			add("sql.data_source_name is required (set APP_SQL_DATA_SOURCE_NAME or APP_SQL_DATA_SOURCE_NAME_FILE)")
+		}
+		if len(c.Sql.Driver) == 0 && len(c.Sql.DataSourceName) == 0 {
+			add("sql.driver is required")
+		}
+	}
+	switch c.User.Repository {
+	case "", user.RepositorySql:
+	case user.RepositoryClient:
+		if len(c.Client.Endpoint.Url) == 0 {
+			add("client.endpoint.url is required when user.repository is %s", user.RepositoryClient)
+		}
+	default:
+		add("user.repository %q must be %s or %s", c.User.Repository, user.RepositorySql, user.RepositoryClient)
+	}
+	if !ValidLevel(c.Log.Level) {
+		add("log.level %q is invalid", c.Log.Level)
+	}
+	if _, err := mask.NewMasker(c.Mask); err != nil {
+		add("mask: %s", err.Error())
+	}
+	if len(c.Client.Endpoint.Url) > 0 {
+		checkUrl(add, "client.endpoint.url", c.Client.Endpoint.Url)
+	}
+	if len(c.Client.Health) > 0 {
+		checkUrl(add, "client.health", c.Client.Health)
+	}
+	if c.Client.Endpoint.Timeout != nil && *c.Client.Endpoint.Timeout < 0 {
+		add("client.endpoint.timeout must not be negative")
+	}
+	if c.Client.Endpoint.MaxBodySize < 0 {
+		add("client.endpoint.max_body_size must not be negative")
+	}
+	if c.Client.Log != nil && c.Client.Log.Sample < 0 {
+		add("client.log.sample must not be negative")
+	}
+	if r := c.Client.Endpoint.Retry; r != nil {
+		if r.MaxAttempts < 0 || r.BaseDelay < 0 || r.MaxDelay < 0 {
+			add("client.endpoint.retry must not have negative values")
+		}
+		if _, err := client.NewRetryTransport(nil, *r, nil); err != nil {
+			add("client.endpoint.%s", err.Error())
+		}
+	}
+	if b := c.Client.Endpoint.Breaker; b != nil && b.Enabled {
+		if b.MinRequests < 0 || b.ConsecutiveFailures < 0 || b.Probes < 0 || b.Window < 0 || b.Cooldown < 0 {
+			add("client.endpoint.breaker must not have negative values")
+		}
+		if _, err := client.NewBreaker(nil, *b); err != nil {
+			add("client.endpoint.%s", err.Error())
+		}
+	}
+	if a := c.Client.Endpoint.Auth; a != nil {
+		if _, err := client.Auth(*a); err != nil {
+			add("client.endpoint.%s", err.Error())
+		}
+	}
+	if sg := c.Client.Endpoint.Signing; sg != nil && len(sg.KeyId) > 0 {
+		var pathErr *fs.PathError
+		if _, err := client.NewSigner(*sg); err != nil && !errors.As(err, &pathErr) {
+			add("client.endpoint.%s", err.Error())
+		}
+		checkFile(add, "client.endpoint.signing.secret_file", sg.SecretFile)
+		if a := c.Client.Endpoint.Auth; a != nil && a.Type == client.AuthHmac {
+			add("client.endpoint.auth of type hmac and client.endpoint.signing cannot be both set")
+		}
+	}
+	if o := c.Client.Endpoint.OAuth2; o != nil && len(o.TokenUrl) > 0 {
+		checkUrl(add, "client.endpoint.oauth2.token_url", o.TokenUrl)
+		if _, err := client.NewClientCredentials(*o, nil); err != nil {
+			add("client.endpoint.%s", err.Error())
+		}
+		checkFile(add, "client.endpoint.oauth2.client_secret_file", o.ClientSecretFile)
+		if c.Client.Endpoint.Auth != nil {
+			add("client.endpoint.auth and client.endpoint.oauth2 cannot be both set")
+		}
+	}
+	if t := c.Client.Endpoint.TLS; t != nil {
+		checkTLS(add, "client.endpoint.", *t)
+		if len(c.Client.Endpoint.CertFile) > 0 || c.Client.Endpoint.Insecure != nil {
+			add("client.endpoint.tls cannot be set with client.endpoint.cert_file or client.endpoint.insecure")
+		}
+		if len(t.ClientAuth) > 0 {
+			add("client.endpoint.tls.client_auth is only used by the server")
+		}
+	}
+	if c.Auth.Enabled {
+		if len(c.Auth.SecretFile) == 0 && len(c.Auth.KeyFiles) == 0 && len(c.Auth.Jwks) == 0 && !c.Auth.ApiKeys && !c.Auth.ClientCerts && len(c.Auth.SigningKeys) == 0 {
+			add("auth is enabled but none of auth.secret_file, auth.key_files, auth.jwks, auth.api_keys, auth.client_certs or auth.signing_keys is set")
+		}
+		if c.Auth.ClientCerts {
+			if a, _ := tlsconfig.ClientAuth(c.ServerTLS.ClientAuth); !c.Server.Secure || a < tls.VerifyClientCertIfGiven {
+				add("auth.client_certs requires server.secure and server_tls.client_auth %s or %s", tlsconfig.ClientAuthVerifyIfGiven, tlsconfig.ClientAuthRequireAndVerify)
+			}
+		}
+		checkFile(add, "auth.secret_file", c.Auth.SecretFile)
+		for kid, file := range c.Auth.KeyFiles {
+			checkFile(add, "auth.key_files."+kid, file)
+		}
+		if strings.HasPrefix(c.Auth.Jwks, "http://") || strings.HasPrefix(c.Auth.Jwks, "https://") {
+			checkUrl(add, "auth.jwks", c.Auth.Jwks)
+		} else {
+			checkFile(add, "auth.jwks", c.Auth.Jwks)
+		}
+		for id, file := range c.Auth.SigningKeys {
+			checkFile(add, "auth.signing_keys."+id, file)
+		}
+		if c.Auth.SignatureWindow < 0 {
+			add("auth.signature_window must not be negative")
+		}
+		checkFile(add, "auth.policy_file", c.Auth.PolicyFile)
+	}
+	if _, err := ratelimit.ParseProxies(c.RateLimit.TrustedProxies); err != nil {
+		add("rate_limit.trusted_proxies: %s", err.Error())
+	}
+	if c.RateLimit.Enabled {
+		checkLimit(add, "rate_limit.ip", c.RateLimit.IP)
+		checkLimit(add, "rate_limit.default", c.RateLimit.Default)
+		for i, route := range c.RateLimit.Routes {
+			if len(route.Path) == 0 {
+				add("rate_limit.routes[%d].path is required", i)
+			}
+			checkLimit(add, fmt.Sprintf("rate_limit.routes[%d].limit", i), route.Limit)
+		}
+	}
+	if c.Shutdown.Delay < 0 || c.Shutdown.Timeout < 0 {
+		add("shutdown.delay and shutdown.timeout must not be negative")
+	}
+	if c.Health.Timeout < 0 || c.Health.Cache < 0 {
+		add("health.timeout and health.cache must not be negative")
+	}
+	if c.Health.MaxInUse < 0 || c.Health.MaxInUse > 1 {
+		add("health.max_in_use must be between 0 and 1")
+	}
+	if c.Metrics.Enabled {
+		if len(c.Metrics.Path) > 0 && !strings.HasPrefix(c.Metrics.Path, "/") {
+			add("metrics.path must start with /")
+		}
+		if c.Metrics.Port != nil && c.Server.Port != nil && *c.Metrics.Port == *c.Server.Port {
+			add("metrics.port must differ from server.port")
+		}
+	}
+	if c.Admin.Enabled && !c.Auth.Enabled {
+		add("admin.enabled requires auth.enabled: the admin endpoints are protected by the auth.admin_role role")
+	}
+	if c.Admin.Enabled && c.Admin.Port == nil {
+		add("admin.port is required when admin.enabled is set")
+	}
+	if c.Admin.Enabled && c.Admin.Port != nil {
+		if *c.Admin.Port <= 0 || *c.Admin.Port > 65535 {
+			add("admin.port must be between 1 and 65535")
+		}
+		if c.Server.Port != nil && *c.Admin.Port == *c.Server.Port {
+			add("admin.port must differ from server.port")
+		}
+		if c.Metrics.Port != nil && *c.Admin.Port == *c.Metrics.Port {
+			add("admin.port must differ from metrics.port")
+		}
+	}
+	if c.Tracing.Enabled {
+		switch c.Tracing.Exporter {
+		case "", tracing.ExporterStdout:
+		case tracing.ExporterFile:
+			if len(c.Tracing.File) == 0 {
+				add("tracing.file is required for the file exporter")
+			}
+		default:
+			add("tracing.exporter %q is not supported", c.Tracing.Exporter)
+		}
+		if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+			add("tracing.sample_ratio must be between 0 and 1")
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func ValidLevel(level string) bool {
+	switch strings.ToLower(level) {
+	case "", "debug", "info", "warn", "error", "dpanic", "panic", "fatal":
+		return true
+	}
+	return false
+}
+func checkFile(add func(string, ...interface{}), key string, file string) {
+	if len(file) == 0 {
+		return
+	}
+	if _, err := os.Stat(file); err != nil {
+		add("%s: %s", key, err.Error())
+	}
+}
+
+// checkTLS reports the errors of NewStore, and every missing file rather than the first one.
+func checkTLS(add func(string, ...interface{}), prefix string, c tlsconfig.Config) {
+	for _, file := range c.CAFiles {
+		checkFile(add, prefix+"tls.ca_files", file)
+	}
+	checkFile(add, prefix+"tls.cert_file", c.CertFile)
+	checkFile(add, prefix+"tls.key_file", c.KeyFile)
+	if c.ReloadInterval < 0 {
+		add("%stls.reload_interval must not be negative", prefix)
+	}
+	if _, err := tlsconfig.NewStore(c, nil); err != nil {
+		if _, ok := err.(*fs.PathError); !ok {
+			add("%s%s", prefix, err.Error())
+		}
+	}
+}
+func checkUrl(add func(string, ...interface{}), key string, s string) {
+	u, err := url.Parse(s)
+	if err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+		add("%s %q is not an absolute url", key, s)
+	}
+}
+func checkLimit(add func(string, ...interface{}), key string, l ratelimit.Limit) {
+	if l.Requests < 0 || l.Period < 0 || l.Burst < 0 {
+		add("%s must not have negative values", key)
+	}
+	if l.Requests > 0 && l.Period == 0 {
+		add("%s.period is required when requests is set", key)
+	}
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-service/internal/user"
+)
+
+func validConfig() Config {
+	port := int64(8080)
+	var c Config
+	c.Server.Port = &port
+	c.Sql.Driver = "postgres"
+	c.Sql.DataSourceName = "postgres://localhost/users"
+	c.User.Repository = user.RepositorySql
+	return c
+}
+
+func TestValidate(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	c := validConfig()
+	c.Server.Port = nil
+	c.Sql.DataSourceName = ""
+	c.Log.Level = "verbose"
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("Validate() must fail")
+	}
+	for _, s := range []string{"server.port", "sql.data_source_name", "log.level"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error %q does not report %s", err, s)
+		}
+	}
+}
+
+func TestValidateJwks(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(file, []byte(`{"keys":[]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		jwks  string
+		valid bool
+	}{
+		{"https://idp.example.com/.well-known/jwks.json", true},
+		{file, true},
+		{filepath.Join(t.TempDir(), "missing.json"), false},
+		{"https://", false},
+	}
+	for _, tt := range tests {
+		c := validConfig()
+		c.Auth.Enabled = true
+		c.Auth.Jwks = tt.jwks
+		if err := c.Validate(); (err == nil) != tt.valid {
+			t.Errorf("auth.jwks %q: Validate() = %v", tt.jwks, err)
+		}
+	}
+}
+
+func TestValidateAdmin(t *testing.T) {
+	port := int64(8090)
+	tests := []struct {
+		name  string
+		auth  bool
+		port  *int64
+		valid bool
+	}{
+		{"with auth and port", true, &port, true},
+		{"without auth", false, &port, false},
+		{"without port", true, nil, false},
+	}
+	for _, tt := range tests {
+		c := validConfig()
+		c.Admin.Enabled = true
+		c.Admin.Port = tt.port
+		c.Auth.Enabled = tt.auth
+		c.Auth.ApiKeys = tt.auth
+		if err := c.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate() = %v", tt.name, err)
+		}
+	}
+	c := validConfig()
+	c.Admin.Port = c.Server.Port
+	if err := c.Validate(); err != nil {
+		t.Errorf("a disabled admin server is not checked: %v", err)
+	}
+}
+
+func TestValidateClientRepository(t *testing.T) {
+	c := validConfig()
+	c.Sql.Driver, c.Sql.DataSourceName = "", ""
+	c.User.Repository = user.RepositoryClient
+	c.Client.Endpoint.Url = "http://users.internal:8080/users"
+	if err := c.Validate(); err != nil || c.UsesSql() {
+		t.Errorf("Validate() = %v, UsesSql() = %v; the client repository needs no database", err, c.UsesSql())
+	}
+	c.Auth.Enabled, c.Auth.ApiKeys = true, true
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "sql.data_source_name") {
+		t.Errorf("Validate() = %v, want the database required by the API keys", err)
+	}
+}
@@ -1,157 +1,116 @@
 package client
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"net/url"
+
+	"github.com/core-go/search"
 
 	"go-service/internal/user/domain"
+	"go-service/internal/user/port"
+	"go-service/pkg/client"
 )
 
+var _ port.UserRepository = (*UserClient)(nil)
+
 type UserClient struct {
-	Client *http.Client
-	Url    string
+	Client   *http.Client
+	Url      string
+	Header   map[string]string
+	Config   *client.LogConfig
+	LogError func(context.Context, string, map[string]interface{})
+	LogInfo  func(context.Context, string, map[string]interface{})
 }
 
-func NewUserClient(client *http.Client, url string) *UserClient {
-	return &UserClient{Client: client, Url: url}
+// NewUserClient uses the middlewares of the client, such as tracing and request id propagation, as built by client.InitClient.
+func NewUserClient(httpClient *http.Client, url string, header map[string]string, conf *client.LogConfig, logError func(context.Context, string, map[string]interface{}), logInfo func(context.Context, string, map[string]interface{})) *UserClient {
+	return &UserClient{Client: httpClient, Url: url, Header: header, Config: conf, LogError: logError, LogInfo: logInfo}
 }
 
+// Load returns nil when the user does not exist. Other failures are returned as *client.HttpError.
 func (c *UserClient) Load(ctx context.Context, id string) (*domain.User, error) {
-	requestURL := fmt.Sprintf("%s/%s", c.Url, id)
-
-	req, err := http.NewRequest("GET", requestURL, nil)
-	if err != nil {
-		panic(err)
-	}
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		panic(err)
-	}
-
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		panic(err)
-	}
-
-	var res domain.User
-	err = json.Unmarshal(body, &res)
-	if err != nil {
-		panic(err)
-	}
-
-	return &res, err
+	var user *domain.User
+	if err := c.do(ctx, http.MethodGet, c.path(id), nil, &user); err != nil {
+		if client.IsStatus(err, http.StatusNotFound) {
+			return nil, nil
+		}
+		return nil, decodeError(err, "decode user "+id)
+	}
+	return user, nil
 }
 
+// Create returns a *client.HttpError with the decoded Errors when the remote service rejects the user with 422.
 func (c *UserClient) Create(ctx context.Context, user *domain.User) (int64, error) {
-	requestURL := c.Url
-
-	data, err := json.Marshal(user)
-	req, err := http.NewRequest("POST", requestURL, bytes.NewBuffer(data))
-	if err != nil {
-		panic(err)
-	}
-
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		panic(err)
-	}
-
-	defer resp.Body.Close()
-
-	if err != nil {
-		panic(err)
-	}
-
-	if resp.StatusCode == 201 {
-		return 1, err
-	} else {
-		return 0, err
-	}
+	return c.write(ctx, http.MethodPost, c.Url, user)
 }
 
 func (c *UserClient) Update(ctx context.Context, user *domain.User) (int64, error) {
-	requestURL := fmt.Sprintf("%s/%s", c.Url, user.Id)
-
-	data, err := json.Marshal(user)
-	req, err := http.NewRequest("PUT", requestURL, bytes.NewBuffer(data))
-	if err != nil {
-		panic(err)
-	}
-
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		panic(err)
-	}
-
-	defer resp.Body.Close()
-
-	if err != nil {
-		panic(err)
-	}
-
-	if resp.StatusCode == 200 {
-		return 1, err
-	} else {
-		return 0, err
-	}
+	return c.write(ctx, http.MethodPut, c.path(user.Id), user)
 }
 
 func (c *UserClient) Patch(ctx context.Context, user map[string]interface{}) (int64, error) {
-	id := user["id"]
-	requestURL := fmt.Sprintf("%s/%s", c.Url, id)
-
-	data, err := json.Marshal(user)
-	req, err := http.NewRequest("PATCH", requestURL, bytes.NewBuffer(data))
-	if err != nil {
-		panic(err)
-	}
-
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		panic(err)
-	}
-
-	defer resp.Body.Close()
-
-	if err != nil {
-		panic(err)
-	}
-
-	if resp.StatusCode == 200 {
-		return 1, err
-	} else {
-		return 0, err
+	id, _ := user["id"].(string)
+	if len(id) == 0 {
+		return -1, errors.New("id is required to patch user")
 	}
+	return c.write(ctx, http.MethodPatch, c.path(id), user)
 }
 
 func (c *UserClient) Delete(ctx context.Context, id string) (int64, error) {
-	requestURL := fmt.Sprintf("%s/%s", c.Url, id)
+	return c.write(ctx, http.MethodDelete, c.path(id), nil)
+}
 
-	req, err := http.NewRequest("DELETE", requestURL, nil)
+// Search posts the filter to /search and decodes the search.Result of the remote handler.
+func (c *UserClient) Search(ctx context.Context, filter *domain.UserFilter) ([]domain.User, int64, error) {
+	body, err := json.Marshal(filter)
 	if err != nil {
-		panic(err)
+		return nil, 0, err
 	}
-
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		panic(err)
+	var users []domain.User
+	res := search.Result{List: &users}
+	if err = c.do(ctx, http.MethodPost, c.Url+"/search", body, &res); err != nil {
+		return nil, 0, decodeError(err, "decode search result")
 	}
+	return users, res.Total, nil
+}
 
-	defer resp.Body.Close()
+// write returns the number of affected rows sent by the remote handler, and 0 when the user does not exist.
+func (c *UserClient) write(ctx context.Context, method string, url string, obj interface{}) (int64, error) {
+	var body []byte
+	if obj != nil {
+		var err error
+		body, err = json.Marshal(obj)
+		if err != nil {
+			return -1, err
+		}
+	}
+	var res int64
+	if err := c.do(ctx, method, url, body, &res); err != nil {
+		if client.IsStatus(err, http.StatusNotFound) {
+			return 0, nil
+		}
+		return -1, decodeError(err, "decode "+method+" "+url)
+	}
+	return res, nil
+}
 
-	if err != nil {
-		panic(err)
-	}
+// do decodes the response as it is read, so that a large search result is not buffered.
+func (c *UserClient) do(ctx context.Context, method string, url string, body []byte, result interface{}) error {
+	return client.DoAndDecode(ctx, c.Client, method, url, body, c.Header, result, c.Config, c.LogError, c.LogInfo)
+}
 
-	if resp.StatusCode == 200 {
-		return 1, err
-	} else {
-		return 0, err
+// decodeError returns the *client.HttpError of the call as is, and wraps the errors of the decoding.
+func decodeError(err error, msg string) error {
+	if _, ok := client.IsHttpError(err); ok {
+		return err
 	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+func (c *UserClient) path(id string) string {
+	return c.Url + "/" + url.PathEscape(id)
 }
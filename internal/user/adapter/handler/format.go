@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/core-go/core"
+	s "github.com/core-go/search"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"go-service/internal/user/domain"
+)
+
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeXML     = "application/xml"
+	ContentTypeCSV     = "text/csv"
+	ContentTypeMsgpack = "application/msgpack"
+)
+
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+var aliases = map[string]string{
+	"application/json":        ContentTypeJSON,
+	"text/json":               ContentTypeJSON,
+	"application/xml":         ContentTypeXML,
+	"text/xml":                ContentTypeXML,
+	"text/csv":                ContentTypeCSV,
+	"application/csv":         ContentTypeCSV,
+	"application/msgpack":     ContentTypeMsgpack,
+	"application/x-msgpack":   ContentTypeMsgpack,
+	"application/vnd.msgpack": ContentTypeMsgpack,
+}
+
+var (
+	ObjectTypes = []string{ContentTypeJSON, ContentTypeXML, ContentTypeMsgpack}
+	ListTypes   = []string{ContentTypeJSON, ContentTypeXML, ContentTypeCSV, ContentTypeMsgpack}
+)
+
+type accept struct {
+	mediaType string
+	q         float64
+}
+
+func Negotiate(r *http.Request, offers []string) (string, bool) {
+	header := r.Header.Get("Accept")
+	if len(strings.TrimSpace(header)) == 0 {
+		return offers[0], true
+	}
+	accepts := parseAccept(header)
+	for _, a := range accepts {
+		if a.q <= 0 {
+			continue
+		}
+		for _, offer := range offers {
+			if matchMediaType(a.mediaType, offer) && !isRejected(accepts, offer) {
+				return offer, true
+			}
+		}
+	}
+	return "", false
+}
+func parseAccept(header string) []accept {
+	parts := strings.Split(header, ",")
+	accepts := make([]accept, 0, len(parts))
+	for _, part := range parts {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, er1 := strconv.ParseFloat(v, 64); er1 == nil {
+				q = f
+			}
+		}
+		accepts = append(accepts, accept{mediaType: mediaType, q: q})
+	}
+	sort.SliceStable(accepts, func(i, j int) bool {
+		if accepts[i].q != accepts[j].q {
+			return accepts[i].q > accepts[j].q
+		}
+		return specificity(accepts[i].mediaType) > specificity(accepts[j].mediaType)
+	})
+	return accepts
+}
+func specificity(mediaType string) int {
+	if mediaType == "*/*" {
+		return 0
+	}
+	if strings.HasSuffix(mediaType, "/*") {
+		return 1
+	}
+	return 2
+}
+func matchMediaType(pattern string, offer string) bool {
+	if pattern == "*/*" {
+		return true
+	}
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(offer, strings.TrimSuffix(pattern, "*"))
+	}
+	return normalize(pattern) == offer
+}
+func isRejected(accepts []accept, offer string) bool {
+	for _, a := range accepts {
+		if a.q <= 0 && specificity(a.mediaType) == 2 && normalize(a.mediaType) == offer {
+			return true
+		}
+	}
+	return false
+}
+func normalize(mediaType string) string {
+	if t, ok := aliases[mediaType]; ok {
+		return t
+	}
+	return mediaType
+}
+
+func Decode(r *http.Request, obj interface{}) error {
+	contentType := ContentTypeJSON
+	if header := r.Header.Get("Content-Type"); len(header) > 0 {
+		mediaType, _, err := mime.ParseMediaType(header)
+		if err != nil {
+			return ErrUnsupportedMediaType
+		}
+		contentType = normalize(mediaType)
+	}
+	switch contentType {
+	case ContentTypeJSON:
+		return json.NewDecoder(r.Body).Decode(obj)
+	case ContentTypeXML:
+		return xml.NewDecoder(r.Body).Decode(obj)
+	case ContentTypeMsgpack:
+		decoder := msgpack.NewDecoder(r.Body)
+		decoder.SetCustomStructTag("json")
+		return decoder.Decode(obj)
+	default:
+		return ErrUnsupportedMediaType
+	}
+}
+func IsJSON(r *http.Request) bool {
+	header := r.Header.Get("Content-Type")
+	if len(header) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	return err == nil && normalize(mediaType) == ContentTypeJSON
+}
+
+func Write(w http.ResponseWriter, contentType string, code int, res interface{}) error {
+	switch contentType {
+	case ContentTypeXML:
+		w.Header().Set("Content-Type", ContentTypeXML)
+		w.WriteHeader(code)
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		return xml.NewEncoder(w).Encode(toXML(res))
+	case ContentTypeMsgpack:
+		w.Header().Set("Content-Type", ContentTypeMsgpack)
+		w.WriteHeader(code)
+		// MessagePack uses the json names, so the structs need no msgpack tags.
+		encoder := msgpack.NewEncoder(w)
+		encoder.SetCustomStructTag("json")
+		return encoder.Encode(res)
+	case ContentTypeCSV:
+		w.Header().Set("Content-Type", ContentTypeCSV)
+		w.WriteHeader(code)
+		return CSV(w, res)
+	default:
+		return JSON(w, code, res)
+	}
+}
+
+type xmlUsers struct {
+	XMLName xml.Name      `xml:"users"`
+	Total   int64         `xml:"total,attr"`
+	List    []domain.User `xml:"user"`
+}
+type xmlErrors struct {
+	XMLName xml.Name   `xml:"errors"`
+	Errors  []xmlError `xml:"error"`
+}
+type xmlError struct {
+	Field   string `xml:"field,omitempty"`
+	Code    string `xml:"code,omitempty"`
+	Param   string `xml:"param,omitempty"`
+	Message string `xml:"message,omitempty"`
+}
+type xmlUser struct {
+	XMLName xml.Name `xml:"user"`
+	*domain.User
+}
+type xmlResult struct {
+	XMLName xml.Name `xml:"result"`
+	Value   int64    `xml:",chardata"`
+}
+
+func toXML(res interface{}) interface{} {
+	switch v := res.(type) {
+	case *domain.User:
+		return xmlUser{User: v}
+	case *s.Result:
+		users := xmlUsers{Total: v.Total}
+		if list, ok := v.List.(*[]domain.User); ok && list != nil {
+			users.List = *list
+		}
+		return users
+	case []core.ErrorMessage:
+		errs := xmlErrors{Errors: make([]xmlError, len(v))}
+		for i, e := range v {
+			errs.Errors[i] = xmlError{Field: e.Field, Code: e.Code, Param: e.Param, Message: e.Message}
+		}
+		return errs
+	case int64:
+		return xmlResult{Value: v}
+	default:
+		return res
+	}
+}
+
+func CSV(w io.Writer, res interface{}) error {
+	var list interface{}
+	if result, ok := res.(*s.Result); ok {
+		list = result.List
+	} else {
+		list = res
+	}
+	value := reflect.Indirect(reflect.ValueOf(list))
+	if value.Kind() != reflect.Slice {
+		return fmt.Errorf("cannot write %T as csv", res)
+	}
+	itemType := value.Type().Elem()
+	var names []string
+	var indexes []int
+	for i := 0; i < itemType.NumField(); i++ {
+		tag := itemType.Field(i).Tag.Get("csv")
+		if len(tag) == 0 || tag == "-" {
+			continue
+		}
+		names = append(names, tag)
+		indexes = append(indexes, i)
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(names); err != nil {
+		return err
+	}
+	for i := 0; i < value.Len(); i++ {
+		item := value.Index(i)
+		row := make([]string, len(indexes))
+		for j, index := range indexes {
+			row[j] = formatCSV(item.Field(index))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+func formatCSV(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	s "github.com/core-go/search"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"go-service/internal/user/domain"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		offers []string
+		want   string
+		ok     bool
+	}{
+		{"", ObjectTypes, ContentTypeJSON, true},
+		{"  ", ListTypes, ContentTypeJSON, true},
+		{"application/json", ObjectTypes, ContentTypeJSON, true},
+		{"text/xml", ObjectTypes, ContentTypeXML, true},
+		{"application/x-msgpack", ObjectTypes, ContentTypeMsgpack, true},
+		{"application/vnd.msgpack", ObjectTypes, ContentTypeMsgpack, true},
+		{"text/csv", ObjectTypes, "", false},
+		{"text/csv", ListTypes, ContentTypeCSV, true},
+		{"application/csv", ListTypes, ContentTypeCSV, true},
+		{"*/*", ObjectTypes, ContentTypeJSON, true},
+		{"text/*", ListTypes, ContentTypeCSV, true},
+		{"image/png", ObjectTypes, "", false},
+		{"application/json;q=0.5, application/xml", ObjectTypes, ContentTypeXML, true},
+		{"application/xml;q=0.2, application/msgpack;q=0.9", ObjectTypes, ContentTypeMsgpack, true},
+		{"*/*;q=0.8, text/csv", ListTypes, ContentTypeCSV, true},
+		{"application/json;q=0, */*", ObjectTypes, ContentTypeXML, true},
+		{"application/json;q=0", ObjectTypes, "", false},
+		{"text/html, application/*;q=0.1", ObjectTypes, ContentTypeJSON, true},
+		{"application/json;q=abc", ObjectTypes, ContentTypeJSON, true},
+		{";;;, application/xml", ObjectTypes, ContentTypeXML, true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/users/ironman", nil)
+		if len(tt.accept) > 0 {
+			r.Header.Set("Accept", tt.accept)
+		}
+		got, ok := Negotiate(r, tt.offers)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Negotiate(%q) = %q, %v; want %q, %v", tt.accept, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	packed, err := msgpack.Marshal(map[string]interface{}{"id": "ironman", "username": "tony.stark", "phone": "0987654321"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		err         error
+	}{
+		{"default", "", []byte(`{"id":"ironman","username":"tony.stark","phone":"0987654321"}`), nil},
+		{"json", "application/json; charset=utf-8", []byte(`{"id":"ironman","username":"tony.stark","phone":"0987654321"}`), nil},
+		{"xml", "text/xml", []byte(`<user><id>ironman</id><username>tony.stark</username><phone>0987654321</phone></user>`), nil},
+		{"msgpack", "application/x-msgpack", packed, nil},
+		{"csv", "text/csv", []byte("id\nironman\n"), ErrUnsupportedMediaType},
+		{"unknown", "text/plain", []byte("ironman"), ErrUnsupportedMediaType},
+		{"invalid", "application/", []byte("{}"), ErrUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(tt.body))
+		if len(tt.contentType) > 0 {
+			r.Header.Set("Content-Type", tt.contentType)
+		}
+		var user domain.User
+		err := Decode(r, &user)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: Decode() error = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if tt.err == nil && (user.Id != "ironman" || user.Username != "tony.stark" || user.Phone != "0987654321") {
+			t.Errorf("%s: Decode() = %+v", tt.name, user)
+		}
+	}
+}
+
+func TestIsJSON(t *testing.T) {
+	for contentType, want := range map[string]bool{
+		"":                                true,
+		"application/json":                true,
+		"text/json; charset=utf-8":        true,
+		"application/merge-patch+json":    false,
+		"application/xml":                 false,
+		"application/json; charset=\"utf": false,
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/users", nil)
+		if len(contentType) > 0 {
+			r.Header.Set("Content-Type", contentType)
+		}
+		if got := IsJSON(r); got != want {
+			t.Errorf("IsJSON(%q) = %v, want %v", contentType, got, want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dob := time.Date(1963, 3, 25, 0, 0, 0, 0, time.UTC)
+	user := &domain.User{Id: "ironman", Username: "tony.stark", Email: "tony.stark@gmail.com", Phone: "0987654321", DateOfBirth: &dob}
+	users := []domain.User{*user, {Id: "spiderman", Username: "peter.parker"}}
+	result := &s.Result{List: &users, Total: 2}
+	tests := []struct {
+		name        string
+		contentType string
+		res         interface{}
+		want        string
+	}{
+		{"json", ContentTypeJSON, user, `{"id":"ironman","username":"tony.stark","email":"tony.stark@gmail.com","phone":"0987654321","dateOfBirth":"1963-03-25T00:00:00Z"}`},
+		{"xml user", ContentTypeXML, user, `<user><id>ironman</id><username>tony.stark</username><email>tony.stark@gmail.com</email><phone>0987654321</phone><dateOfBirth>1963-03-25T00:00:00Z</dateOfBirth></user>`},
+		{"xml result", ContentTypeXML, result, `<users total="2"><user><id>ironman</id>`},
+		{"xml count", ContentTypeXML, int64(1), `<result>1</result>`},
+		{"csv", ContentTypeCSV, result, "id,username,email,phone,dateOfBirth\nironman,tony.stark,tony.stark@gmail.com,0987654321,1963-03-25T00:00:00Z\nspiderman,peter.parker,,,\n"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		if err := Write(w, tt.contentType, http.StatusOK, tt.res); err != nil {
+			t.Errorf("%s: Write() error = %v", tt.name, err)
+			continue
+		}
+		if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.contentType) {
+			t.Errorf("%s: Content-Type = %q, want %q", tt.name, got, tt.contentType)
+		}
+		if body := w.Body.String(); !strings.Contains(body, tt.want) {
+			t.Errorf("%s: body = %q, want it to contain %q", tt.name, body, tt.want)
+		}
+	}
+}
+
+func TestWriteMsgpackUsesJSONNames(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := Write(w, ContentTypeMsgpack, http.StatusOK, &domain.User{Id: "ironman", Username: "tony.stark"}); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := msgpack.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["id"] != "ironman" || got["username"] != "tony.stark" {
+		t.Errorf("msgpack body = %v, want the json field names", got)
+	}
+	if _, ok := got["Id"]; ok {
+		t.Errorf("msgpack body = %v, has the Go field names", got)
+	}
+}
+
+func TestCSVRejectsObject(t *testing.T) {
+	var b bytes.Buffer
+	if err := CSV(&b, &domain.User{Id: "ironman"}); err == nil {
+		t.Error("CSV() of an object should fail")
+	}
+}
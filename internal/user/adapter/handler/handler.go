@@ -3,6 +3,7 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"reflect"
 
@@ -12,21 +13,25 @@ import (
 
 	"go-service/internal/user/domain"
 	"go-service/internal/user/service"
+	"go-service/pkg/auth"
+	"go-service/pkg/patch"
+	"go-service/pkg/problem"
 )
 
 const InternalServerError = "Internal Server Error"
 
-func NewUserHandler(service service.UserService, validate func(context.Context, interface{}) ([]core.ErrorMessage, error), logError func(context.Context, string, ...map[string]interface{})) *UserHandler {
+func NewUserHandler(service service.UserService, validate func(context.Context, interface{}) ([]core.ErrorMessage, error), mask func(map[string]interface{}), logError func(context.Context, string, ...map[string]interface{})) *UserHandler {
 	userType := reflect.TypeOf(domain.User{})
 	_, jsonMap, _ := core.BuildMapField(userType)
 	filterType := reflect.TypeOf(domain.UserFilter{})
 	paramIndex, filterIndex := s.BuildParams(filterType)
-	return &UserHandler{service: service, Validate: validate, jsonMap: jsonMap, LogError: logError, paramIndex: paramIndex, filterIndex: filterIndex}
+	return &UserHandler{service: service, Validate: validate, Mask: mask, jsonMap: jsonMap, LogError: logError, paramIndex: paramIndex, filterIndex: filterIndex}
 }
 
 type UserHandler struct {
 	service     service.UserService
 	Validate    func(context.Context, interface{}) ([]core.ErrorMessage, error)
+	Mask        func(map[string]interface{})
 	LogError    func(context.Context, string, ...map[string]interface{})
 	jsonMap     map[string]int
 	paramIndex  map[string]int
@@ -34,150 +39,206 @@ type UserHandler struct {
 }
 
 func (h *UserHandler) Load(w http.ResponseWriter, r *http.Request) {
+	format, ok := Negotiate(r, ObjectTypes)
+	if !ok {
+		problem.Error(w, r, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return
+	}
 	id := mux.Vars(r)["id"]
 	if len(id) == 0 {
-		http.Error(w, "Id cannot be empty", http.StatusBadRequest)
+		problem.Error(w, r, "Id cannot be empty", http.StatusBadRequest)
 		return
 	}
 
 	user, err := h.service.Load(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if Forbidden(w, r, err) {
+			return
+		}
+		problem.Error(w, r, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	JSON(w, IsFound(user), user)
+	Write(w, format, IsFound(user), user)
 }
 func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
+	format, ok := Negotiate(r, ObjectTypes)
+	if !ok {
+		problem.Error(w, r, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return
+	}
 	var user domain.User
-	er1 := json.NewDecoder(r.Body).Decode(&user)
+	er1 := Decode(r, &user)
 	defer r.Body.Close()
 	if er1 != nil {
-		http.Error(w, er1.Error(), http.StatusBadRequest)
+		if er1 == ErrUnsupportedMediaType {
+			problem.Error(w, r, er1.Error(), http.StatusUnsupportedMediaType)
+			return
+		}
+		problem.Error(w, r, er1.Error(), http.StatusBadRequest)
 		return
 	}
 	errors, er2 := h.Validate(r.Context(), &user)
 	if er2 != nil {
 		h.LogError(r.Context(), er2.Error())
-		http.Error(w, InternalServerError, http.StatusInternalServerError)
+		problem.Error(w, r, InternalServerError, http.StatusInternalServerError)
 		return
 	}
 	if len(errors) > 0 {
-		JSON(w, http.StatusUnprocessableEntity, errors)
+		Write(w, format, http.StatusUnprocessableEntity, errors)
 		return
 	}
 	res, er3 := h.service.Create(r.Context(), &user)
 	if er3 != nil {
-		h.LogError(r.Context(), er3.Error(), MakeMap(user))
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		if Forbidden(w, r, er3) {
+			return
+		}
+		h.LogError(r.Context(), er3.Error(), MakeMap(user, h.Mask))
+		problem.Error(w, r, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-	JSON(w, http.StatusCreated, res)
+	Write(w, format, http.StatusCreated, res)
 }
 func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
+	format, ok := Negotiate(r, ObjectTypes)
+	if !ok {
+		problem.Error(w, r, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return
+	}
 	var user domain.User
-	er1 := json.NewDecoder(r.Body).Decode(&user)
+	er1 := Decode(r, &user)
 	defer r.Body.Close()
 	if er1 != nil {
-		http.Error(w, er1.Error(), http.StatusBadRequest)
+		if er1 == ErrUnsupportedMediaType {
+			problem.Error(w, r, er1.Error(), http.StatusUnsupportedMediaType)
+			return
+		}
+		problem.Error(w, r, er1.Error(), http.StatusBadRequest)
 		return
 	}
 	id := mux.Vars(r)["id"]
 	if len(id) == 0 {
-		http.Error(w, "Id cannot be empty", http.StatusBadRequest)
+		problem.Error(w, r, "Id cannot be empty", http.StatusBadRequest)
 		return
 	}
 	if len(user.Id) == 0 {
 		user.Id = id
 	} else if id != user.Id {
-		http.Error(w, "Id not match", http.StatusBadRequest)
+		problem.Error(w, r, "Id not match", http.StatusBadRequest)
 		return
 	}
 	errors, er2 := h.Validate(r.Context(), &user)
 	if er2 != nil {
 		h.LogError(r.Context(), er2.Error())
-		http.Error(w, InternalServerError, http.StatusInternalServerError)
+		problem.Error(w, r, InternalServerError, http.StatusInternalServerError)
 		return
 	}
 	if len(errors) > 0 {
-		JSON(w, http.StatusUnprocessableEntity, errors)
+		Write(w, format, http.StatusUnprocessableEntity, errors)
 		return
 	}
 	res, er3 := h.service.Update(r.Context(), &user)
 	if er3 != nil {
-		http.Error(w, er3.Error(), http.StatusInternalServerError)
+		if Forbidden(w, r, er3) {
+			return
+		}
+		problem.Error(w, r, er3.Error(), http.StatusInternalServerError)
 		return
 	}
 	status := GetStatus(res)
-	JSON(w, status, res)
+	Write(w, format, status, res)
 }
 func (h *UserHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	format, ok := Negotiate(r, ObjectTypes)
+	if !ok {
+		problem.Error(w, r, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return
+	}
 	id := mux.Vars(r)["id"]
 	if len(id) == 0 {
-		http.Error(w, "Id cannot be empty", http.StatusBadRequest)
+		problem.Error(w, r, "Id cannot be empty", http.StatusBadRequest)
 		return
 	}
-
-	var user domain.User
-	body, er1 := core.BuildMapAndStruct(r, &user)
+	apply, er1 := DecodePatch(r)
+	defer r.Body.Close()
 	if er1 != nil {
-		http.Error(w, er1.Error(), http.StatusInternalServerError)
+		if er1 == ErrUnsupportedMediaType {
+			problem.Error(w, r, er1.Error(), http.StatusUnsupportedMediaType)
+			return
+		}
+		problem.Error(w, r, er1.Error(), http.StatusBadRequest)
 		return
 	}
-	if len(user.Id) == 0 {
-		user.Id = id
-	} else if id != user.Id {
-		http.Error(w, "Id not match", http.StatusBadRequest)
-		return
-	}
-	json, er2 := core.BodyToJsonMap(r, user, body, []string{"id"}, h.jsonMap)
+	ctx := r.Context()
+	res, er2 := h.service.Patch(ctx, id, func(user *domain.User) (map[string]interface{}, error) {
+		return h.applyPatch(ctx, user, apply)
+	})
 	if er2 != nil {
-		http.Error(w, er2.Error(), http.StatusInternalServerError)
-		return
-	}
-	r = r.WithContext(context.WithValue(r.Context(), "method", "patch"))
-	errors, er3 := h.Validate(r.Context(), &user)
-	if er3 != nil {
-		h.LogError(r.Context(), er3.Error())
-		http.Error(w, InternalServerError, http.StatusInternalServerError)
-		return
-	}
-	if len(errors) > 0 {
-		JSON(w, http.StatusUnprocessableEntity, errors)
-		return
-	}
-	res, er4 := h.service.Patch(r.Context(), json)
-	if er4 != nil {
-		http.Error(w, er4.Error(), http.StatusInternalServerError)
+		var validationErr *ValidationError
+		switch {
+		case errors.As(er2, &validationErr):
+			Write(w, format, http.StatusUnprocessableEntity, validationErr.Errors)
+		case errors.Is(er2, auth.ErrForbidden):
+			problem.Error(w, r, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		case errors.Is(er2, patch.ErrTestFailed):
+			problem.Error(w, r, er2.Error(), http.StatusConflict)
+		case errors.Is(er2, patch.ErrPathNotFound), errors.Is(er2, patch.ErrInvalidOperation), errors.Is(er2, patch.ErrInvalidPointer), errors.Is(er2, ErrInvalidDocument):
+			problem.Error(w, r, er2.Error(), http.StatusUnprocessableEntity)
+		case errors.Is(er2, ErrIdNotMatch):
+			problem.Error(w, r, er2.Error(), http.StatusBadRequest)
+		default:
+			h.LogError(ctx, er2.Error())
+			problem.Error(w, r, InternalServerError, http.StatusInternalServerError)
+		}
 		return
 	}
 	status := GetStatus(res)
-	JSON(w, status, res)
+	Write(w, format, status, res)
 }
 func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	format, ok := Negotiate(r, ObjectTypes)
+	if !ok {
+		problem.Error(w, r, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return
+	}
 	id := mux.Vars(r)["id"]
 	if len(id) == 0 {
-		http.Error(w, "Id cannot be empty", http.StatusBadRequest)
+		problem.Error(w, r, "Id cannot be empty", http.StatusBadRequest)
 		return
 	}
 	res, err := h.service.Delete(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if Forbidden(w, r, err) {
+			return
+		}
+		problem.Error(w, r, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	status := GetStatus(res)
-	JSON(w, status, res)
+	Write(w, format, status, res)
 }
 func (h *UserHandler) Search(w http.ResponseWriter, r *http.Request) {
+	format, ok := Negotiate(r, ListTypes)
+	if !ok {
+		problem.Error(w, r, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return
+	}
+	if r.Method == http.MethodPost && !IsJSON(r) {
+		problem.Error(w, r, ErrUnsupportedMediaType.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
 	filter := domain.UserFilter{Filter: &s.Filter{}}
 	s.Decode(r, &filter, h.paramIndex, h.filterIndex)
 
 	var users []domain.User
 	users, total, err := h.service.Search(r.Context(), &filter)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if Forbidden(w, r, err) {
+			return
+		}
+		problem.Error(w, r, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	JSON(w, http.StatusOK, &s.Result{List: &users, Total: total})
+	Write(w, format, http.StatusOK, &s.Result{List: &users, Total: total})
 }
 
 func JSON(w http.ResponseWriter, code int, res interface{}) error {
@@ -185,6 +246,13 @@ func JSON(w http.ResponseWriter, code int, res interface{}) error {
 	w.WriteHeader(code)
 	return json.NewEncoder(w).Encode(res)
 }
+func Forbidden(w http.ResponseWriter, r *http.Request, err error) bool {
+	if errors.Is(err, auth.ErrForbidden) {
+		problem.Error(w, r, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return true
+	}
+	return false
+}
 func GetStatus(status int64) int {
 	if status <= 0 {
 		return http.StatusNotFound
@@ -207,7 +275,7 @@ func isNil(i interface{}) bool {
 	}
 	return false
 }
-func MakeMap(res interface{}, opts ...string) map[string]interface{} {
+func MakeMap(res interface{}, mask func(map[string]interface{}), opts ...string) map[string]interface{} {
 	key := "request"
 	if len(opts) > 0 && len(opts[0]) > 0 {
 		key = opts[0]
@@ -217,6 +285,15 @@ func MakeMap(res interface{}, opts ...string) map[string]interface{} {
 	if err != nil {
 		return m
 	}
+	if mask != nil {
+		obj := make(map[string]interface{})
+		if err = json.Unmarshal(b, &obj); err == nil {
+			mask(obj)
+			if masked, err := json.Marshal(obj); err == nil {
+				b = masked
+			}
+		}
+	}
 	m[key] = string(b)
 	return m
 }
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"reflect"
+
+	"github.com/core-go/core"
+
+	"go-service/internal/user/domain"
+	"go-service/pkg/patch"
+)
+
+var (
+	ErrIdNotMatch      = errors.New("Id not match")
+	ErrInvalidDocument = errors.New("patched document is not a valid user")
+)
+
+type ValidationError struct {
+	Errors []core.ErrorMessage
+}
+
+func (e *ValidationError) Error() string {
+	return "validation failed"
+}
+
+func DecodePatch(r *http.Request) (func(interface{}) (interface{}, error), error) {
+	contentType := patch.ContentTypeMergePatch
+	if header := r.Header.Get("Content-Type"); len(header) > 0 {
+		mediaType, _, err := mime.ParseMediaType(header)
+		if err != nil {
+			return nil, ErrUnsupportedMediaType
+		}
+		contentType = mediaType
+	}
+	switch contentType {
+	case patch.ContentTypeMergePatch, ContentTypeJSON:
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		return func(doc interface{}) (interface{}, error) {
+			return patch.MergePatch(doc, body), nil
+		}, nil
+	case patch.ContentTypeJSONPatch:
+		var ops []patch.Operation
+		if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+			return nil, err
+		}
+		if err := patch.Validate(ops); err != nil {
+			return nil, err
+		}
+		return func(doc interface{}) (interface{}, error) {
+			return patch.Apply(doc, ops)
+		}, nil
+	default:
+		return nil, ErrUnsupportedMediaType
+	}
+}
+
+func (h *UserHandler) applyPatch(ctx context.Context, user *domain.User, apply func(interface{}) (interface{}, error)) (map[string]interface{}, error) {
+	original, err := toDocument(user)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := apply(original)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := patched.(map[string]interface{})
+	if !ok {
+		return nil, ErrInvalidDocument
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var result domain.User
+	if err = json.Unmarshal(b, &result); err != nil {
+		return nil, ErrInvalidDocument
+	}
+	if result.Id != user.Id {
+		return nil, ErrIdNotMatch
+	}
+	errs, err := h.Validate(ctx, &result)
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return nil, &ValidationError{Errors: errs}
+	}
+	changes := make(map[string]interface{})
+	value := reflect.ValueOf(result)
+	for name, i := range h.jsonMap {
+		if !reflect.DeepEqual(original[name], doc[name]) {
+			changes[name] = value.Field(i).Interface()
+		}
+	}
+	*user = result
+	return changes, nil
+}
+func toDocument(user *domain.User) (map[string]interface{}, error) {
+	b, err := json.Marshal(user)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	err = json.Unmarshal(b, &doc)
+	return doc, err
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"go-service/internal/user/port"
+	"go-service/pkg/tracing"
+)
+
+func NewUserTracing(handler port.UserTransport) port.UserTransport {
+	return &UserTracing{handler: handler}
+}
+
+type UserTracing struct {
+	handler port.UserTransport
+}
+
+func (h *UserTracing) Search(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "UserHandler.Search")
+	defer span.End()
+	h.handler.Search(w, r.WithContext(ctx))
+}
+func (h *UserTracing) Load(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "UserHandler.Load")
+	defer span.End()
+	h.handler.Load(w, r.WithContext(ctx))
+}
+func (h *UserTracing) Create(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "UserHandler.Create")
+	defer span.End()
+	h.handler.Create(w, r.WithContext(ctx))
+}
+func (h *UserTracing) Update(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "UserHandler.Update")
+	defer span.End()
+	h.handler.Update(w, r.WithContext(ctx))
+}
+func (h *UserTracing) Patch(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "UserHandler.Patch")
+	defer span.End()
+	h.handler.Patch(w, r.WithContext(ctx))
+}
+func (h *UserTracing) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "UserHandler.Delete")
+	defer span.End()
+	h.handler.Delete(w, r.WithContext(ctx))
+}
@@ -3,14 +3,17 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/core-go/search/query"
 	s "github.com/core-go/sql"
 
 	"go-service/internal/user/domain"
+	"go-service/pkg/tracing"
 )
 
 func NewUserAdapter(db *sql.DB, buildQuery func(*domain.UserFilter) (string, []interface{})) (*UserAdapter, error) {
@@ -25,13 +28,15 @@ func NewUserAdapter(db *sql.DB, buildQuery func(*domain.UserFilter) (string, []i
 	if err != nil {
 		return nil, err
 	}
-	return &UserAdapter{DB: db, Map: fieldsIndex, BuildQuery: buildQuery}, nil
+	jsonColumnMap := s.MakeJsonColumnMap(userType)
+	return &UserAdapter{DB: db, Map: fieldsIndex, JsonColumnMap: jsonColumnMap, BuildQuery: buildQuery}, nil
 }
 
 type UserAdapter struct {
-	DB         *sql.DB
-	Map        map[string]int
-	BuildQuery func(*domain.UserFilter) (string, []interface{})
+	DB            *sql.DB
+	Map           map[string]int
+	JsonColumnMap map[string]string
+	BuildQuery    func(*domain.UserFilter) (string, []interface{})
 }
 
 func (r *UserAdapter) Load(ctx context.Context, id string) (*domain.User, error) {
@@ -43,9 +48,21 @@ func (r *UserAdapter) Load(ctx context.Context, id string) (*domain.User, error)
 			phone,
 			date_of_birth
 		from users where id = $1`
-	rows, err := r.DB.QueryContext(ctx, query, id)
+	var rows *sql.Rows
+	var err error
+	tx := GetTx(ctx)
+	if tx != nil {
+		query = query + " for update"
+	}
+	ctx, span := tracing.StartQuery(ctx, "UserAdapter.Load", query)
+	defer span.End()
+	if tx != nil {
+		rows, err = tx.QueryContext(ctx, query, id)
+	} else {
+		rows, err = r.DB.QueryContext(ctx, query, id)
+	}
 	if err != nil {
-		return nil, err
+		return nil, tracing.Error(span, err)
 	}
 	defer rows.Close()
 	for rows.Next() {
@@ -74,10 +91,12 @@ func (r *UserAdapter) Create(ctx context.Context, user *domain.User) (int64, err
 			$3, 
 			$4,
 			$5)`
+	ctx, span := tracing.StartQuery(ctx, "UserAdapter.Create", query)
+	defer span.End()
 	tx := GetTx(ctx)
 	stmt, err := tx.Prepare(query)
 	if err != nil {
-		return -1, err
+		return -1, tracing.Error(span, err)
 	}
 	res, err := stmt.ExecContext(ctx,
 		user.Id,
@@ -86,7 +105,7 @@ func (r *UserAdapter) Create(ctx context.Context, user *domain.User) (int64, err
 		user.Phone,
 		user.DateOfBirth)
 	if err != nil {
-		return -1, err
+		return -1, tracing.Error(span, err)
 	}
 	return res.RowsAffected()
 }
@@ -99,10 +118,12 @@ func (r *UserAdapter) Update(ctx context.Context, user *domain.User) (int64, err
 			phone = $3,
 			date_of_birth = $4
 		where id = $5`
+	ctx, span := tracing.StartQuery(ctx, "UserAdapter.Update", query)
+	defer span.End()
 	tx := GetTx(ctx)
 	stmt, err := tx.Prepare(query)
 	if err != nil {
-		return -1, err
+		return -1, tracing.Error(span, err)
 	}
 	res, err := stmt.ExecContext(ctx,
 		user.Username,
@@ -111,48 +132,56 @@ func (r *UserAdapter) Update(ctx context.Context, user *domain.User) (int64, err
 		user.DateOfBirth,
 		user.Id)
 	if err != nil {
-		return -1, err
+		return -1, tracing.Error(span, err)
 	}
 	return res.RowsAffected()
 }
 func (r *UserAdapter) Patch(ctx context.Context, user map[string]interface{}) (int64, error) {
-	updateClause := "update users set"
-	whereClause := fmt.Sprintf("where id='%s'", user["id"])
-
-	setClause := make([]string, 0)
-	if user["username"] != nil {
-		msg := fmt.Sprintf("username='%s'", fmt.Sprint(user["username"]))
-		setClause = append(setClause, msg)
-	}
-	if user["email"] != nil {
-		msg := fmt.Sprintf("email='%s'", fmt.Sprint(user["email"]))
-		setClause = append(setClause, msg)
-	}
-	if user["phone"] != nil {
-		msg := fmt.Sprintf("phone='%s'", fmt.Sprint(user["phone"]))
-		setClause = append(setClause, msg)
-	}
-	setClauseRes := strings.Join(setClause, ",")
-	querySlice := []string{updateClause, setClauseRes, whereClause}
-	query := strings.Join(querySlice, " ")
+	id, ok := user["id"]
+	if !ok {
+		return -1, errors.New("id is required to patch user")
+	}
+	keys := make([]string, 0, len(user))
+	for k := range user {
+		if _, ok := r.JsonColumnMap[k]; ok && k != "id" {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return -1, errors.New("no column to patch")
+	}
+	sort.Strings(keys)
+	buildParam := s.BuildDollarParam
+	setClause := make([]string, 0, len(keys))
+	params := make([]interface{}, 0, len(keys)+1)
+	for i, k := range keys {
+		setClause = append(setClause, fmt.Sprintf("%s = %s", r.JsonColumnMap[k], buildParam(i+1)))
+		params = append(params, user[k])
+	}
+	params = append(params, id)
+	query := fmt.Sprintf("update users set %s where id = %s", strings.Join(setClause, ", "), buildParam(len(params)))
 
+	ctx, span := tracing.StartQuery(ctx, "UserAdapter.Patch", query)
+	defer span.End()
 	tx := GetTx(ctx)
-	res, err := tx.ExecContext(ctx, query)
+	res, err := tx.ExecContext(ctx, query, params...)
 	if err != nil {
-		return -1, err
+		return -1, tracing.Error(span, err)
 	}
 	return res.RowsAffected()
 }
 func (r *UserAdapter) Delete(ctx context.Context, id string) (int64, error) {
 	query := "delete from users where id = $1"
+	ctx, span := tracing.StartQuery(ctx, "UserAdapter.Delete", query)
+	defer span.End()
 	tx := GetTx(ctx)
 	stmt, err := tx.Prepare(query)
 	if err != nil {
-		return -1, err
+		return -1, tracing.Error(span, err)
 	}
 	res, err := stmt.ExecContext(ctx, id)
 	if err != nil {
-		return -1, err
+		return -1, tracing.Error(span, err)
 	}
 	return res.RowsAffected()
 }
@@ -166,18 +195,25 @@ func (r *UserAdapter) Search(ctx context.Context, filter *domain.UserFilter) ([]
 	pagingQuery := s.BuildPagingQuery(query, filter.Limit, offset)
 	countQuery := s.BuildCountQuery(query)
 
-	row := r.DB.QueryRowContext(ctx, countQuery, params...)
+	countCtx, countSpan := tracing.StartQuery(ctx, "UserAdapter.Search.Count", countQuery)
+	row := r.DB.QueryRowContext(countCtx, countQuery, params...)
 	if row.Err() != nil {
+		tracing.Error(countSpan, row.Err())
+		countSpan.End()
 		return users, 0, row.Err()
 	}
 	var total int64
 	err := row.Scan(&total)
+	tracing.Error(countSpan, err)
+	countSpan.End()
 	if err != nil || total == 0 {
 		return users, total, err
 	}
 
+	ctx, span := tracing.StartQuery(ctx, "UserAdapter.Search", pagingQuery)
+	defer span.End()
 	err = s.Query(ctx, r.DB, r.Map, &users, pagingQuery, params...)
-	return users, total, err
+	return users, total, tracing.Error(span, err)
 }
 
 func GetTx(ctx context.Context) *sql.Tx {
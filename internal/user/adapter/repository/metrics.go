@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go-service/internal/user/domain"
+	"go-service/internal/user/port"
+)
+
+func NewUserMetrics(repository port.UserRepository, observe func(string, string, time.Time, error)) port.UserRepository {
+	return &UserMetrics{repository: repository, Observe: observe}
+}
+
+type UserMetrics struct {
+	repository port.UserRepository
+	Observe    func(repository string, method string, start time.Time, err error)
+}
+
+func (r *UserMetrics) Load(ctx context.Context, id string) (*domain.User, error) {
+	start := time.Now()
+	user, err := r.repository.Load(ctx, id)
+	r.Observe("user", "Load", start, err)
+	return user, err
+}
+func (r *UserMetrics) Create(ctx context.Context, user *domain.User) (int64, error) {
+	start := time.Now()
+	res, err := r.repository.Create(ctx, user)
+	r.Observe("user", "Create", start, err)
+	return res, err
+}
+func (r *UserMetrics) Update(ctx context.Context, user *domain.User) (int64, error) {
+	start := time.Now()
+	res, err := r.repository.Update(ctx, user)
+	r.Observe("user", "Update", start, err)
+	return res, err
+}
+func (r *UserMetrics) Patch(ctx context.Context, user map[string]interface{}) (int64, error) {
+	start := time.Now()
+	res, err := r.repository.Patch(ctx, user)
+	r.Observe("user", "Patch", start, err)
+	return res, err
+}
+func (r *UserMetrics) Delete(ctx context.Context, id string) (int64, error) {
+	start := time.Now()
+	res, err := r.repository.Delete(ctx, id)
+	r.Observe("user", "Delete", start, err)
+	return res, err
+}
+func (r *UserMetrics) Search(ctx context.Context, filter *domain.UserFilter) ([]domain.User, int64, error) {
+	start := time.Now()
+	users, total, err := r.repository.Search(ctx, filter)
+	r.Observe("user", "Search", start, err)
+	return users, total, err
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"go-service/pkg/auth"
+
+	. "go-service/internal/user/domain"
+)
+
+const (
+	ActionRead   = "read"
+	ActionSearch = "search"
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionPatch  = "patch"
+	ActionDelete = "delete"
+)
+
+func NewUserPolicy(policy *auth.Policy, service UserService) UserService {
+	return &UserPolicy{policy: policy, service: service}
+}
+
+type UserPolicy struct {
+	policy  *auth.Policy
+	service UserService
+}
+
+func (s *UserPolicy) Load(ctx context.Context, id string) (*User, error) {
+	if _, err := s.authorize(ctx, ActionRead, id); err != nil {
+		return nil, err
+	}
+	return s.service.Load(ctx, id)
+}
+func (s *UserPolicy) Create(ctx context.Context, user *User) (int64, error) {
+	if _, err := s.authorize(ctx, ActionCreate, user.Id); err != nil {
+		return -1, err
+	}
+	return s.service.Create(ctx, user)
+}
+func (s *UserPolicy) Update(ctx context.Context, user *User) (int64, error) {
+	principal, err := s.authorize(ctx, ActionUpdate, user.Id)
+	if err != nil {
+		return -1, err
+	}
+	if !s.policy.AllowAllFields(principal, ActionUpdate, user.Id) {
+		current, err := s.service.Load(ctx, user.Id)
+		if err != nil {
+			return -1, err
+		}
+		if current != nil && !s.policy.AllowFields(principal, ActionUpdate, user.Id, ChangedFields(current, user)) {
+			return -1, auth.ErrForbidden
+		}
+	}
+	return s.service.Update(ctx, user)
+}
+func (s *UserPolicy) Patch(ctx context.Context, id string, apply func(*User) (map[string]interface{}, error)) (int64, error) {
+	principal, err := s.authorize(ctx, ActionPatch, id)
+	if err != nil {
+		return -1, err
+	}
+	return s.service.Patch(ctx, id, func(user *User) (map[string]interface{}, error) {
+		changes, err := apply(user)
+		if err != nil {
+			return nil, err
+		}
+		fields := make([]string, 0, len(changes))
+		for field := range changes {
+			if field != "id" {
+				fields = append(fields, field)
+			}
+		}
+		if !s.policy.AllowFields(principal, ActionPatch, id, fields) {
+			return nil, auth.ErrForbidden
+		}
+		return changes, nil
+	})
+}
+func (s *UserPolicy) Delete(ctx context.Context, id string) (int64, error) {
+	if _, err := s.authorize(ctx, ActionDelete, id); err != nil {
+		return -1, err
+	}
+	return s.service.Delete(ctx, id)
+}
+func (s *UserPolicy) Search(ctx context.Context, filter *UserFilter) ([]User, int64, error) {
+	principal, ok := auth.GetPrincipal(ctx)
+	if !ok {
+		return nil, 0, auth.ErrForbidden
+	}
+	if !s.policy.Allow(principal, ActionSearch, "") {
+		if !s.policy.Allow(principal, ActionSearch, principal.Subject) {
+			return nil, 0, auth.ErrForbidden
+		}
+		filter.Id = principal.Subject
+	}
+	return s.service.Search(ctx, filter)
+}
+
+func (s *UserPolicy) authorize(ctx context.Context, action string, id string) (*auth.Principal, error) {
+	principal, ok := auth.GetPrincipal(ctx)
+	if !ok || !s.policy.Allow(principal, action, id) {
+		return nil, auth.ErrForbidden
+	}
+	return principal, nil
+}
+
+func ChangedFields(current *User, user *User) []string {
+	a, b := toMap(current), toMap(user)
+	fields := make([]string, 0)
+	for k, v := range b {
+		if k != "id" && !reflect.DeepEqual(a[k], v) {
+			fields = append(fields, k)
+		}
+	}
+	return fields
+}
+func toMap(user *User) map[string]interface{} {
+	m := make(map[string]interface{})
+	b, err := json.Marshal(user)
+	if err == nil {
+		json.Unmarshal(b, &m)
+	}
+	return m
+}
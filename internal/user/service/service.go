@@ -12,11 +12,12 @@ type UserService interface {
 	Load(ctx context.Context, id string) (*User, error)
 	Create(ctx context.Context, user *User) (int64, error)
 	Update(ctx context.Context, user *User) (int64, error)
-	Patch(ctx context.Context, user map[string]interface{}) (int64, error)
+	Patch(ctx context.Context, id string, apply func(*User) (map[string]interface{}, error)) (int64, error)
 	Delete(ctx context.Context, id string) (int64, error)
 	Search(ctx context.Context, filter *UserFilter) ([]User, int64, error)
 }
 
+// NewUserService runs the writes in a transaction of db, or without one when db is nil, as for a repository calling another service.
 func NewUserService(db *sql.DB, repository UserRepository) UserService {
 	return &UserUseCase{db: db, repository: repository}
 }
@@ -30,6 +31,9 @@ func (s *UserUseCase) Load(ctx context.Context, id string) (*User, error) {
 	return s.repository.Load(ctx, id)
 }
 func (s *UserUseCase) Create(ctx context.Context, user *User) (int64, error) {
+	if s.db == nil {
+		return s.repository.Create(ctx, user)
+	}
 	tx, err := s.db.Begin()
 	if err != nil {
 		return -1, nil
@@ -47,6 +51,9 @@ func (s *UserUseCase) Create(ctx context.Context, user *User) (int64, error) {
 	return res, err
 }
 func (s *UserUseCase) Update(ctx context.Context, user *User) (int64, error) {
+	if s.db == nil {
+		return s.repository.Update(ctx, user)
+	}
 	tx, err := s.db.Begin()
 	if err != nil {
 		return -1, nil
@@ -63,24 +70,48 @@ func (s *UserUseCase) Update(ctx context.Context, user *User) (int64, error) {
 	err = tx.Commit()
 	return res, err
 }
-func (s *UserUseCase) Patch(ctx context.Context, user map[string]interface{}) (int64, error) {
+func (s *UserUseCase) Patch(ctx context.Context, id string, apply func(*User) (map[string]interface{}, error)) (int64, error) {
+	if s.db == nil {
+		return s.patch(ctx, id, apply)
+	}
 	tx, err := s.db.Begin()
 	if err != nil {
-		return -1, nil
+		return -1, err
 	}
 	ctx = context.WithValue(ctx, "tx", tx)
-	res, err := s.repository.Patch(ctx, user)
-	if err != nil {
+	res, err := s.patch(ctx, id, apply)
+	if err != nil || res <= 0 {
 		er := tx.Rollback()
 		if er != nil {
 			return -1, er
 		}
-		return -1, err
+		return res, err
 	}
 	err = tx.Commit()
 	return res, err
 }
+func (s *UserUseCase) patch(ctx context.Context, id string, apply func(*User) (map[string]interface{}, error)) (int64, error) {
+	user, err := s.repository.Load(ctx, id)
+	if err != nil {
+		return -1, err
+	}
+	if user == nil {
+		return 0, nil
+	}
+	changes, err := apply(user)
+	if err != nil {
+		return -1, err
+	}
+	if len(changes) == 0 {
+		return 1, nil
+	}
+	changes["id"] = id
+	return s.repository.Patch(ctx, changes)
+}
 func (s *UserUseCase) Delete(ctx context.Context, id string) (int64, error) {
+	if s.db == nil {
+		return s.repository.Delete(ctx, id)
+	}
 	tx, err := s.db.Begin()
 	if err != nil {
 		return -1, nil
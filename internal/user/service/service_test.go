@@ -0,0 +1,34 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"go-service/internal/user/domain"
+	"go-service/internal/user/service"
+	"go-service/internal/user/usertest"
+)
+
+func TestWritesWithoutDatabase(t *testing.T) {
+	ctx := context.Background()
+	s := service.NewUserService(nil, usertest.NewRepository(usertest.Users()...))
+	if res, err := s.Create(ctx, &domain.User{Id: "hulk", Username: "bruce.banner", Phone: "0987654321"}); res != 1 || err != nil {
+		t.Errorf("Create() = %d, %v", res, err)
+	}
+	if res, err := s.Update(ctx, &domain.User{Id: "hulk", Username: "bruce.banner", Phone: "0123456789"}); res != 1 || err != nil {
+		t.Errorf("Update() = %d, %v", res, err)
+	}
+	res, err := s.Patch(ctx, "hulk", func(user *domain.User) (map[string]interface{}, error) {
+		user.Email = "bruce@banner.com"
+		return map[string]interface{}{"email": user.Email}, nil
+	})
+	if res != 1 || err != nil {
+		t.Errorf("Patch() = %d, %v", res, err)
+	}
+	if user, err := s.Load(ctx, "hulk"); err != nil || user == nil || user.Email != "bruce@banner.com" || user.Phone != "0123456789" {
+		t.Errorf("Load() = %+v, %v", user, err)
+	}
+	if res, err := s.Delete(ctx, "hulk"); res != 1 || err != nil {
+		t.Errorf("Delete() = %d, %v", res, err)
+	}
+}
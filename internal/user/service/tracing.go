@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	. "go-service/internal/user/domain"
+	"go-service/pkg/tracing"
+)
+
+func NewUserTracing(service UserService) UserService {
+	return &UserTracing{service: service}
+}
+
+type UserTracing struct {
+	service UserService
+}
+
+func (s *UserTracing) Load(ctx context.Context, id string) (*User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Load", attribute.String("user.id", id))
+	defer span.End()
+	user, err := s.service.Load(ctx, id)
+	return user, tracing.Error(span, err)
+}
+func (s *UserTracing) Create(ctx context.Context, user *User) (int64, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Create", attribute.String("user.id", user.Id), attribute.Bool("db.transaction", true))
+	defer span.End()
+	res, err := s.service.Create(ctx, user)
+	span.SetAttributes(attribute.Int64("result", res))
+	return res, tracing.Error(span, err)
+}
+func (s *UserTracing) Update(ctx context.Context, user *User) (int64, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Update", attribute.String("user.id", user.Id), attribute.Bool("db.transaction", true))
+	defer span.End()
+	res, err := s.service.Update(ctx, user)
+	span.SetAttributes(attribute.Int64("result", res))
+	return res, tracing.Error(span, err)
+}
+func (s *UserTracing) Patch(ctx context.Context, id string, apply func(*User) (map[string]interface{}, error)) (int64, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Patch", attribute.String("user.id", id), attribute.Bool("db.transaction", true))
+	defer span.End()
+	res, err := s.service.Patch(ctx, id, apply)
+	span.SetAttributes(attribute.Int64("result", res))
+	return res, tracing.Error(span, err)
+}
+func (s *UserTracing) Delete(ctx context.Context, id string) (int64, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Delete", attribute.String("user.id", id), attribute.Bool("db.transaction", true))
+	defer span.End()
+	res, err := s.service.Delete(ctx, id)
+	span.SetAttributes(attribute.Int64("result", res))
+	return res, tracing.Error(span, err)
+}
+func (s *UserTracing) Search(ctx context.Context, filter *UserFilter) ([]User, int64, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Search")
+	defer span.End()
+	users, total, err := s.service.Search(ctx, filter)
+	span.SetAttributes(attribute.Int64("total", total))
+	return users, total, tracing.Error(span, err)
+}
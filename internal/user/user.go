@@ -4,12 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"net/http"
+	"time"
 
 	v "github.com/core-go/core/v10"
 
 	"go-service/internal/user/adapter/handler"
 	"go-service/internal/user/adapter/repository"
+	"go-service/internal/user/port"
 	"go-service/internal/user/service"
+	"go-service/pkg/auth"
 )
 
 type UserTransport interface {
@@ -21,17 +24,37 @@ type UserTransport interface {
 	Delete(w http.ResponseWriter, r *http.Request)
 }
 
-func NewUserHandler(db *sql.DB, logError func(context.Context, string, ...map[string]interface{})) (UserTransport,error) {
+const (
+	RepositorySql    = "sql"
+	RepositoryClient = "client"
+)
+
+type Config struct {
+	Repository string `yaml:"repository" mapstructure:"repository" json:"repository,omitempty" gorm:"column:repository" bson:"repository,omitempty" dynamodbav:"repository,omitempty" firestore:"repository,omitempty"`
+}
+
+// NewUserHandler serves the users of the SQL adapter, or of userRepository when it is not nil, such as the client of another instance.
+func NewUserHandler(db *sql.DB, userRepository port.UserRepository, policy *auth.Policy, observe func(string, string, time.Time, error), mask func(map[string]interface{}), logError func(context.Context, string, ...map[string]interface{})) (UserTransport, error) {
 	validator, err := v.NewValidator()
 	if err != nil {
 		return nil, err
 	}
 
-	userRepository, err := repository.NewUserAdapter(db, repository.BuildQuery)
-	if err != nil {
-		return nil, err
+	if userRepository == nil {
+		userAdapter, err := repository.NewUserAdapter(db, repository.BuildQuery)
+		if err != nil {
+			return nil, err
+		}
+		userRepository = userAdapter
+	}
+	if observe != nil {
+		userRepository = repository.NewUserMetrics(userRepository, observe)
 	}
 	userService := service.NewUserService(db, userRepository)
-	userHandler := handler.NewUserHandler(userService, validator.Validate, logError)
-	return userHandler, nil
+	if policy != nil {
+		userService = service.NewUserPolicy(policy, userService)
+	}
+	userService = service.NewUserTracing(userService)
+	userHandler := handler.NewUserHandler(userService, validator.Validate, mask, logError)
+	return handler.NewUserTracing(userHandler), nil
 }
@@ -0,0 +1,338 @@
+package usertest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"time"
+
+	"github.com/core-go/core"
+	"github.com/core-go/search"
+
+	userclient "go-service/internal/user/adapter/client"
+	"go-service/internal/user/domain"
+	"go-service/pkg/client"
+	"go-service/pkg/problem"
+)
+
+// T is the part of *testing.T used by the suites, so that they can be run by a test, or by anything which reports the errors.
+type T interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// Users returns the users of docs/data.json, which the scenarios start from.
+func Users() []domain.User {
+	return []domain.User{
+		{Id: "ironman", Username: "tony.stark", Email: "tony.stark@gmail.com", Phone: "0987654321", DateOfBirth: date("1963-03-25T00:00:00+07:00")},
+		{Id: "spiderman", Username: "peter.parker", Email: "peter.parker@gmail.com", Phone: "0987654321", DateOfBirth: date("1962-08-25T00:00:00+07:00")},
+		{Id: "wolverine", Username: "james.howlett", Email: "james.howlett@gmail.com", Phone: "0987654321", DateOfBirth: date("1974-11-16T00:00:00+07:00")},
+	}
+}
+
+func date(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+// Target serves the /users API, starting from the users.
+type Target struct {
+	Name    string
+	Handler func(users ...domain.User) (http.Handler, error)
+}
+
+// FakeTarget is the Fake. The handler of the service is the target of the contract test of internal/app, which routes it as the service does.
+func FakeTarget() Target {
+	return Target{Name: "fake", Handler: func(users ...domain.User) (http.Handler, error) {
+		return NewFake(users...), nil
+	}}
+}
+
+const (
+	ironman   = `{"id":"ironman","username":"tony.stark","email":"tony.stark@gmail.com","phone":"0987654321","dateOfBirth":"1963-03-25T00:00:00+07:00"}`
+	spiderman = `{"id":"spiderman","username":"peter.parker","email":"peter.parker@gmail.com","phone":"0987654321","dateOfBirth":"1962-08-25T00:00:00+07:00"}`
+	wolverine = `{"id":"wolverine","username":"james.howlett","email":"james.howlett@gmail.com","phone":"0987654321","dateOfBirth":"1974-11-16T00:00:00+07:00"}`
+	hulk      = `{"id":"hulk","username":"bruce.banner","email":"bruce.banner@gmail.com","phone":"0987654321","dateOfBirth":null}`
+)
+
+type step func(ctx context.Context, c *userclient.UserClient) (interface{}, error)
+
+// scenario runs its steps on a new server, and expects the JSON array of their outcomes.
+// The outcome of an *client.HttpError is its status and validation errors without messages, and of any other error, {"error":true}.
+type scenario struct {
+	name  string
+	steps []step
+	want  string
+}
+
+var scenarios = []scenario{
+	{"load", []step{load("ironman")}, `[` + ironman + `]`},
+	{"load missing", []step{load("hulk")}, `[null]`},
+	{"load status", []step{exchange(http.MethodGet, "/hulk", "")}, `[{"status":404,"contentType":"application/json","body":null}]`},
+	{"create", []step{create(newHulk()), load("hulk")}, `[1,` + hulk + `]`},
+	{"create invalid", []step{create(&domain.User{Id: "hulk"})}, `[{"status":422,"errors":[{"field":"username","code":"required"},{"field":"phone","code":"required"}]}]`},
+	{"create duplicate", []step{create(&domain.User{Id: "ironman", Username: "tony.stark", Phone: "0987654321"})},
+		`[{"status":500,"problem":` + problemBody(500, "Internal Server Error", "", "/users") + `}]`},
+	{"create malformed", []step{exchange(http.MethodPost, "", `{"id":`)},
+		`[{"status":400,"contentType":"application/problem+json","body":` + problemBody(400, "Bad Request", "unexpected EOF", "/users") + `}]`},
+	{"update", []step{update(`{"id":"ironman","username":"tony.stark","email":"tony@stark.com","phone":"0123456789","dateOfBirth":null}`), load("ironman")},
+		`[1,{"id":"ironman","username":"tony.stark","email":"tony@stark.com","phone":"0123456789","dateOfBirth":null}]`},
+	{"update missing", []step{update(hulk), load("hulk")}, `[0,null]`},
+	{"update id mismatch", []step{exchange(http.MethodPut, "/ironman", hulk)},
+		`[{"status":400,"contentType":"application/problem+json","body":` + problemBody(400, "Bad Request", "Id not match", "/users/ironman") + `}]`},
+	{"update invalid", []step{update(`{"id":"ironman","username":"tony.stark","email":"tony","phone":"0987654321"}`)}, `[{"status":422,"errors":[{"field":"email","code":"email"}]}]`},
+	{"patch", []step{patchUser(map[string]interface{}{"id": "ironman", "email": "tony@stark.com"}), load("ironman")},
+		`[1,{"id":"ironman","username":"tony.stark","email":"tony@stark.com","phone":"0987654321","dateOfBirth":"1963-03-25T00:00:00+07:00"}]`},
+	{"patch missing", []step{patchUser(map[string]interface{}{"id": "hulk", "email": "bruce@banner.com"})}, `[0]`},
+	{"patch invalid", []step{patchUser(map[string]interface{}{"id": "ironman", "username": nil})}, `[{"status":422,"errors":[{"field":"username","code":"required"}]}]`},
+	{"delete", []step{remove("ironman"), remove("ironman"), load("ironman")}, `[1,0,null]`},
+	{"search", []step{find(&domain.UserFilter{Filter: &search.Filter{Limit: 2, Page: 1}}), find(&domain.UserFilter{Filter: &search.Filter{Limit: 2, Page: 2}})},
+		`[{"list":[` + ironman + `,` + spiderman + `],"total":3},{"list":[` + wolverine + `],"total":3}]`},
+	{"search filter", []step{find(&domain.UserFilter{Filter: &search.Filter{Limit: 10}, Username: "peter"})}, `[{"list":[` + spiderman + `],"total":1}]`},
+	{"search query", []step{exchange(http.MethodGet, "/search?username=james&limit=10", "")}, `[{"status":200,"contentType":"application/json","body":{"list":[` + wolverine + `],"total":1}}]`},
+}
+
+// problemBody is the problem of a response, with the request id that both the service and the Fake add.
+func problemBody(status int, title string, detail string, instance string) string {
+	p := map[string]interface{}{"type": "about:blank", "title": title, "status": status, "instance": instance, "requestId": true}
+	if len(detail) > 0 {
+		p["detail"] = detail
+	}
+	b, _ := json.Marshal(p)
+	return string(b)
+}
+
+func newHulk() *domain.User {
+	return &domain.User{Id: "hulk", Username: "bruce.banner", Email: "bruce.banner@gmail.com", Phone: "0987654321"}
+}
+
+func load(id string) step {
+	return func(ctx context.Context, c *userclient.UserClient) (interface{}, error) {
+		return c.Load(ctx, id)
+	}
+}
+func create(user *domain.User) step {
+	return func(ctx context.Context, c *userclient.UserClient) (interface{}, error) {
+		return c.Create(ctx, user)
+	}
+}
+func update(user string) step {
+	return func(ctx context.Context, c *userclient.UserClient) (interface{}, error) {
+		var u domain.User
+		if err := json.Unmarshal([]byte(user), &u); err != nil {
+			return nil, err
+		}
+		return c.Update(ctx, &u)
+	}
+}
+func patchUser(user map[string]interface{}) step {
+	return func(ctx context.Context, c *userclient.UserClient) (interface{}, error) {
+		return c.Patch(ctx, user)
+	}
+}
+func remove(id string) step {
+	return func(ctx context.Context, c *userclient.UserClient) (interface{}, error) {
+		return c.Delete(ctx, id)
+	}
+}
+func find(filter *domain.UserFilter) step {
+	return func(ctx context.Context, c *userclient.UserClient) (interface{}, error) {
+		users, total, err := c.Search(ctx, filter)
+		return &search.Result{List: users, Total: total}, err
+	}
+}
+
+// exchange sends a request which UserClient does not, and returns the status, the media type, and the body when it is JSON or a problem.
+func exchange(method string, path string, body string) step {
+	return func(ctx context.Context, c *userclient.UserClient) (interface{}, error) {
+		var rq io.Reader
+		if len(body) > 0 {
+			rq = bytes.NewReader([]byte(body))
+		}
+		req, err := http.NewRequestWithContext(ctx, method, c.Url+path, rq)
+		if err != nil {
+			return nil, err
+		}
+		if rq != nil {
+			req.Header.Set("Content-Type", contentTypeJSON)
+		}
+		res, err := c.Client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+		mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+		result := map[string]interface{}{"status": res.StatusCode, "contentType": mediaType}
+		switch mediaType {
+		case contentTypeJSON:
+			result["body"] = json.RawMessage(b)
+		case problem.ContentType:
+			result["body"] = problemOf(b)
+		}
+		return result, nil
+	}
+}
+
+// Contract runs the scenarios through UserClient against each target, the Fake when there is none, and checks the responses against the spec, if any.
+// The handler passing proves that UserClient and UserHandler agree on paths, status codes and payloads, and the fake passing, that it can stand for the service.
+func Contract(t T, spec *Spec, targets ...Target) {
+	t.Helper()
+	if len(targets) == 0 {
+		targets = []Target{FakeTarget()}
+	}
+	for _, target := range targets {
+		for _, sc := range scenarios {
+			run(t, spec, target, sc)
+		}
+	}
+}
+
+func run(t T, spec *Spec, target Target, sc scenario) {
+	t.Helper()
+	name := target.Name + "/" + sc.name
+	handler, err := target.Handler(Users()...)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+		return
+	}
+	if spec != nil {
+		handler = spec.Handler(t, name, handler)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	c := userclient.NewUserClient(server.Client(), server.URL+"/users", nil, nil, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	outcomes := make([]interface{}, 0, len(sc.steps))
+	for _, s := range sc.steps {
+		outcomes = append(outcomes, outcome(s(ctx, c)))
+	}
+	got, er1 := normalize(outcomes)
+	want, er2 := normalize(json.RawMessage(sc.want))
+	if er1 != nil || er2 != nil {
+		t.Fatalf("%s: %v", name, errors.Join(er1, er2))
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		g, _ := json.Marshal(got)
+		t.Errorf("%s: got %s, want %s", name, g, sc.want)
+	}
+}
+
+func outcome(res interface{}, err error) interface{} {
+	if err == nil {
+		return res
+	}
+	httpErr, ok := client.IsHttpError(err)
+	if !ok {
+		return map[string]interface{}{"error": true}
+	}
+	o := map[string]interface{}{"status": httpErr.StatusCode}
+	if len(httpErr.Errors) > 0 {
+		// the messages are for people, and depend on the translator of the validator
+		errs := make([]core.ErrorMessage, 0, len(httpErr.Errors))
+		for _, e := range httpErr.Errors {
+			errs = append(errs, core.ErrorMessage{Field: e.Field, Code: e.Code, Param: e.Param})
+		}
+		o["errors"] = errs
+	} else if p := problemOf([]byte(httpErr.Response)); p != nil {
+		o["problem"] = p
+	}
+	return o
+}
+
+// problemOf decodes a problem body, with true as its request id, which differs on each request.
+func problemOf(b []byte) map[string]interface{} {
+	var p map[string]interface{}
+	if json.Unmarshal(b, &p) != nil || p["type"] == nil {
+		return nil
+	}
+	if p["requestId"] != nil {
+		p["requestId"] = true
+	}
+	return p
+}
+
+func normalize(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var res interface{}
+	err = json.Unmarshal(b, &res)
+	return res, err
+}
+
+// Faults checks that UserClient reports the faults of a Fake as errors, and recovers once they stop.
+func Faults(t T) {
+	t.Helper()
+	server := NewServer(Users()...)
+	defer server.Close()
+	// without keep-alive, the transport does not retry a request on a new connection when the connection is reset
+	httpClient := &http.Client{Timeout: 200 * time.Millisecond, Transport: &http.Transport{DisableKeepAlives: true}}
+	c := userclient.NewUserClient(httpClient, server.URL+"/users", nil, nil, nil, nil)
+	ctx := context.Background()
+
+	// errorType is the ErrorType of the *client.HttpError, or empty when the error is of the decoding of the body
+	cases := []struct {
+		name      string
+		fault     Fault
+		errorType string
+		status    int
+	}{
+		{"latency", Fault{Latency: time.Second, Times: 1}, client.ErrorTypeNetwork, 0},
+		{"status", Fault{Method: http.MethodGet, Status: http.StatusServiceUnavailable, Times: 1}, client.ErrorTypeResponse, http.StatusServiceUnavailable},
+		{"reset", Fault{Reset: true, Times: 1}, client.ErrorTypeNetwork, 0},
+		{"malformed", Fault{Malformed: true, Times: 1}, "", 0},
+	}
+	for _, tc := range cases {
+		server.Inject(tc.fault)
+		_, err := c.Load(ctx, "ironman")
+		if err == nil {
+			t.Errorf("faults/%s: Load succeeded", tc.name)
+			continue
+		}
+		httpErr, ok := client.IsHttpError(err)
+		switch {
+		case len(tc.errorType) == 0 && ok:
+			t.Errorf("faults/%s: got %v, want an error of decoding", tc.name, err)
+		case len(tc.errorType) > 0 && (!ok || httpErr.ErrorType != tc.errorType || httpErr.StatusCode != tc.status):
+			t.Errorf("faults/%s: got %v, want an error of type %s with status %d", tc.name, err, tc.errorType, tc.status)
+		}
+		if tc.status > 0 {
+			if p := problemOf([]byte(httpErr.Response)); p == nil || p["status"] != float64(tc.status) {
+				t.Errorf("faults/%s: got the body %q, want a problem of status %d", tc.name, httpErr.Response, tc.status)
+			}
+		}
+		if user, err := c.Load(ctx, "ironman"); err != nil || user == nil {
+			t.Errorf("faults/%s: Load after the fault: %v, %v", tc.name, user, err)
+		}
+	}
+
+	server.Inject(Fault{Path: "/users/spiderman", Status: http.StatusInternalServerError})
+	if _, err := c.Load(ctx, "ironman"); err != nil {
+		t.Errorf("faults/path: Load of another path: %v", err)
+	}
+	if _, err := c.Load(ctx, "spiderman"); !client.IsStatus(err, http.StatusInternalServerError) {
+		t.Errorf("faults/path: got %v, want status 500", err)
+	}
+	server.Clear()
+	if _, err := c.Load(ctx, "spiderman"); err != nil {
+		t.Errorf("faults/clear: %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package usertest
+
+import "testing"
+
+func TestContract(t *testing.T) {
+	spec, err := FindSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	Contract(t, spec, FakeTarget())
+}
+
+func TestFaults(t *testing.T) {
+	Faults(t)
+}
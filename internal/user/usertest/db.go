@@ -0,0 +1,52 @@
+package usertest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+)
+
+var ErrNoStatement = errors.New("usertest: the database only supports transactions")
+
+// NewDB returns a database whose transactions do nothing, for the service, which begins a transaction around each write,
+// when the users are kept by a Repository.
+func NewDB() *sql.DB {
+	return sql.OpenDB(connector{})
+}
+
+type connector struct{}
+
+func (connector) Connect(context.Context) (driver.Conn, error) {
+	return conn{}, nil
+}
+func (connector) Driver() driver.Driver {
+	return txDriver{}
+}
+
+type txDriver struct{}
+
+func (txDriver) Open(string) (driver.Conn, error) {
+	return conn{}, nil
+}
+
+type conn struct{}
+
+func (conn) Prepare(string) (driver.Stmt, error) {
+	return nil, ErrNoStatement
+}
+func (conn) Close() error {
+	return nil
+}
+func (conn) Begin() (driver.Tx, error) {
+	return tx{}, nil
+}
+
+type tx struct{}
+
+func (tx) Commit() error {
+	return nil
+}
+func (tx) Rollback() error {
+	return nil
+}
@@ -0,0 +1,178 @@
+package usertest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"go-service/internal/user/domain"
+	"go-service/internal/user/port"
+)
+
+var ErrDuplicateKey = errors.New("duplicate key value violates unique constraint \"users_pkey\"")
+
+var _ port.UserRepository = (*Repository)(nil)
+
+// Repository keeps the users in memory, with the semantics of the SQL adapter: writes return the number of affected rows,
+// and Search filters by prefix of username and email, by part of phone, and returns nothing without a limit.
+type Repository struct {
+	mu    sync.RWMutex
+	users map[string]domain.User
+}
+
+func NewRepository(users ...domain.User) *Repository {
+	r := &Repository{users: make(map[string]domain.User)}
+	for _, user := range users {
+		r.users[user.Id] = copyUser(user)
+	}
+	return r
+}
+
+func (r *Repository) Load(ctx context.Context, id string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+	user = copyUser(user)
+	return &user, nil
+}
+func (r *Repository) Create(ctx context.Context, user *domain.User) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[user.Id]; ok {
+		return -1, ErrDuplicateKey
+	}
+	r.users[user.Id] = copyUser(*user)
+	return 1, nil
+}
+func (r *Repository) Update(ctx context.Context, user *domain.User) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[user.Id]; !ok {
+		return 0, nil
+	}
+	r.users[user.Id] = copyUser(*user)
+	return 1, nil
+}
+func (r *Repository) Patch(ctx context.Context, user map[string]interface{}) (int64, error) {
+	id, ok := user["id"].(string)
+	if !ok {
+		return -1, errors.New("id is required to patch user")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.users[id]
+	if !ok {
+		return 0, nil
+	}
+	b, err := json.Marshal(current)
+	if err != nil {
+		return -1, err
+	}
+	doc := make(map[string]interface{})
+	if err = json.Unmarshal(b, &doc); err != nil {
+		return -1, err
+	}
+	for k, v := range user {
+		doc[k] = v
+	}
+	if b, err = json.Marshal(doc); err != nil {
+		return -1, err
+	}
+	var patched domain.User
+	if err = json.Unmarshal(b, &patched); err != nil {
+		return -1, err
+	}
+	patched.Id = id
+	r.users[id] = patched
+	return 1, nil
+}
+func (r *Repository) Delete(ctx context.Context, id string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[id]; !ok {
+		return 0, nil
+	}
+	delete(r.users, id)
+	return 1, nil
+}
+
+// Search sorts the users by id, so that the pages are stable.
+func (r *Repository) Search(ctx context.Context, filter *domain.UserFilter) ([]domain.User, int64, error) {
+	var users []domain.User
+	if filter.Filter == nil || filter.Limit <= 0 {
+		return users, 0, nil
+	}
+	r.mu.RLock()
+	matched := make([]domain.User, 0, len(r.users))
+	for _, user := range r.users {
+		if match(filter, user) {
+			matched = append(matched, copyUser(user))
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Id < matched[j].Id })
+
+	total := int64(len(matched))
+	offset := int64(0)
+	if filter.Page > 1 {
+		offset = filter.Limit * (filter.Page - 1)
+	}
+	if offset >= total {
+		return users, total, nil
+	}
+	end := min(offset+filter.Limit, total)
+	return matched[offset:end], total, nil
+}
+
+// All returns the users sorted by id.
+func (r *Repository) All() []domain.User {
+	r.mu.RLock()
+	users := make([]domain.User, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, copyUser(user))
+	}
+	r.mu.RUnlock()
+	sort.Slice(users, func(i, j int) bool { return users[i].Id < users[j].Id })
+	return users
+}
+
+func match(filter *domain.UserFilter, user domain.User) bool {
+	if len(filter.Id) > 0 && user.Id != filter.Id {
+		return false
+	}
+	if len(filter.Username) > 0 && !strings.HasPrefix(user.Username, filter.Username) {
+		return false
+	}
+	if len(filter.Email) > 0 && !strings.HasPrefix(user.Email, filter.Email) {
+		return false
+	}
+	if len(filter.Phone) > 0 && !strings.Contains(user.Phone, filter.Phone) {
+		return false
+	}
+	if filter.DateOfBirth != nil {
+		if user.DateOfBirth == nil {
+			return false
+		}
+		if filter.DateOfBirth.Min != nil && user.DateOfBirth.Before(*filter.DateOfBirth.Min) {
+			return false
+		}
+		if filter.DateOfBirth.Max != nil && user.DateOfBirth.After(*filter.DateOfBirth.Max) {
+			return false
+		}
+	}
+	return true
+}
+
+func copyUser(user domain.User) domain.User {
+	if user.DateOfBirth != nil {
+		dateOfBirth := *user.DateOfBirth
+		user.DateOfBirth = &dateOfBirth
+	}
+	return user
+}
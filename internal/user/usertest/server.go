@@ -0,0 +1,400 @@
+package usertest
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/core-go/core"
+	"github.com/core-go/search"
+	"github.com/gorilla/mux"
+
+	"go-service/internal/user/domain"
+	"go-service/pkg/patch"
+	"go-service/pkg/problem"
+	"go-service/pkg/requestid"
+)
+
+const (
+	contentTypeJSON = "application/json"
+	malformedJSON   = `{"id":"`
+)
+
+// Fault changes the responses of the requests it matches. Latency applies first, then the first of Reset, Malformed and Status which is set.
+type Fault struct {
+	Method    string // any method when empty
+	Path      string // any path when empty, else the path of the request, such as /users/ironman
+	Latency   time.Duration
+	Status    int  // responds with the status and a problem of its text, instead of serving the request
+	Reset     bool // closes the connection without a response
+	Malformed bool // responds with a truncated JSON body and status 200
+	Times     int  // the number of requests to apply to, every request when 0
+}
+
+// Fake serves the /users API as described by api/swagger.yaml, from a Repository, independently of the handler of the service.
+// As the service, it answers the errors with problems, and echoes or generates the X-Request-ID.
+type Fake struct {
+	Users   *Repository
+	handler http.Handler
+	router  *mux.Router
+	mu      sync.Mutex
+	faults  []*Fault
+}
+
+func NewFake(users ...domain.User) *Fake {
+	f := &Fake{Users: NewRepository(users...)}
+	r := mux.NewRouter()
+	user := r.PathPrefix("/users").Subrouter()
+	user.HandleFunc("/search", f.search).Methods(http.MethodGet, http.MethodPost)
+	user.HandleFunc("/{id}", f.load).Methods(http.MethodGet)
+	user.HandleFunc("", f.create).Methods(http.MethodPost)
+	user.HandleFunc("/{id}", f.update).Methods(http.MethodPut)
+	user.HandleFunc("/{id}", f.patch).Methods(http.MethodPatch)
+	user.HandleFunc("/{id}", f.delete).Methods(http.MethodDelete)
+	f.router = r
+	f.handler = requestid.Handle(http.HandlerFunc(f.serve))
+	return f
+}
+
+// Inject adds a fault, which applies until it has matched Times requests.
+func (f *Fake) Inject(fault Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = append(f.faults, &fault)
+}
+
+// Clear removes the faults.
+func (f *Fake) Clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = nil
+}
+
+func (f *Fake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.handler.ServeHTTP(w, r)
+}
+func (f *Fake) serve(w http.ResponseWriter, r *http.Request) {
+	fault := f.take(r)
+	if fault == nil {
+		f.router.ServeHTTP(w, r)
+		return
+	}
+	if fault.Latency > 0 {
+		timer := time.NewTimer(fault.Latency)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			timer.Stop()
+			return
+		}
+	}
+	switch {
+	case fault.Reset:
+		reset(w)
+	case fault.Malformed:
+		w.Header().Set("Content-Type", contentTypeJSON)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(malformedJSON))
+	case fault.Status > 0:
+		problem.Error(w, r, http.StatusText(fault.Status), fault.Status)
+	default:
+		f.router.ServeHTTP(w, r)
+	}
+}
+
+func (f *Fake) take(r *http.Request) *Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, fault := range f.faults {
+		if (len(fault.Method) > 0 && fault.Method != r.Method) || (len(fault.Path) > 0 && fault.Path != r.URL.Path) {
+			continue
+		}
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				f.faults = append(f.faults[:i:i], f.faults[i+1:]...)
+			}
+		}
+		return fault
+	}
+	return nil
+}
+
+// reset closes the connection with a TCP reset rather than a graceful close, when it can.
+func reset(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	conn.Close()
+}
+
+// Server is a Fake, served by an httptest.Server.
+type Server struct {
+	*httptest.Server
+	*Fake
+}
+
+func NewServer(users ...domain.User) *Server {
+	fake := NewFake(users...)
+	return &Server{Server: httptest.NewServer(fake), Fake: fake}
+}
+
+func (f *Fake) load(w http.ResponseWriter, r *http.Request) {
+	user, err := f.Users.Load(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		problem.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		writeJSON(w, http.StatusNotFound, nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+func (f *Fake) create(w http.ResponseWriter, r *http.Request) {
+	var user domain.User
+	if !decode(w, r, &user) {
+		return
+	}
+	if errs := validate(user); len(errs) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, errs)
+		return
+	}
+	res, err := f.Users.Create(r.Context(), &user)
+	if err != nil {
+		problem.Error(w, r, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, res)
+}
+func (f *Fake) update(w http.ResponseWriter, r *http.Request) {
+	var user domain.User
+	if !decode(w, r, &user) {
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if len(user.Id) == 0 {
+		user.Id = id
+	} else if user.Id != id {
+		problem.Error(w, r, "Id not match", http.StatusBadRequest)
+		return
+	}
+	if errs := validate(user); len(errs) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, errs)
+		return
+	}
+	res, err := f.Users.Update(r.Context(), &user)
+	if err != nil {
+		problem.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, writeStatus(res), res)
+}
+func (f *Fake) patch(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	mediaType := patch.ContentTypeMergePatch
+	if header := r.Header.Get("Content-Type"); len(header) > 0 {
+		mediaType, _, _ = mime.ParseMediaType(header)
+	}
+	var apply func(interface{}) (interface{}, error)
+	switch mediaType {
+	case patch.ContentTypeMergePatch, contentTypeJSON:
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			problem.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		apply = func(doc interface{}) (interface{}, error) {
+			return patch.MergePatch(doc, body), nil
+		}
+	case patch.ContentTypeJSONPatch:
+		var ops []patch.Operation
+		if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+			problem.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := patch.Validate(ops); err != nil {
+			problem.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		apply = func(doc interface{}) (interface{}, error) {
+			return patch.Apply(doc, ops)
+		}
+	default:
+		problem.Error(w, r, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	user, err := f.Users.Load(r.Context(), id)
+	if err != nil {
+		problem.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		writeJSON(w, http.StatusNotFound, 0)
+		return
+	}
+	b, _ := json.Marshal(user)
+	var doc map[string]interface{}
+	json.Unmarshal(b, &doc)
+	patched, err := apply(doc)
+	if err != nil {
+		if errors.Is(err, patch.ErrTestFailed) {
+			problem.Error(w, r, err.Error(), http.StatusConflict)
+		} else {
+			problem.Error(w, r, err.Error(), http.StatusUnprocessableEntity)
+		}
+		return
+	}
+	var result domain.User
+	if b, err = json.Marshal(patched); err != nil || json.Unmarshal(b, &result) != nil {
+		problem.Error(w, r, "patched document is not a valid user", http.StatusUnprocessableEntity)
+		return
+	}
+	if result.Id != id {
+		problem.Error(w, r, "Id not match", http.StatusBadRequest)
+		return
+	}
+	if errs := validate(result); len(errs) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, errs)
+		return
+	}
+	res, err := f.Users.Update(r.Context(), &result)
+	if err != nil {
+		problem.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, writeStatus(res), res)
+}
+func (f *Fake) delete(w http.ResponseWriter, r *http.Request) {
+	res, err := f.Users.Delete(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		problem.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, writeStatus(res), res)
+}
+func (f *Fake) search(w http.ResponseWriter, r *http.Request) {
+	filter := domain.UserFilter{Filter: &search.Filter{}}
+	if r.Method == http.MethodPost {
+		if !decode(w, r, &filter) {
+			return
+		}
+	} else if !filterFromQuery(w, r, &filter) {
+		return
+	}
+	users, total, err := f.Users.Search(r.Context(), &filter)
+	if err != nil {
+		problem.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, &search.Result{List: &users, Total: total})
+}
+
+// filterFromQuery reads the parameters by the json names of the fields of domain.UserFilter, as the handler does.
+func filterFromQuery(w http.ResponseWriter, r *http.Request, filter *domain.UserFilter) bool {
+	query := r.URL.Query()
+	for _, name := range []string{"page", "limit"} {
+		if s := query.Get(name); len(s) > 0 {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				problem.Error(w, r, name+" must be an integer", http.StatusBadRequest)
+				return false
+			}
+			if name == "page" {
+				filter.Page = n
+			} else {
+				filter.Limit = n
+			}
+		}
+	}
+	filter.Id = query.Get("id")
+	filter.Username = query.Get("username")
+	filter.Email = query.Get("email")
+	filter.Phone = query.Get("phone")
+	for _, bound := range []string{"min", "max"} {
+		s := query.Get("dateOfBirth." + bound)
+		if len(s) == 0 {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			problem.Error(w, r, "dateOfBirth."+bound+" must be a date-time", http.StatusBadRequest)
+			return false
+		}
+		if filter.DateOfBirth == nil {
+			filter.DateOfBirth = &search.TimeRange{}
+		}
+		if bound == "min" {
+			filter.DateOfBirth.Min = &t
+		} else {
+			filter.DateOfBirth.Max = &t
+		}
+	}
+	return true
+}
+
+func decode(w http.ResponseWriter, r *http.Request, obj interface{}) bool {
+	if header := r.Header.Get("Content-Type"); len(header) > 0 {
+		if mediaType, _, _ := mime.ParseMediaType(header); mediaType != contentTypeJSON {
+			problem.Error(w, r, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+			return false
+		}
+	}
+	if err := json.NewDecoder(r.Body).Decode(obj); err != nil {
+		problem.Error(w, r, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// validate checks the constraints of the User schema, and reports them as the validator of the service does.
+func validate(user domain.User) []core.ErrorMessage {
+	var errs []core.ErrorMessage
+	required := func(field string, value string, max int) {
+		if len(value) == 0 {
+			errs = append(errs, core.ErrorMessage{Field: field, Code: "required"})
+		} else if len(value) > max {
+			errs = append(errs, core.ErrorMessage{Field: field, Code: "max", Param: strconv.Itoa(max)})
+		}
+	}
+	required("id", user.Id, 40)
+	required("username", user.Username, 100)
+	if len(user.Email) > 0 {
+		if _, err := mail.ParseAddress(user.Email); err != nil {
+			errs = append(errs, core.ErrorMessage{Field: "email", Code: "email"})
+		} else if len(user.Email) > 100 {
+			errs = append(errs, core.ErrorMessage{Field: "email", Code: "max", Param: "100"})
+		}
+	}
+	required("phone", user.Phone, 18)
+	return errs
+}
+
+func writeStatus(res int64) int {
+	if res <= 0 {
+		return http.StatusNotFound
+	}
+	return http.StatusOK
+}
+
+func writeJSON(w http.ResponseWriter, status int, res interface{}) {
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,142 @@
+package usertest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const SpecFile = "api/swagger.yaml"
+
+// Spec holds the operations of an OpenAPI document, and the statuses of their responses.
+type Spec struct {
+	operations []operation
+}
+
+type operation struct {
+	Method   string
+	Path     string
+	segments []string
+	statuses map[int]bool
+}
+
+// LoadSpec reads the paths of an OpenAPI document, such as api/swagger.yaml.
+func LoadSpec(file string) (*Spec, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Paths map[string]map[string]struct {
+			Responses map[string]interface{} `yaml:"responses"`
+		} `yaml:"paths"`
+	}
+	if err = yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	spec := &Spec{}
+	for path, methods := range doc.Paths {
+		for method, op := range methods {
+			statuses := make(map[int]bool)
+			for code := range op.Responses {
+				status, err := strconv.Atoi(code)
+				if err != nil {
+					return nil, fmt.Errorf("%s: response %s of %s %s is not a status", file, code, strings.ToUpper(method), path)
+				}
+				statuses[status] = true
+			}
+			spec.operations = append(spec.operations, operation{Method: strings.ToUpper(method), Path: path, segments: strings.Split(strings.Trim(path, "/"), "/"), statuses: statuses})
+		}
+	}
+	return spec, nil
+}
+
+// FindSpec loads SpecFile from the working directory or the nearest of its parents, so that it is found from the directory of any package.
+func FindSpec() (*Spec, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		file := filepath.Join(dir, SpecFile)
+		if _, err := os.Stat(file); err == nil {
+			return LoadSpec(file)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, errors.New(SpecFile + " is not found")
+		}
+		dir = parent
+	}
+}
+
+// Check returns an error when the operation of the method and path is not in the spec, or does not declare the status.
+// A literal segment, such as /users/search, is preferred to a parameter, such as /users/{userId}.
+func (s *Spec) Check(method string, path string, status int) error {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var found *operation
+	best := -1
+	for i := range s.operations {
+		op := &s.operations[i]
+		if op.Method != method {
+			continue
+		}
+		if literals, ok := matchPath(op.segments, segments); ok && literals > best {
+			found, best = op, literals
+		}
+	}
+	if found == nil {
+		return fmt.Errorf("%s %s is not in the spec", method, path)
+	}
+	if !found.statuses[status] {
+		return fmt.Errorf("status %d of %s %s is not declared by %s %s", status, method, path, found.Method, found.Path)
+	}
+	return nil
+}
+
+// Handler reports to t the responses of next which do not conform to the spec.
+func (s *Spec) Handler(t T, name string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		if err := s.Check(r.Method, r.URL.Path, sw.status); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	})
+}
+
+func matchPath(template []string, segments []string) (int, bool) {
+	if len(template) != len(segments) {
+		return 0, false
+	}
+	literals := 0
+	for i, segment := range template {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if len(segments[i]) == 0 {
+				return 0, false
+			}
+			continue
+		}
+		if segment != segments[i] {
+			return 0, false
+		}
+		literals++
+	}
+	return literals, true
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -2,51 +2,91 @@ package main
 
 import (
 	"context"
-	"github.com/core-go/config"
 	"github.com/core-go/core"
 	mid "github.com/core-go/log/middleware"
-	"github.com/core-go/log/strings"
 	"github.com/core-go/log/zap"
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
+	"net/http"
 
 	"go-service/internal/app"
+	"go-service/pkg/auth"
+	"go-service/pkg/lifecycle"
+	"go-service/pkg/mask"
+	"go-service/pkg/reload"
+	"go-service/pkg/requestid"
 )
 
 func main() {
-	var cfg app.Config
-	err := config.Load(&cfg, "configs/config")
+	cfg, err := app.LoadConfig()
 	if err != nil {
 		panic(err)
 	}
 	r := mux.NewRouter()
 
 	log.Initialize(cfg.Log)
+	r.Use(requestid.Handle)
 	r.Use(mid.BuildContext)
-	logger := mid.NewMaskLogger(MaskLog, MaskLog)
-	if log.IsInfoEnable() {
-		r.Use(mid.Logger(cfg.MiddleWare, log.InfoFields, logger))
+	r.Use(auth.LogFields(cfg.Auth.Claims))
+	masker, err := mask.NewReloadable(cfg.Mask)
+	if err != nil {
+		panic(err)
 	}
+	requestLog := reload.NewMiddleware(requestLogger(cfg.MiddleWare, masker))
+	r.Use(requestLog.Handle)
 	r.Use(mid.Recover(log.PanicMsg))
 
 	ctx := context.Background()
-	err = app.Route(ctx, r, cfg)
+	// the app updates masker on reload, for the request log as well as for its own logs
+	application, err := app.Route(ctx, r, cfg, masker)
 	if err != nil {
 		panic(err)
 	}
+	application.Reloader.OnReload(func(c app.Config) error {
+		requestLog.Set(requestLogger(c.MiddleWare, masker))
+		return nil
+	})
+	var metricsServer *http.Server
+	if application.Metrics != nil {
+		metricsServer = application.Metrics.Server()
+	}
+	if metricsServer != nil {
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error(ctx, err.Error())
+			}
+		}()
+	}
+	if application.AdminServer != nil {
+		go func() {
+			if err := application.AdminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error(ctx, err.Error())
+			}
+		}()
+	}
 	log.Info(ctx, core.ServerInfo(cfg.Server))
-	server := core.CreateServer(cfg.Server, r)
-	if err = server.ListenAndServe(); err != nil {
+	server := core.CreateServer(cfg.Server, r, application.TLS)
+	// the metrics and admin servers are drained with the server, so that in-flight scrapes and admin calls complete
+	if err = lifecycle.ListenAndServe(ctx, server, cfg.Shutdown, application.Readiness, log.LogInfo, metricsServer, application.AdminServer); err != nil {
+		log.Error(ctx, err.Error())
+	}
+	if err = application.Close(); err != nil {
 		log.Error(ctx, err.Error())
 	}
 }
 
-func MaskLog(obj map[string]interface{}){
-	v, ok := obj["phone"]
-	if ok {
-		s, ok2 := v.(string)
-		if ok2 && len(s) > 3 {
-			obj["phone"] = strings.Mask(s, 0, 3, "*")
-		}
+// requestLogger logs requests while the info level is enabled, so that a log level change applies without restart.
+func requestLogger(c mid.LogConfig, masker *mask.Reloadable) func(http.Handler) http.Handler {
+	logger := mid.NewMaskLogger(masker.Mask, masker.Mask)
+	logged := mid.Logger(c, log.InfoFields, logger)
+	return func(next http.Handler) http.Handler {
+		h := logged(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if log.IsInfoEnable() {
+				h.ServeHTTP(w, r)
+			} else {
+				next.ServeHTTP(w, r)
+			}
+		})
 	}
 }
@@ -0,0 +1,52 @@
+package admin
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set by the linker: go build -ldflags "-X go-service/pkg/admin.Version=1.2.0 -X go-service/pkg/admin.Commit=$(git rev-parse HEAD)"
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+type BuildInfo struct {
+	Version   string `json:"version,omitempty"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"buildTime,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"goVersion"`
+	Module    string `json:"module,omitempty"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+// Build returns the linker values, falling back to the VCS stamp embedded by the go command.
+func Build() BuildInfo {
+	b := BuildInfo{Version: Version, Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version(), OS: runtime.GOOS, Arch: runtime.GOARCH}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return b
+	}
+	b.Module = info.Main.Path
+	if len(b.Version) == 0 && info.Main.Version != "(devel)" {
+		b.Version = info.Main.Version
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if len(b.Commit) == 0 {
+				b.Commit = s.Value
+			}
+		case "vcs.time":
+			if len(b.BuildTime) == 0 {
+				b.BuildTime = s.Value
+			}
+		case "vcs.modified":
+			b.Modified = s.Value == "true"
+		}
+	}
+	return b
+}
@@ -0,0 +1,8 @@
+package admin
+
+type Config struct {
+	Enabled bool   `yaml:"enabled" mapstructure:"enabled" json:"enabled,omitempty" gorm:"column:enabled" bson:"enabled,omitempty" dynamodbav:"enabled,omitempty" firestore:"enabled,omitempty"`
+	Host    string `yaml:"host" mapstructure:"host" json:"host,omitempty" gorm:"column:host" bson:"host,omitempty" dynamodbav:"host,omitempty" firestore:"host,omitempty"`
+	Port    *int64 `yaml:"port" mapstructure:"port" json:"port,omitempty" gorm:"column:port" bson:"port,omitempty" dynamodbav:"port,omitempty" firestore:"port,omitempty"`
+	Pprof   bool   `yaml:"pprof" mapstructure:"pprof" json:"pprof,omitempty" gorm:"column:pprof" bson:"pprof,omitempty" dynamodbav:"pprof,omitempty" firestore:"pprof,omitempty"`
+}
@@ -0,0 +1,104 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	rpprof "runtime/pprof"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"go-service/pkg/problem"
+)
+
+type Handler struct {
+	DB       *sql.DB
+	Level    func() string
+	SetLevel func(string) error
+}
+
+func NewHandler(db *sql.DB, level func() string, setLevel func(string) error) *Handler {
+	return &Handler{DB: db, Level: level, SetLevel: setLevel}
+}
+
+func (h *Handler) Build(w http.ResponseWriter, r *http.Request) {
+	JSON(w, http.StatusOK, Build())
+}
+
+func (h *Handler) DBStats(w http.ResponseWriter, r *http.Request) {
+	s := h.DB.Stats()
+	JSON(w, http.StatusOK, map[string]interface{}{
+		"maxOpenConnections": s.MaxOpenConnections,
+		"openConnections":    s.OpenConnections,
+		"inUse":              s.InUse,
+		"idle":               s.Idle,
+		"waitCount":          s.WaitCount,
+		"waitDuration":       s.WaitDuration.String(),
+		"maxIdleClosed":      s.MaxIdleClosed,
+		"maxIdleTimeClosed":  s.MaxIdleTimeClosed,
+		"maxLifetimeClosed":  s.MaxLifetimeClosed,
+	})
+}
+
+// Goroutines writes the stack of every goroutine as text, or only the count with ?count=true.
+func (h *Handler) Goroutines(w http.ResponseWriter, r *http.Request) {
+	if count, _ := strconv.ParseBool(r.URL.Query().Get("count")); count {
+		JSON(w, http.StatusOK, map[string]int{"goroutines": runtime.NumGoroutine()})
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rpprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+func (h *Handler) LogLevel(w http.ResponseWriter, r *http.Request) {
+	JSON(w, http.StatusOK, map[string]string{"level": h.Level()})
+}
+
+// SetLogLevel changes the level from a body such as {"level":"debug"}. The level of the config file applies again at the next reload.
+func (h *Handler) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Level string `json:"level"`
+	}
+	er1 := json.NewDecoder(r.Body).Decode(&body)
+	defer r.Body.Close()
+	if er1 != nil {
+		problem.Error(w, r, er1.Error(), http.StatusBadRequest)
+		return
+	}
+	if er2 := h.SetLevel(body.Level); er2 != nil {
+		problem.Error(w, r, er2.Error(), http.StatusBadRequest)
+		return
+	}
+	JSON(w, http.StatusOK, map[string]string{"level": h.Level()})
+}
+
+// Pprof registers the net/http/pprof handlers under /debug/pprof.
+func Pprof(r *mux.Router) {
+	r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	r.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	r.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+}
+
+// Server returns the server of the admin port, listening on 127.0.0.1 unless a host is configured, or nil when no port is set.
+func Server(c Config, handler http.Handler) *http.Server {
+	if c.Port == nil {
+		return nil
+	}
+	host := c.Host
+	if len(host) == 0 {
+		host = "127.0.0.1"
+	}
+	return &http.Server{Addr: host + ":" + strconv.FormatInt(*c.Port, 10), Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+}
+
+func JSON(w http.ResponseWriter, code int, res interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	return json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"go-service/pkg/problem"
+)
+
+const (
+	HeaderApiKey    = "X-API-Key"
+	SchemeApiKey    = "ApiKey"
+	PrincipalApiKey = "apikey"
+)
+
+type ApiKeyAuthenticator struct {
+	Verify   func(ctx context.Context, key string) (*Principal, error)
+	LogError func(context.Context, string, ...map[string]interface{})
+}
+
+func NewApiKeyAuthenticator(verify func(ctx context.Context, key string) (*Principal, error), logError func(context.Context, string, ...map[string]interface{})) *ApiKeyAuthenticator {
+	return &ApiKeyAuthenticator{Verify: verify, LogError: logError}
+}
+
+func (a *ApiKeyAuthenticator) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := ApiKey(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		principal, err := a.Verify(r.Context(), key)
+		if err != nil {
+			a.LogError(r.Context(), err.Error())
+			problem.Error(w, r, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if principal == nil {
+			Unauthorized(w, r, "invalid_token")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func ApiKey(r *http.Request) (string, bool) {
+	if key := strings.TrimSpace(r.Header.Get(HeaderApiKey)); len(key) > 0 {
+		return key, true
+	}
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) == 2 && strings.EqualFold(parts[0], SchemeApiKey) {
+		key := strings.TrimSpace(parts[1])
+		return key, len(key) > 0
+	}
+	return "", false
+}
+
+func Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := GetPrincipal(r.Context()); !ok {
+			Unauthorized(w, r, "")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := GetPrincipal(r.Context())
+			if !ok {
+				Unauthorized(w, r, "")
+				return
+			}
+			if !principal.HasRole(role) {
+				problem.Error(w, r, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestApiKeyAuthenticator(t *testing.T) {
+	verify := func(ctx context.Context, key string) (*Principal, error) {
+		switch key {
+		case "gsk_valid":
+			return &Principal{Subject: "billing", Type: PrincipalApiKey, Scopes: []string{"users:read"}}, nil
+		case "gsk_error":
+			return nil, errors.New("database is down")
+		default:
+			return nil, nil
+		}
+	}
+	a := NewApiKeyAuthenticator(verify, func(context.Context, string, ...map[string]interface{}) {})
+	var principal *Principal
+	h := a.Authenticate(Require(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = GetPrincipal(r.Context())
+	})))
+	tests := []struct {
+		header string
+		value  string
+		status int
+	}{
+		{HeaderApiKey, "gsk_valid", http.StatusOK},
+		{"Authorization", "ApiKey gsk_valid", http.StatusOK},
+		{"Authorization", "apikey  gsk_valid ", http.StatusOK},
+		{HeaderApiKey, "gsk_unknown", http.StatusUnauthorized},
+		{HeaderApiKey, "gsk_error", http.StatusInternalServerError},
+		{"Authorization", "ApiKey ", http.StatusUnauthorized},
+		{"Authorization", "Bearer gsk_valid", http.StatusUnauthorized},
+		{"", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		principal = nil
+		r := httptest.NewRequest(http.MethodGet, "/users/ironman", nil)
+		if len(tt.header) > 0 {
+			r.Header.Set(tt.header, tt.value)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s: %q: status %d, want %d", tt.header, tt.value, w.Code, tt.status)
+		}
+		if tt.status == http.StatusOK && (principal == nil || principal.Subject != "billing" || !principal.HasScope("users:read")) {
+			t.Errorf("%s: %q: principal = %+v", tt.header, tt.value, principal)
+		}
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	h := RequireRole("admin")(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	for _, tt := range []struct {
+		principal *Principal
+		status    int
+	}{
+		{nil, http.StatusUnauthorized},
+		{&Principal{Roles: []string{"user"}}, http.StatusForbidden},
+		{&Principal{Roles: []string{"user", "admin"}}, http.StatusOK},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/admin/config", nil)
+		if tt.principal != nil {
+			r = r.WithContext(WithPrincipal(r.Context(), tt.principal))
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("RequireRole(admin) with %+v: status %d, want %d", tt.principal, w.Code, tt.status)
+		}
+	}
+}
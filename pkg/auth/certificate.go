@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"crypto/x509"
+	"net/http"
+)
+
+const PrincipalCertificate = "certificate"
+
+// Certificate authenticates the requests having a client certificate verified by the TLS config of the server.
+// A request already authenticated, or without verified certificate, is passed as is, so Require must come after it.
+func Certificate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := GetPrincipal(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cert, ok := ClientCertificate(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), CertificatePrincipal(cert))))
+	})
+}
+
+// ClientCertificate returns the leaf of the first verified chain. Certificates the server has not verified are ignored.
+func ClientCertificate(r *http.Request) (*x509.Certificate, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	return r.TLS.VerifiedChains[0][0], true
+}
+
+// CertificatePrincipal maps a client certificate to a principal. The subject is its first URI, a SPIFFE id for example, or else its common name,
+// and the roles are its organizational units.
+func CertificatePrincipal(cert *x509.Certificate) *Principal {
+	subject := cert.Subject.CommonName
+	uris := make([]string, 0, len(cert.URIs))
+	for _, uri := range cert.URIs {
+		uris = append(uris, uri.String())
+	}
+	if len(uris) > 0 {
+		subject = uris[0]
+	}
+	claims := map[string]interface{}{
+		"common_name": cert.Subject.CommonName,
+		"issuer":      cert.Issuer.String(),
+		"serial":      cert.SerialNumber.String(),
+	}
+	if len(uris) > 0 {
+		claims["uris"] = uris
+	}
+	if len(cert.DNSNames) > 0 {
+		claims["dns_names"] = cert.DNSNames
+	}
+	return &Principal{Subject: subject, Type: PrincipalCertificate, Roles: cert.Subject.OrganizationalUnit, Claims: claims}
+}
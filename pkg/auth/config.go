@@ -0,0 +1,24 @@
+package auth
+
+import "time"
+
+type Config struct {
+	Enabled         bool              `yaml:"enabled" mapstructure:"enabled" json:"enabled,omitempty" gorm:"column:enabled" bson:"enabled,omitempty" dynamodbav:"enabled,omitempty" firestore:"enabled,omitempty"`
+	Header          string            `yaml:"header" mapstructure:"header" json:"header,omitempty" gorm:"column:header" bson:"header,omitempty" dynamodbav:"header,omitempty" firestore:"header,omitempty"`
+	Algorithms      string            `yaml:"algorithms" mapstructure:"algorithms" json:"algorithms,omitempty" gorm:"column:algorithms" bson:"algorithms,omitempty" dynamodbav:"algorithms,omitempty" firestore:"algorithms,omitempty"`
+	SecretFile      string            `yaml:"secret_file" mapstructure:"secret_file" json:"secretFile,omitempty" gorm:"column:secretfile" bson:"secretFile,omitempty" dynamodbav:"secretFile,omitempty" firestore:"secretFile,omitempty"`
+	KeyFiles        map[string]string `yaml:"key_files" mapstructure:"key_files" json:"keyFiles,omitempty" gorm:"column:keyfiles" bson:"keyFiles,omitempty" dynamodbav:"keyFiles,omitempty" firestore:"keyFiles,omitempty"`
+	Jwks            string            `yaml:"jwks" mapstructure:"jwks" json:"jwks,omitempty" gorm:"column:jwks" bson:"jwks,omitempty" dynamodbav:"jwks,omitempty" firestore:"jwks,omitempty"`
+	RefreshInterval time.Duration     `yaml:"refresh_interval" mapstructure:"refresh_interval" json:"refreshInterval,omitempty" gorm:"column:refreshinterval" bson:"refreshInterval,omitempty" dynamodbav:"refreshInterval,omitempty" firestore:"refreshInterval,omitempty"`
+	Issuer          string            `yaml:"issuer" mapstructure:"issuer" json:"issuer,omitempty" gorm:"column:issuer" bson:"issuer,omitempty" dynamodbav:"issuer,omitempty" firestore:"issuer,omitempty"`
+	Audience        string            `yaml:"audience" mapstructure:"audience" json:"audience,omitempty" gorm:"column:audience" bson:"audience,omitempty" dynamodbav:"audience,omitempty" firestore:"audience,omitempty"`
+	ClockSkew       time.Duration     `yaml:"clock_skew" mapstructure:"clock_skew" json:"clockSkew,omitempty" gorm:"column:clockskew" bson:"clockSkew,omitempty" dynamodbav:"clockSkew,omitempty" firestore:"clockSkew,omitempty"`
+	RolesClaim      string            `yaml:"roles_claim" mapstructure:"roles_claim" json:"rolesClaim,omitempty" gorm:"column:rolesclaim" bson:"rolesClaim,omitempty" dynamodbav:"rolesClaim,omitempty" firestore:"rolesClaim,omitempty"`
+	PolicyFile      string            `yaml:"policy_file" mapstructure:"policy_file" json:"policyFile,omitempty" gorm:"column:policyfile" bson:"policyFile,omitempty" dynamodbav:"policyFile,omitempty" firestore:"policyFile,omitempty"`
+	ApiKeys         bool              `yaml:"api_keys" mapstructure:"api_keys" json:"apiKeys,omitempty" gorm:"column:apikeys" bson:"apiKeys,omitempty" dynamodbav:"apiKeys,omitempty" firestore:"apiKeys,omitempty"`
+	ClientCerts     bool              `yaml:"client_certs" mapstructure:"client_certs" json:"clientCerts,omitempty" gorm:"column:clientcerts" bson:"clientCerts,omitempty" dynamodbav:"clientCerts,omitempty" firestore:"clientCerts,omitempty"`
+	SigningKeys     map[string]string `yaml:"signing_keys" mapstructure:"signing_keys" json:"signingKeys,omitempty" gorm:"column:signingkeys" bson:"signingKeys,omitempty" dynamodbav:"signingKeys,omitempty" firestore:"signingKeys,omitempty"`
+	SignatureWindow time.Duration     `yaml:"signature_window" mapstructure:"signature_window" json:"signatureWindow,omitempty" gorm:"column:signaturewindow" bson:"signatureWindow,omitempty" dynamodbav:"signatureWindow,omitempty" firestore:"signatureWindow,omitempty"`
+	AdminRole       string            `yaml:"admin_role" mapstructure:"admin_role" json:"adminRole,omitempty" gorm:"column:adminrole" bson:"adminRole,omitempty" dynamodbav:"adminRole,omitempty" firestore:"adminRole,omitempty"`
+	Claims          map[string]string `yaml:"claims" mapstructure:"claims" json:"claims,omitempty" gorm:"column:claims" bson:"claims,omitempty" dynamodbav:"claims,omitempty" firestore:"claims,omitempty"`
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"go-service/pkg/problem"
+)
+
+const defaultAlgorithms = "HS256,RS256,ES256"
+
+type Authenticator struct {
+	Config   Config
+	Keys     *KeyStore
+	LogError func(context.Context, string, ...map[string]interface{})
+	parser   *jwt.Parser
+}
+
+func NewAuthenticator(c Config, keys *KeyStore, logError func(context.Context, string, ...map[string]interface{})) *Authenticator {
+	algorithms := c.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = defaultAlgorithms
+	}
+	if len(c.Header) == 0 {
+		c.Header = "Authorization"
+	}
+	if len(c.RolesClaim) == 0 {
+		c.RolesClaim = "roles"
+	}
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(strings.Split(algorithms, ",")),
+		jwt.WithLeeway(c.ClockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if len(c.Issuer) > 0 {
+		options = append(options, jwt.WithIssuer(c.Issuer))
+	}
+	if len(c.Audience) > 0 {
+		options = append(options, jwt.WithAudience(c.Audience))
+	}
+	return &Authenticator{Config: c, Keys: keys, LogError: logError, parser: jwt.NewParser(options...)}
+}
+
+func (a *Authenticator) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := GetPrincipal(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, ok := BearerToken(r, a.Config.Header)
+		if !ok {
+			Unauthorized(w, r, "")
+			return
+		}
+		ctx, err := a.Verify(r.Context(), token)
+		if err != nil {
+			Unauthorized(w, r, "invalid_token")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (a *Authenticator) Verify(ctx context.Context, token string) (context.Context, error) {
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		if len(kid) > 0 {
+			return a.Keys.Key(ctx, kid)
+		}
+		keys := a.Keys.Keys()
+		set := jwt.VerificationKeySet{Keys: make([]jwt.VerificationKey, 0, len(keys))}
+		for _, key := range keys {
+			set.Keys = append(set.Keys, key)
+		}
+		return set, nil
+	})
+	if err != nil {
+		return ctx, err
+	}
+	subject, _ := claims.GetSubject()
+	principal := &Principal{
+		Subject: subject,
+		Type:    "jwt",
+		Roles:   toStrings(claims[a.Config.RolesClaim]),
+		Scopes:  scopes(claims),
+		Claims:  claims,
+	}
+	ctx = WithPrincipal(ctx, principal)
+	for claim, key := range a.Config.Claims {
+		if v, ok := claims[claim]; ok && v != nil {
+			ctx = WithLogField(ctx, key, fmt.Sprint(v))
+		}
+	}
+	return ctx, nil
+}
+
+func BearerToken(r *http.Request, header string) (string, bool) {
+	if len(header) == 0 {
+		header = "Authorization"
+	}
+	h := r.Header.Get(header)
+	if len(h) < 7 || !strings.EqualFold(h[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(h[7:])
+	return token, len(token) > 0
+}
+func Unauthorized(w http.ResponseWriter, r *http.Request, code string) {
+	if len(code) > 0 {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="%s"`, code))
+	} else {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	problem.Error(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+func scopes(claims jwt.MapClaims) []string {
+	if s, ok := claims["scope"].(string); ok {
+		return strings.Fields(s)
+	}
+	return toStrings(claims["scp"])
+}
+func toStrings(v interface{}) []string {
+	switch x := v.(type) {
+	case string:
+		return strings.Split(x, ",")
+	case []interface{}:
+		s := make([]string, 0, len(x))
+		for _, e := range x {
+			if str, ok := e.(string); ok {
+				s = append(s, str)
+			}
+		}
+		return s
+	case []string:
+		return x
+	default:
+		return nil
+	}
+}
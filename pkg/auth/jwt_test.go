@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func writeFile(t *testing.T, name string, data string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if len(kid) > 0 {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"sub":   "ironman",
+		"iss":   "https://login.example.com/",
+		"aud":   "go-service",
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"roles": []string{"admin", "user"},
+		"scope": "users:read users:write",
+		"email": "tony.stark@gmail.com",
+	}
+}
+
+func newAuthenticator(t *testing.T, c Config) *Authenticator {
+	t.Helper()
+	keys, err := NewKeyStore(context.Background(), c, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewAuthenticator(c, keys, nil)
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := jwksOf(t, map[string]interface{}{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey})
+	c := Config{
+		SecretFile: writeFile(t, "secret", testSecret+"\n"),
+		Jwks:       writeFile(t, "jwks.json", jwks),
+		Issuer:     "https://login.example.com/",
+		Audience:   "go-service",
+		ClockSkew:  30 * time.Second,
+		Claims:     map[string]string{"sub": "userId", "email": "mail"},
+	}
+	a := newAuthenticator(t, c)
+
+	claims := func(change func(jwt.MapClaims)) jwt.MapClaims {
+		c := validClaims()
+		change(c)
+		return c
+	}
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"HS256", sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", validClaims()), true},
+		{"RS256", sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", validClaims()), true},
+		{"ES256", sign(t, jwt.SigningMethodES256, ecKey, "ec", validClaims()), true},
+		{"RS256 without kid", sign(t, jwt.SigningMethodRS256, rsaKey, "", validClaims()), true},
+		{"wrong secret", sign(t, jwt.SigningMethodHS256, []byte("another secret"), "", validClaims()), false},
+		{"wrong key", sign(t, jwt.SigningMethodRS256, otherKey, "rsa", validClaims()), false},
+		{"unknown kid", sign(t, jwt.SigningMethodRS256, otherKey, "other", validClaims()), false},
+		{"HS384 not allowed", sign(t, jwt.SigningMethodHS384, []byte(testSecret), "", validClaims()), false},
+		{"RS256 key as HMAC secret", sign(t, jwt.SigningMethodHS256, []byte(jwks), "rsa", validClaims()), false},
+		{"none", sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", validClaims()), false},
+		{"expired", sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() })), false},
+		{"expired within skew", sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-10 * time.Second).Unix() })), true},
+		{"no exp", sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(func(c jwt.MapClaims) { delete(c, "exp") })), false},
+		{"issued in the future", sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(func(c jwt.MapClaims) { c["iat"] = time.Now().Add(time.Hour).Unix() })), false},
+		{"wrong issuer", sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com/" })), false},
+		{"wrong audience", sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(func(c jwt.MapClaims) { c["aud"] = "other-service" })), false},
+		{"audience list", sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(func(c jwt.MapClaims) { c["aud"] = []string{"other-service", "go-service"} })), true},
+		{"malformed", "a.b.c", false},
+	}
+	for _, tt := range tests {
+		ctx, err := a.Verify(context.Background(), tt.token)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: Verify() error = %v, want ok %v", tt.name, err, tt.ok)
+			continue
+		}
+		if !tt.ok {
+			if _, ok := GetPrincipal(ctx); ok {
+				t.Errorf("%s: Verify() set a principal for an invalid token", tt.name)
+			}
+			continue
+		}
+		p, ok := GetPrincipal(ctx)
+		if !ok || p.Subject != "ironman" || p.Type != "jwt" || !p.HasRole("admin") || !p.HasScope("users:write") {
+			t.Errorf("%s: principal = %+v", tt.name, p)
+		}
+		if ctx.Value("userId") != "ironman" || ctx.Value("mail") != "tony.stark@gmail.com" {
+			t.Errorf("%s: claims are not in the context", tt.name)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	a := newAuthenticator(t, Config{SecretFile: writeFile(t, "secret", testSecret)})
+	var principal *Principal
+	h := a.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = GetPrincipal(r.Context())
+	}))
+	token := sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", validClaims())
+	tests := []struct {
+		header string
+		status int
+		error  string
+	}{
+		{"Bearer " + token, http.StatusOK, ""},
+		{"bearer " + token, http.StatusOK, ""},
+		{"", http.StatusUnauthorized, "Bearer"},
+		{"Basic dXNlcjpwYXNz", http.StatusUnauthorized, "Bearer"},
+		{"Bearer ", http.StatusUnauthorized, "Bearer"},
+		{"Bearer " + token + "x", http.StatusUnauthorized, `Bearer error="invalid_token"`},
+	}
+	for _, tt := range tests {
+		principal = nil
+		r := httptest.NewRequest(http.MethodGet, "/users/ironman", nil)
+		if len(tt.header) > 0 {
+			r.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.status || w.Header().Get("WWW-Authenticate") != tt.error {
+			t.Errorf("Authorization %q: status %d, WWW-Authenticate %q; want %d, %q", tt.header, w.Code, w.Header().Get("WWW-Authenticate"), tt.status, tt.error)
+		}
+		if tt.status == http.StatusOK && (principal == nil || principal.Subject != "ironman") {
+			t.Errorf("Authorization %q: principal = %+v", tt.header, principal)
+		}
+	}
+}
+
+func TestAuthenticateKeepsPrincipal(t *testing.T) {
+	a := newAuthenticator(t, Config{SecretFile: writeFile(t, "secret", testSecret)})
+	called := false
+	h := a.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := GetPrincipal(r.Context())
+		called = p.Type == PrincipalApiKey
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/users/ironman", nil)
+	r = r.WithContext(WithPrincipal(r.Context(), &Principal{Subject: "partner", Type: PrincipalApiKey}))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if !called {
+		t.Error("a request authenticated before must be passed as is")
+	}
+}
+
+func TestLogFields(t *testing.T) {
+	a := newAuthenticator(t, Config{SecretFile: writeFile(t, "secret", testSecret), Claims: map[string]string{"sub": "userId"}})
+	var outer context.Context
+	h := LogFields(a.Config.Claims)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outer = r.Context()
+		a.Authenticate(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(w, r)
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/users/ironman", nil)
+	r.Header.Set("Authorization", "Bearer "+sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", validClaims()))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if outer == nil || outer.Value("userId") != "ironman" {
+		t.Errorf("the context of the outer middlewares has userId %v, want ironman", outer.Value("userId"))
+	}
+	if outer.Value("email") != nil {
+		t.Errorf("the claims not in auth.claims must not be in the context")
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Token", "Bearer  abc ")
+	if token, ok := BearerToken(r, "X-Token"); !ok || token != "abc" {
+		t.Errorf("BearerToken() = %q, %v", token, ok)
+	}
+	if _, ok := BearerToken(r, ""); ok {
+		t.Error("BearerToken() must read Authorization by default")
+	}
+}
+
+func TestToStrings(t *testing.T) {
+	if got := strings.Join(toStrings("a,b"), "|"); got != "a|b" {
+		t.Errorf("toStrings(string) = %s", got)
+	}
+	if got := strings.Join(toStrings([]interface{}{"a", 1, "b"}), "|"); got != "a|b" {
+		t.Errorf("toStrings([]interface{}) = %s", got)
+	}
+	if got := toStrings(1); got != nil {
+		t.Errorf("toStrings(int) = %v", got)
+	}
+}
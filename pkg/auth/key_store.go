@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const minRefreshInterval = 30 * time.Second
+
+var ErrKeyNotFound = errors.New("signing key not found")
+
+type KeyStore struct {
+	Config   Config
+	Client   *http.Client
+	LogError func(context.Context, string, ...map[string]interface{})
+	mu       sync.RWMutex
+	keys     map[string]interface{}
+	loading  singleflight.Group
+	// attempted is the time of the last load, successful or not, so that unknown kids do not reload a failing JWKS on every request.
+	attempted time.Time
+}
+
+func NewKeyStore(ctx context.Context, c Config, client *http.Client, logError func(context.Context, string, ...map[string]interface{})) (*KeyStore, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	s := &KeyStore{Config: c, Client: client, LogError: logError}
+	if err := s.Load(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *KeyStore) Load(ctx context.Context) error {
+	_, err, _ := s.loading.Do("", func() (interface{}, error) {
+		s.mu.Lock()
+		s.attempted = time.Now()
+		s.mu.Unlock()
+		return nil, s.load(ctx)
+	})
+	return err
+}
+func (s *KeyStore) load(ctx context.Context) error {
+	keys := make(map[string]interface{})
+	if len(s.Config.SecretFile) > 0 {
+		secret, err := os.ReadFile(s.Config.SecretFile)
+		if err != nil {
+			return err
+		}
+		keys[""] = []byte(strings.TrimSpace(string(secret)))
+	}
+	for kid, file := range s.Config.KeyFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		key, err := ParsePublicKey(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		keys[kid] = key
+	}
+	if len(s.Config.Jwks) > 0 {
+		data, err := s.readJwks(ctx)
+		if err != nil {
+			return err
+		}
+		set, err := ParseJwks(data)
+		if err != nil {
+			return err
+		}
+		for kid, key := range set {
+			keys[kid] = key
+		}
+	}
+	if len(keys) == 0 {
+		return errors.New("no key is configured for authentication")
+	}
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+func (s *KeyStore) readJwks(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(s.Config.Jwks, "http://") && !strings.HasPrefix(s.Config.Jwks, "https://") {
+		return os.ReadFile(s.Config.Jwks)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.Config.Jwks, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot load jwks from %s: status %d", s.Config.Jwks, res.StatusCode)
+	}
+	return io.ReadAll(res.Body)
+}
+
+func (s *KeyStore) Run(ctx context.Context) {
+	if s.Config.RefreshInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.Config.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Load(ctx); err != nil && s.LogError != nil {
+				s.LogError(ctx, "cannot refresh authentication keys: "+err.Error())
+			}
+		}
+	}
+}
+
+func (s *KeyStore) Key(ctx context.Context, kid string) (interface{}, error) {
+	s.mu.RLock()
+	key, ok := s.keys[kid]
+	s.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if len(kid) > 0 {
+		if err := s.refresh(ctx); err != nil {
+			return nil, err
+		}
+		s.mu.RLock()
+		key, ok = s.keys[kid]
+		s.mu.RUnlock()
+		if ok {
+			return key, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// refresh loads the keys for an unknown kid, at most once every minRefreshInterval. The concurrent callers wait for the same load,
+// which is not cancelled with the request that started it.
+func (s *KeyStore) refresh(ctx context.Context) error {
+	_, err, _ := s.loading.Do("refresh", func() (interface{}, error) {
+		s.mu.RLock()
+		attempted := s.attempted
+		s.mu.RUnlock()
+		if time.Since(attempted) <= minRefreshInterval {
+			return nil, nil
+		}
+		return nil, s.Load(context.WithoutCancel(ctx))
+	})
+	return err
+}
+func (s *KeyStore) Keys() []interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]interface{}, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func ParsePublicKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	}
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+func ParseJwks(data []byte) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if len(k.Use) > 0 && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwk %s: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
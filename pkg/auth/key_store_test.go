@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func jwksOf(t *testing.T, keys map[string]interface{}) string {
+	t.Helper()
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	for kid, key := range keys {
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, jwk{Kty: "RSA", Kid: kid, Use: "sig", N: encodeBigInt(k.N), E: encodeBigInt(big.NewInt(int64(k.E)))})
+		case *ecdsa.PublicKey:
+			set.Keys = append(set.Keys, jwk{Kty: "EC", Kid: kid, Crv: "P-256", X: encodeBigInt(k.X), Y: encodeBigInt(k.Y)})
+		default:
+			t.Fatalf("unsupported key %T", key)
+		}
+	}
+	b, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestParseJwks(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	x := encodeBigInt(ecKey.X)
+	tests := []struct {
+		name  string
+		jwks  string
+		kids  []string
+		error bool
+	}{
+		{"rsa and ec", jwksOf(t, map[string]interface{}{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey}), []string{"rsa", "ec"}, false},
+		{"oct", `{"keys":[{"kty":"oct","kid":"hs","k":"c2VjcmV0"}]}`, []string{"hs"}, false},
+		{"encryption keys are skipped", `{"keys":[{"kty":"oct","kid":"enc","use":"enc","k":"c2VjcmV0"}]}`, nil, false},
+		{"point not on curve", `{"keys":[{"kty":"EC","kid":"ec","crv":"P-256","x":"` + x + `","y":"` + x + `"}]}`, nil, true},
+		{"unsupported curve", `{"keys":[{"kty":"EC","kid":"ec","crv":"P-192","x":"AA","y":"AA"}]}`, nil, true},
+		{"unsupported type", `{"keys":[{"kty":"OKP","kid":"ed"}]}`, nil, true},
+		{"invalid base64", `{"keys":[{"kty":"RSA","kid":"rsa","n":"!!","e":"AQAB"}]}`, nil, true},
+		{"invalid json", `{"keys":`, nil, true},
+	}
+	for _, tt := range tests {
+		keys, err := ParseJwks([]byte(tt.jwks))
+		if (err != nil) != tt.error {
+			t.Errorf("%s: ParseJwks() error = %v", tt.name, err)
+			continue
+		}
+		if len(keys) != len(tt.kids) {
+			t.Errorf("%s: ParseJwks() = %d keys, want %d", tt.name, len(keys), len(tt.kids))
+		}
+		for _, kid := range tt.kids {
+			if _, ok := keys[kid]; !ok {
+				t.Errorf("%s: ParseJwks() has no key %s", tt.name, kid)
+			}
+		}
+	}
+	keys, _ := ParseJwks([]byte(jwksOf(t, map[string]interface{}{"rsa": &rsaKey.PublicKey})))
+	if k, ok := keys["rsa"].(*rsa.PublicKey); !ok || !k.Equal(&rsaKey.PublicKey) {
+		t.Errorf("ParseJwks() = %v, want the RSA key", keys["rsa"])
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	pkix, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(time.Hour)}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &rsaKey.PublicKey, rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, block := range map[string]*pem.Block{
+		"pkix":        {Type: "PUBLIC KEY", Bytes: pkix},
+		"pkcs1":       {Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)},
+		"certificate": {Type: "CERTIFICATE", Bytes: cert},
+	} {
+		key, err := ParsePublicKey(pem.EncodeToMemory(block))
+		if err != nil {
+			t.Errorf("%s: ParsePublicKey() error = %v", name, err)
+			continue
+		}
+		if k, ok := key.(*rsa.PublicKey); !ok || !k.Equal(&rsaKey.PublicKey) {
+			t.Errorf("%s: ParsePublicKey() = %v", name, key)
+		}
+	}
+	if _, err := ParsePublicKey([]byte("not pem")); err == nil {
+		t.Error("ParsePublicKey() must fail without a PEM block")
+	}
+}
+
+// jwksServer serves the JWKS of keys, or fails with 503 while failing is set, and counts the requests.
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	jwks    string
+	failing atomic.Bool
+	hits    atomic.Int32
+}
+
+func newJwksServer(jwks string) *jwksServer {
+	s := &jwksServer{jwks: jwks}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.hits.Add(1)
+		if s.failing.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.Write([]byte(s.jwks))
+	}))
+	return s
+}
+func (s *jwksServer) set(jwks string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jwks = jwks
+}
+
+func TestKeyStoreReloadsUnknownKid(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	server := newJwksServer(jwksOf(t, map[string]interface{}{"old": &oldKey.PublicKey}))
+	defer server.Close()
+	s, err := NewKeyStore(context.Background(), Config{Jwks: server.URL}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.set(jwksOf(t, map[string]interface{}{"old": &oldKey.PublicKey, "new": &newKey.PublicKey}))
+	if _, err := s.Key(context.Background(), "new"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Key() = %v, want ErrKeyNotFound right after a load", err)
+	}
+	s.attempted = time.Now().Add(-time.Minute)
+	key, err := s.Key(context.Background(), "new")
+	if err != nil {
+		t.Fatalf("Key() error = %v after the rotation", err)
+	}
+	if k, ok := key.(*rsa.PublicKey); !ok || !k.Equal(&newKey.PublicKey) {
+		t.Errorf("Key() = %v, want the new key", key)
+	}
+	if hits := server.hits.Load(); hits != 2 {
+		t.Errorf("the JWKS was loaded %d times, want 2", hits)
+	}
+}
+
+func TestKeyStoreLimitsReloadsWhileFailing(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	server := newJwksServer(jwksOf(t, map[string]interface{}{"rsa": &rsaKey.PublicKey}))
+	defer server.Close()
+	s, err := NewKeyStore(context.Background(), Config{Jwks: server.URL}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.failing.Store(true)
+	s.attempted = time.Now().Add(-time.Minute)
+
+	for round := 0; round < 2; round++ {
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if _, err := s.Key(context.Background(), "random-"+strconv.Itoa(i)); err == nil {
+					t.Error("Key() of an unknown kid must fail")
+				}
+			}(i)
+		}
+		wg.Wait()
+	}
+	if hits := server.hits.Load(); hits != 2 {
+		t.Errorf("the failing JWKS was requested %d times, want 1 after the initial load", hits-1)
+	}
+	if _, err := s.Key(context.Background(), "rsa"); err != nil {
+		t.Errorf("Key() of a known kid = %v while the JWKS is failing", err)
+	}
+}
+
+func TestKeyStoreLoad(t *testing.T) {
+	if _, err := NewKeyStore(context.Background(), Config{}, nil, nil); err == nil {
+		t.Error("NewKeyStore() must fail without keys")
+	}
+	if _, err := NewKeyStore(context.Background(), Config{SecretFile: "missing"}, nil, nil); err == nil {
+		t.Error("NewKeyStore() must fail when a file is missing")
+	}
+	server := newJwksServer("")
+	defer server.Close()
+	server.failing.Store(true)
+	if _, err := NewKeyStore(context.Background(), Config{Jwks: server.URL}, nil, nil); err == nil {
+		t.Error("NewKeyStore() must fail when the JWKS cannot be loaded")
+	}
+	s, err := NewKeyStore(context.Background(), Config{SecretFile: writeFile(t, "secret", " "+testSecret+"\n")}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, err := s.Key(context.Background(), ""); err != nil || string(key.([]byte)) != testSecret {
+		t.Errorf("Key() = %v, %v; want the trimmed secret", key, err)
+	}
+}
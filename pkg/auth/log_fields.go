@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+type logFieldsKey struct{}
+
+// logFields is a context whose values of the configured keys are set after it is created, by the authenticators.
+// The request log reads the context of the request it wraps, which is created before the authentication.
+type logFields struct {
+	context.Context
+	keys   map[string]bool
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+func (c *logFields) Value(key interface{}) interface{} {
+	if _, ok := key.(logFieldsKey); ok {
+		return c
+	}
+	if k, ok := key.(string); ok && c.keys[k] {
+		c.mu.RLock()
+		v, ok := c.values[k]
+		c.mu.RUnlock()
+		if ok {
+			return v
+		}
+	}
+	return c.Context.Value(key)
+}
+
+// LogFields makes the values of the auth.claims keys visible to the middlewares placed after it, such as the request log,
+// once the request is authenticated. It does nothing when claims is empty.
+func LogFields(claims map[string]string) func(http.Handler) http.Handler {
+	keys := make(map[string]bool, len(claims))
+	for _, key := range claims {
+		keys[key] = true
+	}
+	return func(next http.Handler) http.Handler {
+		if len(keys) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := &logFields{Context: r.Context(), keys: keys, values: make(map[string]string, len(keys))}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// WithLogField stores the value under key in ctx, and in the context created by LogFields, if any.
+func WithLogField(ctx context.Context, key string, value string) context.Context {
+	if c, ok := ctx.Value(logFieldsKey{}).(*logFields); ok && c.keys[key] {
+		c.mu.Lock()
+		c.values[key] = value
+		c.mu.Unlock()
+	}
+	return context.WithValue(ctx, key, value)
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"errors"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ScopeAny  = "any"
+	ScopeSelf = "self"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+type Rule struct {
+	Actions []string `yaml:"actions" json:"actions,omitempty"`
+	Scope   string   `yaml:"scope" json:"scope,omitempty"`
+	Fields  []string `yaml:"fields" json:"fields,omitempty"`
+}
+
+type Policy struct {
+	Roles  map[string][]Rule `yaml:"roles" json:"roles,omitempty"`
+	Scopes map[string][]Rule `yaml:"scopes" json:"scopes,omitempty"`
+}
+
+func LoadPolicy(file string) (*Policy, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	var policy Policy
+	if err = decoder.Decode(&policy); err != nil {
+		return nil, err
+	}
+	for _, rules := range policy.Roles {
+		if err = validateRules(rules); err != nil {
+			return nil, err
+		}
+	}
+	for _, rules := range policy.Scopes {
+		if err = validateRules(rules); err != nil {
+			return nil, err
+		}
+	}
+	return &policy, nil
+}
+func validateRules(rules []Rule) error {
+	for _, rule := range rules {
+		if len(rule.Scope) > 0 && rule.Scope != ScopeAny && rule.Scope != ScopeSelf {
+			return errors.New("invalid rule scope: " + rule.Scope)
+		}
+		if len(rule.Actions) == 0 {
+			return errors.New("rule must have at least one action")
+		}
+	}
+	return nil
+}
+
+func (p *Policy) Allow(principal *Principal, action string, owner string) bool {
+	for _, rule := range p.rules(principal) {
+		if rule.matches(principal, action, owner) {
+			return true
+		}
+	}
+	return false
+}
+func (p *Policy) AllowAllFields(principal *Principal, action string, owner string) bool {
+	for _, rule := range p.rules(principal) {
+		if rule.matches(principal, action, owner) && rule.allFields() {
+			return true
+		}
+	}
+	return false
+}
+func (p *Policy) AllowFields(principal *Principal, action string, owner string, fields []string) bool {
+	for _, rule := range p.rules(principal) {
+		if rule.matches(principal, action, owner) && rule.allows(fields) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) rules(principal *Principal) []Rule {
+	if principal == nil {
+		return nil
+	}
+	var rules []Rule
+	for _, role := range principal.Roles {
+		rules = append(rules, p.Roles[role]...)
+	}
+	for _, scope := range principal.Scopes {
+		rules = append(rules, p.Scopes[scope]...)
+	}
+	return rules
+}
+func (r Rule) matches(principal *Principal, action string, owner string) bool {
+	if !contains(r.Actions, action) && !contains(r.Actions, "*") {
+		return false
+	}
+	if r.Scope == ScopeSelf {
+		return len(principal.Subject) > 0 && principal.Subject == owner
+	}
+	return true
+}
+func (r Rule) allFields() bool {
+	return len(r.Fields) == 0 || contains(r.Fields, "*")
+}
+func (r Rule) allows(fields []string) bool {
+	if r.allFields() {
+		return true
+	}
+	for _, field := range fields {
+		if !contains(r.Fields, field) {
+			return false
+		}
+	}
+	return true
+}
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"testing"
+)
+
+func testPolicy() *Policy {
+	return &Policy{
+		Roles: map[string][]Rule{
+			"admin":   {{Actions: []string{"*"}}},
+			"user":    {{Actions: []string{"read", "update", "patch"}, Scope: ScopeSelf, Fields: []string{"email", "phone"}}},
+			"auditor": {{Actions: []string{"read", "search"}, Scope: ScopeAny}},
+		},
+		Scopes: map[string][]Rule{
+			"users:read": {{Actions: []string{"read"}}},
+		},
+	}
+}
+
+func TestPolicyAllow(t *testing.T) {
+	p := testPolicy()
+	tests := []struct {
+		name      string
+		principal *Principal
+		action    string
+		owner     string
+		want      bool
+	}{
+		{"no principal", nil, "read", "ironman", false},
+		{"admin wildcard", &Principal{Roles: []string{"admin"}}, "delete", "ironman", true},
+		{"self", &Principal{Subject: "ironman", Roles: []string{"user"}}, "update", "ironman", true},
+		{"not self", &Principal{Subject: "ironman", Roles: []string{"user"}}, "update", "spiderman", false},
+		{"self without subject", &Principal{Roles: []string{"user"}}, "read", "", false},
+		{"action not listed", &Principal{Subject: "ironman", Roles: []string{"user"}}, "delete", "ironman", false},
+		{"any", &Principal{Roles: []string{"auditor"}}, "search", "", true},
+		{"scope", &Principal{Scopes: []string{"users:read"}}, "read", "wolverine", true},
+		{"scope action not listed", &Principal{Scopes: []string{"users:read"}}, "create", "", false},
+		{"unknown role", &Principal{Roles: []string{"guest"}}, "read", "ironman", false},
+		{"roles add up", &Principal{Subject: "ironman", Roles: []string{"auditor", "user"}}, "patch", "ironman", true},
+	}
+	for _, tt := range tests {
+		if got := p.Allow(tt.principal, tt.action, tt.owner); got != tt.want {
+			t.Errorf("%s: Allow() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyFields(t *testing.T) {
+	p := testPolicy()
+	user := &Principal{Subject: "ironman", Roles: []string{"user"}}
+	admin := &Principal{Roles: []string{"admin"}}
+	if p.AllowAllFields(user, "patch", "ironman") {
+		t.Error("AllowAllFields() of a rule with fields must be false")
+	}
+	if !p.AllowAllFields(admin, "patch", "ironman") {
+		t.Error("AllowAllFields() of a rule without fields must be true")
+	}
+	tests := []struct {
+		fields []string
+		want   bool
+	}{
+		{[]string{"email"}, true},
+		{[]string{"email", "phone"}, true},
+		{[]string{"email", "username"}, false},
+		{nil, true},
+	}
+	for _, tt := range tests {
+		if got := p.AllowFields(user, "patch", "ironman", tt.fields); got != tt.want {
+			t.Errorf("AllowFields(%v) = %v, want %v", tt.fields, got, tt.want)
+		}
+	}
+	if p.AllowFields(user, "patch", "spiderman", []string{"email"}) {
+		t.Error("AllowFields() must check the owner")
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name  string
+		yaml  string
+		error bool
+	}{
+		{"valid", "roles:\n  user:\n    - actions: [read]\n      scope: self\n      fields: [email]\nscopes:\n  users:read:\n    - actions: [read]\n", false},
+		{"unknown field", "roles:\n  user:\n    - actions: [read]\n      owner: self\n", true},
+		{"invalid scope", "roles:\n  user:\n    - actions: [read]\n      scope: team\n", true},
+		{"no action", "scopes:\n  users:read:\n    - scope: any\n", true},
+		{"invalid yaml", "roles: [", true},
+	}
+	for _, tt := range tests {
+		policy, err := LoadPolicy(writeFile(t, "policy.yml", tt.yaml))
+		if (err != nil) != tt.error {
+			t.Errorf("%s: LoadPolicy() error = %v", tt.name, err)
+			continue
+		}
+		if err == nil && !policy.Allow(&Principal{Subject: "ironman", Roles: []string{"user"}}, "read", "ironman") {
+			t.Errorf("%s: LoadPolicy() = %+v", tt.name, policy)
+		}
+	}
+	if _, err := LoadPolicy("missing.yml"); err == nil {
+		t.Error("LoadPolicy() must fail when the file is missing")
+	}
+}
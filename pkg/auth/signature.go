@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go-service/pkg/problem"
+	"go-service/pkg/signature"
+)
+
+const (
+	PrincipalSignature = "signature"
+	maxSignedBody      = 10 << 20
+)
+
+// SignatureAuthenticator authenticates the requests signed with one of the keys of auth.signing_keys, as pkg/client signs them.
+// The principal is the key id, so a partner having several keys during a rotation is seen as one subject per key.
+type SignatureAuthenticator struct {
+	Config   Config
+	Verifier *signature.Verifier
+	LogError func(context.Context, string, ...map[string]interface{})
+}
+
+// NewSignatureAuthenticator loads the keys of auth.signing_keys. The nonces are kept in memory when nonces is nil.
+func NewSignatureAuthenticator(c Config, nonces signature.NonceStore, logError func(context.Context, string, ...map[string]interface{})) (*SignatureAuthenticator, error) {
+	keys, err := LoadSigningKeys(c.SigningKeys)
+	if err != nil {
+		return nil, err
+	}
+	return &SignatureAuthenticator{Config: c, Verifier: signature.NewVerifier(keys, c.SignatureWindow, nonces), LogError: logError}, nil
+}
+
+// LoadSigningKeys reads the shared secret of each key id from its file.
+func LoadSigningKeys(files map[string]string) (map[string][]byte, error) {
+	if len(files) == 0 {
+		return nil, errors.New("auth.signing_keys is empty")
+	}
+	keys := make(map[string][]byte, len(files))
+	for id, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		secret := strings.TrimSpace(string(b))
+		if len(secret) == 0 {
+			return nil, errors.New("auth.signing_keys." + id + " is empty")
+		}
+		keys[id] = []byte(secret)
+	}
+	return keys, nil
+}
+
+// Run reads the key files again every auth.refresh_interval, so a secret can be replaced without restart. The ids are those of the config at startup.
+func (a *SignatureAuthenticator) Run(ctx context.Context) {
+	if a.Config.RefreshInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(a.Config.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			keys, err := LoadSigningKeys(a.Config.SigningKeys)
+			if err != nil {
+				if a.LogError != nil {
+					a.LogError(ctx, "cannot refresh signing keys: "+err.Error())
+				}
+				continue
+			}
+			a.Verifier.SetKeys(keys)
+		}
+	}
+}
+
+// Authenticate verifies the requests having an X-Signature header; the others are passed as is, so Require must come after it.
+func (a *SignatureAuthenticator) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.Header.Get(signature.HeaderSignature)) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSignedBody))
+		r.Body.Close()
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				problem.Error(w, r, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			problem.Error(w, r, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		keyId := r.Header.Get(signature.HeaderKeyId)
+		err = a.Verifier.Verify(r.Context(), signature.Request{
+			Method:    r.Method,
+			URI:       r.URL.RequestURI(),
+			Body:      body,
+			KeyId:     keyId,
+			Timestamp: r.Header.Get(signature.HeaderTimestamp),
+			Nonce:     r.Header.Get(signature.HeaderNonce),
+			Signature: r.Header.Get(signature.HeaderSignature),
+		})
+		if err != nil {
+			if invalidSignature(err) {
+				Unauthorized(w, r, "invalid_signature")
+				return
+			}
+			a.LogError(r.Context(), "cannot verify signature: "+err.Error())
+			problem.Error(w, r, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		principal := &Principal{Subject: keyId, Type: PrincipalSignature, Claims: map[string]interface{}{"key_id": keyId}}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func invalidSignature(err error) bool {
+	for _, e := range []error{signature.ErrMissing, signature.ErrUnknownKey, signature.ErrExpired, signature.ErrNonce, signature.ErrInvalid, signature.ErrReplayed} {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-service/pkg/client"
+	"go-service/pkg/signature"
+)
+
+func TestSignatureAuthenticator(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "partner.key")
+	if err := os.WriteFile(file, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewSignatureAuthenticator(Config{SigningKeys: map[string]string{"partner": file}}, nil, func(context.Context, string, ...map[string]interface{}) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var principal *Principal
+	var body string
+	h := a.Authenticate(Require(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = GetPrincipal(r.Context())
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	})))
+	serve := func(r *http.Request) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+	newRequest := func(secret string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"id":"ironman"}`))
+		signer := &client.Signer{KeyId: "partner", Secret: []byte(secret)}
+		if err := signer.Sign(r); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	r := newRequest("secret")
+	if status := serve(r.Clone(r.Context())); status != http.StatusOK {
+		t.Fatalf("status %d, want 200", status)
+	}
+	if principal == nil || principal.Subject != "partner" || principal.Type != PrincipalSignature || body != `{"id":"ironman"}` {
+		t.Errorf("principal = %+v, body %q", principal, body)
+	}
+	replay := newRequest("secret")
+	replay.Header = r.Header
+	if status := serve(replay); status != http.StatusUnauthorized {
+		t.Errorf("replay: status %d, want 401", status)
+	}
+	if status := serve(newRequest("guess")); status != http.StatusUnauthorized {
+		t.Errorf("wrong secret: status %d, want 401", status)
+	}
+	unsigned := httptest.NewRequest(http.MethodPost, "/users", nil)
+	if status := serve(unsigned); status != http.StatusUnauthorized {
+		t.Errorf("unsigned: status %d, want 401 from Require", status)
+	}
+	tooLarge := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(strings.Repeat("a", maxSignedBody+1)))
+	tooLarge.Header.Set(signature.HeaderSignature, "x")
+	if status := serve(tooLarge); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("too large: status %d, want 413", status)
+	}
+}
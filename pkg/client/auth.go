@@ -0,0 +1,169 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"go-service/pkg/signature"
+)
+
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthApiKey = "api_key"
+	AuthHmac   = "hmac"
+
+	HeaderKeyId     = signature.HeaderKeyId
+	HeaderTimestamp = signature.HeaderTimestamp
+	HeaderNonce     = signature.HeaderNonce
+	HeaderSignature = signature.HeaderSignature
+)
+
+type AuthConfig struct {
+	Type     string `yaml:"type" mapstructure:"type" json:"type,omitempty" gorm:"column:type" bson:"type,omitempty" dynamodbav:"type,omitempty" firestore:"type,omitempty"`
+	Username string `yaml:"username" mapstructure:"username" json:"username,omitempty" gorm:"column:username" bson:"username,omitempty" dynamodbav:"username,omitempty" firestore:"username,omitempty"`
+	Password string `yaml:"password" mapstructure:"password" json:"password,omitempty" gorm:"column:password" bson:"password,omitempty" dynamodbav:"password,omitempty" firestore:"password,omitempty"`
+	Token    string `yaml:"token" mapstructure:"token" json:"token,omitempty" gorm:"column:token" bson:"token,omitempty" dynamodbav:"token,omitempty" firestore:"token,omitempty"`
+	Header   string `yaml:"header" mapstructure:"header" json:"header,omitempty" gorm:"column:header" bson:"header,omitempty" dynamodbav:"header,omitempty" firestore:"header,omitempty"`
+	ApiKey   string `yaml:"api_key" mapstructure:"api_key" json:"apiKey,omitempty" gorm:"column:apikey" bson:"apiKey,omitempty" dynamodbav:"apiKey,omitempty" firestore:"apiKey,omitempty"`
+	KeyId    string `yaml:"key_id" mapstructure:"key_id" json:"keyId,omitempty" gorm:"column:keyid" bson:"keyId,omitempty" dynamodbav:"keyId,omitempty" firestore:"keyId,omitempty"`
+	Secret   string `yaml:"secret" mapstructure:"secret" json:"secret,omitempty" gorm:"column:secret" bson:"secret,omitempty" dynamodbav:"secret,omitempty" firestore:"secret,omitempty"`
+}
+
+// TokenSource returns the token of a Bearer authorization, which can change from one request to the next.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+type StaticToken string
+
+func (t StaticToken) Token(ctx context.Context) (string, error) {
+	return string(t), nil
+}
+
+// AuthTransport sets the credentials of every request. It is placed inside the retries, so each attempt is authorized again.
+type AuthTransport struct {
+	Base      http.RoundTripper
+	Authorize func(req *http.Request) error
+}
+
+func (t *AuthTransport) Unwrap() http.RoundTripper {
+	return t.Base
+}
+func (t *AuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	if err := t.Authorize(r); err != nil {
+		closeBody(req)
+		return nil, err
+	}
+	return t.Base.RoundTrip(r)
+}
+
+func authorize(f func(req *http.Request) error) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &AuthTransport{Base: next, Authorize: f}
+	}
+}
+
+// Auth returns the middleware of the config type: basic, bearer, api_key or hmac.
+func Auth(c AuthConfig) (Middleware, error) {
+	switch c.Type {
+	case AuthBasic:
+		if len(c.Username) == 0 {
+			return nil, errors.New("auth.username is required for basic auth")
+		}
+		return Basic(c.Username, c.Password), nil
+	case AuthBearer:
+		if len(c.Token) == 0 {
+			return nil, errors.New("auth.token is required for bearer auth")
+		}
+		return Bearer(StaticToken(c.Token)), nil
+	case AuthApiKey:
+		if len(c.ApiKey) == 0 {
+			return nil, errors.New("auth.api_key is required for api_key auth")
+		}
+		return ApiKey(c.Header, c.ApiKey), nil
+	case AuthHmac:
+		if len(c.KeyId) == 0 || len(c.Secret) == 0 {
+			return nil, errors.New("auth.key_id and auth.secret are required for hmac auth")
+		}
+		return Hmac(c.KeyId, []byte(c.Secret)), nil
+	}
+	return nil, errors.New("auth.type " + strconv.Quote(c.Type) + " must be basic, bearer, api_key or hmac")
+}
+
+func Basic(username string, password string) Middleware {
+	value := "Basic " + BasicAuth(username, password)
+	return authorize(func(req *http.Request) error {
+		req.Header.Set("Authorization", value)
+		return nil
+	})
+}
+func Bearer(source TokenSource) Middleware {
+	return authorize(func(req *http.Request) error {
+		token, err := source.Token(req.Context())
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// ApiKey sets the key in the header, X-API-Key by default.
+func ApiKey(header string, key string) Middleware {
+	if len(header) == 0 {
+		header = HeaderApiKey
+	}
+	return authorize(func(req *http.Request) error {
+		req.Header.Set(header, key)
+		return nil
+	})
+}
+
+// Hmac signs the request with the key, as Signing does.
+func Hmac(keyId string, secret []byte) Middleware {
+	return Signing(&Signer{KeyId: keyId, Secret: secret})
+}
+
+// Canonical is the signed form of a request, as defined by signature.Canonical.
+func Canonical(method string, uri string, timestamp string, nonce string, body []byte) string {
+	return signature.Canonical(method, uri, timestamp, nonce, body)
+}
+func Sign(secret []byte, canonical string) string {
+	return signature.Sign(secret, canonical)
+}
+
+// readBody returns the body of the request and leaves it readable, through GetBody when the request has one.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
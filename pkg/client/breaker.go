@@ -0,0 +1,251 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open"
+)
+
+var ErrCircuitOpen = errors.New("circuit open")
+
+type BreakerConfig struct {
+	Enabled             bool          `yaml:"enabled" mapstructure:"enabled" json:"enabled,omitempty" gorm:"column:enabled" bson:"enabled,omitempty" dynamodbav:"enabled,omitempty" firestore:"enabled,omitempty"`
+	FailureRate         float64       `yaml:"failure_rate" mapstructure:"failure_rate" json:"failureRate,omitempty" gorm:"column:failurerate" bson:"failureRate,omitempty" dynamodbav:"failureRate,omitempty" firestore:"failureRate,omitempty"`
+	MinRequests         int           `yaml:"min_requests" mapstructure:"min_requests" json:"minRequests,omitempty" gorm:"column:minrequests" bson:"minRequests,omitempty" dynamodbav:"minRequests,omitempty" firestore:"minRequests,omitempty"`
+	Window              time.Duration `yaml:"window" mapstructure:"window" json:"window,omitempty" gorm:"column:window" bson:"window,omitempty" dynamodbav:"window,omitempty" firestore:"window,omitempty"`
+	ConsecutiveFailures int           `yaml:"consecutive_failures" mapstructure:"consecutive_failures" json:"consecutiveFailures,omitempty" gorm:"column:consecutivefailures" bson:"consecutiveFailures,omitempty" dynamodbav:"consecutiveFailures,omitempty" firestore:"consecutiveFailures,omitempty"`
+	Cooldown            time.Duration `yaml:"cooldown" mapstructure:"cooldown" json:"cooldown,omitempty" gorm:"column:cooldown" bson:"cooldown,omitempty" dynamodbav:"cooldown,omitempty" firestore:"cooldown,omitempty"`
+	Probes              int           `yaml:"probes" mapstructure:"probes" json:"probes,omitempty" gorm:"column:probes" bson:"probes,omitempty" dynamodbav:"probes,omitempty" firestore:"probes,omitempty"`
+	Statuses            string        `yaml:"statuses" mapstructure:"statuses" json:"statuses,omitempty" gorm:"column:statuses" bson:"statuses,omitempty" dynamodbav:"statuses,omitempty" firestore:"statuses,omitempty"`
+}
+
+var observeCircuit func(host string, from string, to string)
+
+// SetCircuitObserver sets the function called on every state change of a circuit. It is called under the lock of the breaker, so it must not block.
+func SetCircuitObserver(f func(host string, from string, to string)) {
+	observeCircuit = f
+}
+
+// Breaker is a RoundTripper keeping one circuit per host. A closed circuit opens after ConsecutiveFailures failures in a row,
+// or when the failure rate of the current Window reaches FailureRate over at least MinRequests requests.
+// An open circuit fails fast until Cooldown has elapsed, then lets Probes requests through: it closes when all of them succeed,
+// and opens again on the first failure. Transport errors and the Statuses responses are failures.
+type Breaker struct {
+	Base                http.RoundTripper
+	FailureRate         float64
+	MinRequests         int
+	Window              time.Duration
+	ConsecutiveFailures int
+	Cooldown            time.Duration
+	Probes              int
+	Statuses            map[int]bool
+	mu                  sync.Mutex
+	circuits            map[string]*circuit
+}
+
+type circuit struct {
+	state       string
+	generation  int
+	start       time.Time
+	requests    int
+	failures    int
+	consecutive int
+	opened      time.Time
+	probes      int
+	successes   int
+}
+
+func NewBreaker(base http.RoundTripper, c BreakerConfig) (*Breaker, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	b := &Breaker{Base: base, FailureRate: c.FailureRate, MinRequests: c.MinRequests, Window: c.Window, ConsecutiveFailures: c.ConsecutiveFailures, Cooldown: c.Cooldown, Probes: c.Probes, circuits: make(map[string]*circuit)}
+	if b.FailureRate < 0 || b.FailureRate > 1 {
+		return nil, errors.New("breaker.failure_rate must be between 0 and 1")
+	}
+	if b.FailureRate == 0 {
+		b.FailureRate = 0.5
+	}
+	if b.MinRequests <= 0 {
+		b.MinRequests = 10
+	}
+	if b.Window <= 0 {
+		b.Window = 10 * time.Second
+	}
+	if b.ConsecutiveFailures <= 0 {
+		b.ConsecutiveFailures = 5
+	}
+	if b.Cooldown <= 0 {
+		b.Cooldown = 5 * time.Second
+	}
+	if b.Probes <= 0 {
+		b.Probes = 1
+	}
+	statuses, err := ParseStatuses(c.Statuses, "500,502,503,504")
+	if err != nil {
+		return nil, errors.New("breaker.statuses: " + err.Error())
+	}
+	b.Statuses = statuses
+	return b, nil
+}
+
+// WithBreaker returns a copy of the client failing fast while the circuit of the host is open.
+// The breaker wraps the retries, so a call counts once whatever its number of attempts, and the timeout of the client applies inside it.
+func WithBreaker(client *http.Client, c BreakerConfig) (*http.Client, *Breaker, error) {
+	base := client.Transport
+	if _, ok := Find[*TimeoutTransport](client); !ok {
+		base = NewTimeoutTransport(client.Transport, client.Timeout)
+	}
+	b, err := NewBreaker(base, c)
+	if err != nil {
+		return nil, nil, err
+	}
+	broken := *client
+	broken.Transport = b
+	broken.Timeout = 0
+	return &broken, b, nil
+}
+
+func (b *Breaker) Unwrap() http.RoundTripper {
+	return b.Base
+}
+func (b *Breaker) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	done, err := b.allow(host)
+	if err != nil {
+		return nil, err
+	}
+	res, err := b.Base.RoundTrip(req)
+	if err != nil {
+		if errors.Is(req.Context().Err(), context.Canceled) {
+			done(false, true)
+		} else {
+			done(true, false)
+		}
+		return res, err
+	}
+	done(b.Statuses[res.StatusCode], false)
+	return res, nil
+}
+
+// allow returns the function recording the outcome of the request, or a circuit open error.
+// An outcome recorded after the circuit has changed state is ignored.
+func (b *Breaker) allow(host string) (func(failed bool, ignored bool), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	c := b.circuit(host, now)
+	if c.state == StateOpen {
+		if now.Sub(c.opened) < b.Cooldown {
+			return nil, fmt.Errorf("%w for %s", ErrCircuitOpen, host)
+		}
+		b.change(host, c, StateHalfOpen, now)
+	}
+	probe := c.state == StateHalfOpen
+	if probe {
+		if c.probes+c.successes >= b.Probes {
+			return nil, fmt.Errorf("%w for %s, waiting for the probes", ErrCircuitOpen, host)
+		}
+		c.probes++
+	} else if now.Sub(c.start) >= b.Window {
+		c.start, c.requests, c.failures = now, 0, 0
+	}
+	generation := c.generation
+	return func(failed bool, ignored bool) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if c.generation != generation {
+			return
+		}
+		now := time.Now()
+		if probe {
+			c.probes--
+			switch {
+			case ignored:
+			case failed:
+				b.change(host, c, StateOpen, now)
+			default:
+				c.successes++
+				if c.successes >= b.Probes {
+					b.change(host, c, StateClosed, now)
+				}
+			}
+			return
+		}
+		if ignored {
+			return
+		}
+		c.requests++
+		if failed {
+			c.failures++
+			c.consecutive++
+		} else {
+			c.consecutive = 0
+		}
+		if c.consecutive >= b.ConsecutiveFailures || (c.requests >= b.MinRequests && float64(c.failures)/float64(c.requests) >= b.FailureRate) {
+			b.change(host, c, StateOpen, now)
+		}
+	}, nil
+}
+
+func (b *Breaker) circuit(host string, now time.Time) *circuit {
+	c, ok := b.circuits[host]
+	if !ok {
+		c = &circuit{state: StateClosed, start: now}
+		b.circuits[host] = c
+	}
+	return c
+}
+
+func (b *Breaker) change(host string, c *circuit, state string, now time.Time) {
+	from := c.state
+	c.state = state
+	c.generation++
+	c.start, c.requests, c.failures, c.consecutive = now, 0, 0, 0
+	c.probes, c.successes = 0, 0
+	if state == StateOpen {
+		c.opened = now
+	}
+	if observeCircuit != nil {
+		observeCircuit(host, from, state)
+	}
+}
+
+// State returns the state of the circuit of the host. An open circuit whose cooldown has elapsed is reported as half open.
+func (b *Breaker) State(host string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[host]
+	if !ok {
+		return StateClosed
+	}
+	return b.state(c, time.Now())
+}
+
+// States returns the state of the circuit of every host called so far.
+func (b *Breaker) States() map[string]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	states := make(map[string]string, len(b.circuits))
+	for host, c := range b.circuits {
+		states[host] = b.state(c, now)
+	}
+	return states
+}
+
+func (b *Breaker) state(c *circuit, now time.Time) string {
+	if c.state == StateOpen && now.Sub(c.opened) >= b.Cooldown {
+		return StateHalfOpen
+	}
+	return c.state
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// outcomes returns a transport answering with the status set by status, or a network error when it is 0, and counting the calls.
+func outcomes(status *int, calls *int) RoundTripperFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		*calls++
+		if *status == 0 {
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{StatusCode: *status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+	}
+}
+
+func call(b *Breaker, url string) error {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	res, err := b.RoundTrip(req)
+	if res != nil {
+		res.Body.Close()
+	}
+	return err
+}
+
+func TestBreakerOpensOnConsecutiveFailures(t *testing.T) {
+	status, calls := 503, 0
+	var changes []string
+	SetCircuitObserver(func(host string, from string, to string) {
+		changes = append(changes, host+":"+from+">"+to)
+	})
+	defer SetCircuitObserver(nil)
+	b, err := NewBreaker(outcomes(&status, &calls), BreakerConfig{ConsecutiveFailures: 3, Cooldown: 50 * time.Millisecond, Probes: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		call(b, "http://users.internal/users/ironman")
+	}
+	if err := call(b, "http://users.internal/users/ironman"); !errors.Is(err, ErrCircuitOpen) || calls != 3 {
+		t.Fatalf("error %v after %d calls, want the circuit open without calling", err, calls)
+	}
+	if b.State("users.internal") != StateOpen || b.State("other.internal") != StateClosed {
+		t.Errorf("states = %v", b.States())
+	}
+	if err := call(b, "http://other.internal/users/ironman"); errors.Is(err, ErrCircuitOpen) {
+		t.Error("the circuits of the hosts must be separate")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if b.State("users.internal") != StateHalfOpen {
+		t.Errorf("state after the cooldown = %s, want half open", b.State("users.internal"))
+	}
+	status = 200
+	for i := 0; i < 2; i++ {
+		if err := call(b, "http://users.internal/users/ironman"); err != nil {
+			t.Fatalf("probe %d: %v", i, err)
+		}
+	}
+	if b.State("users.internal") != StateClosed {
+		t.Errorf("state after the probes = %s, want closed", b.State("users.internal"))
+	}
+	want := []string{"users.internal:closed>open", "users.internal:open>half_open", "users.internal:half_open>closed"}
+	if strings.Join(changes, ",") != strings.Join(want, ",") {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+}
+
+func TestBreakerReopensOnFailedProbe(t *testing.T) {
+	status, calls := 0, 0
+	b, _ := NewBreaker(outcomes(&status, &calls), BreakerConfig{ConsecutiveFailures: 1, Cooldown: 20 * time.Millisecond})
+	call(b, "http://users.internal/")
+	time.Sleep(30 * time.Millisecond)
+	if err := call(b, "http://users.internal/"); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("probe: %v, want the error of the transport", err)
+	}
+	if err := call(b, "http://users.internal/"); !errors.Is(err, ErrCircuitOpen) || b.State("users.internal") != StateOpen {
+		t.Errorf("after a failed probe: %v, state %s", err, b.State("users.internal"))
+	}
+}
+
+func TestBreakerOpensOnFailureRate(t *testing.T) {
+	status, calls := 200, 0
+	b, _ := NewBreaker(outcomes(&status, &calls), BreakerConfig{FailureRate: 0.5, MinRequests: 4, ConsecutiveFailures: 10, Window: time.Minute})
+	for _, s := range []int{200, 500, 200, 500} {
+		status = s
+		call(b, "http://users.internal/")
+	}
+	if b.State("users.internal") != StateOpen {
+		t.Errorf("state = %s, want open at half of 4 requests failed", b.State("users.internal"))
+	}
+	status = 404
+	b, _ = NewBreaker(outcomes(&status, &calls), BreakerConfig{MinRequests: 1, ConsecutiveFailures: 1})
+	call(b, "http://users.internal/")
+	if b.State("users.internal") != StateClosed {
+		t.Error("a status which is not in Statuses is not a failure")
+	}
+}
+
+func TestBreakerIgnoresCanceledRequests(t *testing.T) {
+	b, _ := NewBreaker(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, req.Context().Err()
+	}), BreakerConfig{ConsecutiveFailures: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://users.internal/", nil)
+	b.RoundTrip(req)
+	if b.State("users.internal") != StateClosed {
+		t.Error("a request canceled by the caller is not a failure of the host")
+	}
+}
+
+func TestNewBreaker(t *testing.T) {
+	b, err := NewBreaker(nil, BreakerConfig{})
+	if err != nil || b.FailureRate != 0.5 || b.MinRequests != 10 || b.ConsecutiveFailures != 5 || b.Probes != 1 || !b.Statuses[503] {
+		t.Errorf("NewBreaker() = %+v, %v", b, err)
+	}
+	if _, err := NewBreaker(nil, BreakerConfig{FailureRate: 1.5}); err == nil {
+		t.Error("a failure rate over 1 must be rejected")
+	}
+}
@@ -7,17 +7,24 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
-	"net/http/httputil"
-	"strings"
+	neturl "net/url"
 	"time"
+
+	"github.com/core-go/core"
+
+	"go-service/pkg/requestid"
+	"go-service/pkg/tlsconfig"
+	"go-service/pkg/tracing"
 )
 
 type ClientConfig struct {
 	Endpoint Config     `yaml:"endpoint" mapstructure:"endpoint" json:"endpoint,omitempty" gorm:"column:endpoint" bson:"endpoint,omitempty" dynamodbav:"endpoint,omitempty" firestore:"endpoint,omitempty"`
+	Health   string     `yaml:"health" mapstructure:"health" json:"health,omitempty" gorm:"column:health" bson:"health,omitempty" dynamodbav:"health,omitempty" firestore:"health,omitempty"`
 	Log      *LogConfig `yaml:"log" mapstructure:"log" json:"log,omitempty" gorm:"column:log" bson:"log,omitempty" dynamodbav:"log,omitempty" firestore:"log,omitempty"`
 }
 type ClientConf struct {
@@ -29,23 +36,41 @@ type Endpoint struct {
 	Url      string  `yaml:"url" mapstructure:"url" json:"url,omitempty" gorm:"column:url" bson:"url,omitempty" dynamodbav:"url,omitempty" firestore:"url,omitempty"`
 	Username *string `yaml:"username" mapstructure:"username" json:"username,omitempty" gorm:"column:username" bson:"username,omitempty" dynamodbav:"username,omitempty" firestore:"username,omitempty"`
 	Password *string `yaml:"password" mapstructure:"password" json:"password,omitempty" gorm:"column:password" bson:"password,omitempty" dynamodbav:"password,omitempty" firestore:"password,omitempty"`
+	ApiKey   *string `yaml:"api_key" mapstructure:"api_key" json:"apiKey,omitempty" gorm:"column:apikey" bson:"apiKey,omitempty" dynamodbav:"apiKey,omitempty" firestore:"apiKey,omitempty"`
 }
 type Config struct {
-	Insecure *bool          `yaml:"insecure" mapstructure:"insecure" json:"insecure,omitempty" gorm:"column:insecure" bson:"insecure,omitempty" dynamodbav:"insecure,omitempty" firestore:"insecure,omitempty"`
-	Timeout  *time.Duration `yaml:"timeout" mapstructure:"timeout" json:"timeout,omitempty" gorm:"column:timeout" bson:"timeout,omitempty" dynamodbav:"timeout,omitempty" firestore:"timeout,omitempty"`
-	CertFile string         `yaml:"cert_file" mapstructure:"cert_file" json:"certFile,omitempty" gorm:"column:certfile" bson:"certFile,omitempty" dynamodbav:"certFile,omitempty" firestore:"certFile,omitempty"`
-	KeyFile  string         `yaml:"key_file" mapstructure:"key_file" json:"keyFile,omitempty" gorm:"column:keyfile" bson:"keyFile,omitempty" dynamodbav:"keyFile,omitempty" firestore:"keyFile,omitempty"`
-	PEMFile  bool           `yaml:"pem_file" mapstructure:"pem_file" json:"pemFile,omitempty" gorm:"column:pemFile" bson:"pemFile,omitempty" dynamodbav:"pemFile,omitempty" firestore:"pemFile,omitempty"`
-	Url      string         `yaml:"url" mapstructure:"url" json:"url,omitempty" gorm:"column:url" bson:"url,omitempty" dynamodbav:"url,omitempty" firestore:"url,omitempty"`
-	Username *string        `yaml:"username" mapstructure:"username" json:"username,omitempty" gorm:"column:username" bson:"username,omitempty" dynamodbav:"username,omitempty" firestore:"username,omitempty"`
-	Password *string        `yaml:"password" mapstructure:"password" json:"password,omitempty" gorm:"column:password" bson:"password,omitempty" dynamodbav:"password,omitempty" firestore:"password,omitempty"`
+	Insecure    *bool             `yaml:"insecure" mapstructure:"insecure" json:"insecure,omitempty" gorm:"column:insecure" bson:"insecure,omitempty" dynamodbav:"insecure,omitempty" firestore:"insecure,omitempty"`
+	Timeout     *time.Duration    `yaml:"timeout" mapstructure:"timeout" json:"timeout,omitempty" gorm:"column:timeout" bson:"timeout,omitempty" dynamodbav:"timeout,omitempty" firestore:"timeout,omitempty"`
+	CertFile    string            `yaml:"cert_file" mapstructure:"cert_file" json:"certFile,omitempty" gorm:"column:certfile" bson:"certFile,omitempty" dynamodbav:"certFile,omitempty" firestore:"certFile,omitempty"`
+	KeyFile     string            `yaml:"key_file" mapstructure:"key_file" json:"keyFile,omitempty" gorm:"column:keyfile" bson:"keyFile,omitempty" dynamodbav:"keyFile,omitempty" firestore:"keyFile,omitempty"`
+	PEMFile     bool              `yaml:"pem_file" mapstructure:"pem_file" json:"pemFile,omitempty" gorm:"column:pemFile" bson:"pemFile,omitempty" dynamodbav:"pemFile,omitempty" firestore:"pemFile,omitempty"`
+	Url         string            `yaml:"url" mapstructure:"url" json:"url,omitempty" gorm:"column:url" bson:"url,omitempty" dynamodbav:"url,omitempty" firestore:"url,omitempty"`
+	Username    *string           `yaml:"username" mapstructure:"username" json:"username,omitempty" gorm:"column:username" bson:"username,omitempty" dynamodbav:"username,omitempty" firestore:"username,omitempty"`
+	Password    *string           `yaml:"password" mapstructure:"password" json:"password,omitempty" gorm:"column:password" bson:"password,omitempty" dynamodbav:"password,omitempty" firestore:"password,omitempty"`
+	ApiKey      *string           `yaml:"api_key" mapstructure:"api_key" json:"apiKey,omitempty" gorm:"column:apikey" bson:"apiKey,omitempty" dynamodbav:"apiKey,omitempty" firestore:"apiKey,omitempty"`
+	Retry       *RetryConfig      `yaml:"retry" mapstructure:"retry" json:"retry,omitempty" gorm:"column:retry" bson:"retry,omitempty" dynamodbav:"retry,omitempty" firestore:"retry,omitempty"`
+	Breaker     *BreakerConfig    `yaml:"breaker" mapstructure:"breaker" json:"breaker,omitempty" gorm:"column:breaker" bson:"breaker,omitempty" dynamodbav:"breaker,omitempty" firestore:"breaker,omitempty"`
+	OAuth2      *OAuth2Config     `yaml:"oauth2" mapstructure:"oauth2" json:"oauth2,omitempty" gorm:"column:oauth2" bson:"oauth2,omitempty" dynamodbav:"oauth2,omitempty" firestore:"oauth2,omitempty"`
+	Auth        *AuthConfig       `yaml:"auth" mapstructure:"auth" json:"auth,omitempty" gorm:"column:auth" bson:"auth,omitempty" dynamodbav:"auth,omitempty" firestore:"auth,omitempty"`
+	Middleware  *MiddlewareConfig `yaml:"middleware" mapstructure:"middleware" json:"middleware,omitempty" gorm:"column:middleware" bson:"middleware,omitempty" dynamodbav:"middleware,omitempty" firestore:"middleware,omitempty"`
+	TLS         *tlsconfig.Config `yaml:"tls" mapstructure:"tls" json:"tls,omitempty" gorm:"column:tls" bson:"tls,omitempty" dynamodbav:"tls,omitempty" firestore:"tls,omitempty"`
+	MaxBodySize int64             `yaml:"max_body_size" mapstructure:"max_body_size" json:"maxBodySize,omitempty" gorm:"column:maxbodysize" bson:"maxBodySize,omitempty" dynamodbav:"maxBodySize,omitempty" firestore:"maxBodySize,omitempty"`
+	Signing     *SigningConfig    `yaml:"signing" mapstructure:"signing" json:"signing,omitempty" gorm:"column:signing" bson:"signing,omitempty" dynamodbav:"signing,omitempty" firestore:"signing,omitempty"`
 }
 type Conf struct {
-	Insecure *bool          `yaml:"insecure" mapstructure:"insecure" json:"insecure,omitempty" gorm:"column:insecure" bson:"insecure,omitempty" dynamodbav:"insecure,omitempty" firestore:"insecure,omitempty"`
-	Timeout  *time.Duration `yaml:"timeout" mapstructure:"timeout" json:"timeout,omitempty" gorm:"column:timeout" bson:"timeout,omitempty" dynamodbav:"timeout,omitempty" firestore:"timeout,omitempty"`
-	CertFile string         `yaml:"cert_file" mapstructure:"cert_file" json:"certFile,omitempty" gorm:"column:certfile" bson:"certFile,omitempty" dynamodbav:"certFile,omitempty" firestore:"certFile,omitempty"`
-	KeyFile  string         `yaml:"key_file" mapstructure:"key_file" json:"keyFile,omitempty" gorm:"column:keyfile" bson:"keyFile,omitempty" dynamodbav:"keyFile,omitempty" firestore:"keyFile,omitempty"`
-	PEMFile  bool           `yaml:"pem_file" mapstructure:"pem_file" json:"pemFile,omitempty" gorm:"column:pemFile" bson:"pemFile,omitempty" dynamodbav:"pemFile,omitempty" firestore:"pemFile,omitempty"`
+	Insecure    *bool             `yaml:"insecure" mapstructure:"insecure" json:"insecure,omitempty" gorm:"column:insecure" bson:"insecure,omitempty" dynamodbav:"insecure,omitempty" firestore:"insecure,omitempty"`
+	Timeout     *time.Duration    `yaml:"timeout" mapstructure:"timeout" json:"timeout,omitempty" gorm:"column:timeout" bson:"timeout,omitempty" dynamodbav:"timeout,omitempty" firestore:"timeout,omitempty"`
+	CertFile    string            `yaml:"cert_file" mapstructure:"cert_file" json:"certFile,omitempty" gorm:"column:certfile" bson:"certFile,omitempty" dynamodbav:"certFile,omitempty" firestore:"certFile,omitempty"`
+	KeyFile     string            `yaml:"key_file" mapstructure:"key_file" json:"keyFile,omitempty" gorm:"column:keyfile" bson:"keyFile,omitempty" dynamodbav:"keyFile,omitempty" firestore:"keyFile,omitempty"`
+	PEMFile     bool              `yaml:"pem_file" mapstructure:"pem_file" json:"pemFile,omitempty" gorm:"column:pemFile" bson:"pemFile,omitempty" dynamodbav:"pemFile,omitempty" firestore:"pemFile,omitempty"`
+	Retry       *RetryConfig      `yaml:"retry" mapstructure:"retry" json:"retry,omitempty" gorm:"column:retry" bson:"retry,omitempty" dynamodbav:"retry,omitempty" firestore:"retry,omitempty"`
+	Breaker     *BreakerConfig    `yaml:"breaker" mapstructure:"breaker" json:"breaker,omitempty" gorm:"column:breaker" bson:"breaker,omitempty" dynamodbav:"breaker,omitempty" firestore:"breaker,omitempty"`
+	OAuth2      *OAuth2Config     `yaml:"oauth2" mapstructure:"oauth2" json:"oauth2,omitempty" gorm:"column:oauth2" bson:"oauth2,omitempty" dynamodbav:"oauth2,omitempty" firestore:"oauth2,omitempty"`
+	Auth        *AuthConfig       `yaml:"auth" mapstructure:"auth" json:"auth,omitempty" gorm:"column:auth" bson:"auth,omitempty" dynamodbav:"auth,omitempty" firestore:"auth,omitempty"`
+	Middleware  *MiddlewareConfig `yaml:"middleware" mapstructure:"middleware" json:"middleware,omitempty" gorm:"column:middleware" bson:"middleware,omitempty" dynamodbav:"middleware,omitempty" firestore:"middleware,omitempty"`
+	TLS         *tlsconfig.Config `yaml:"tls" mapstructure:"tls" json:"tls,omitempty" gorm:"column:tls" bson:"tls,omitempty" dynamodbav:"tls,omitempty" firestore:"tls,omitempty"`
+	MaxBodySize int64             `yaml:"max_body_size" mapstructure:"max_body_size" json:"maxBodySize,omitempty" gorm:"column:maxbodysize" bson:"maxBodySize,omitempty" dynamodbav:"maxBodySize,omitempty" firestore:"maxBodySize,omitempty"`
+	Signing     *SigningConfig    `yaml:"signing" mapstructure:"signing" json:"signing,omitempty" gorm:"column:signing" bson:"signing,omitempty" dynamodbav:"signing,omitempty" firestore:"signing,omitempty"`
 }
 type LogConfig struct {
 	Separate       bool   `yaml:"separate" mapstructure:"separate" json:"separate,omitempty" gorm:"column:separate" bson:"separate,omitempty" dynamodbav:"separate,omitempty" firestore:"separate,omitempty"`
@@ -56,6 +81,7 @@ type LogConfig struct {
 	Request        string `yaml:"request" mapstructure:"request" json:"request,omitempty" gorm:"column:request" bson:"request,omitempty" dynamodbav:"request,omitempty" firestore:"request,omitempty"`
 	Response       string `yaml:"response" mapstructure:"response" json:"response,omitempty" gorm:"column:response" bson:"response,omitempty" dynamodbav:"response,omitempty" firestore:"response,omitempty"`
 	Error          string `yaml:"error" mapstructure:"error" json:"error,omitempty" gorm:"column:error" bson:"error,omitempty" dynamodbav:"error,omitempty" firestore:"error,omitempty"`
+	Sample         int    `yaml:"sample" mapstructure:"sample" json:"sample,omitempty" gorm:"column:sample" bson:"sample,omitempty" dynamodbav:"sample,omitempty" firestore:"sample,omitempty"`
 }
 type Params struct {
 	Client   *http.Client
@@ -64,6 +90,8 @@ type Params struct {
 	Config   *LogConfig
 	LogError func(context.Context, string, map[string]interface{})
 	LogInfo  func(context.Context, string, map[string]interface{})
+	// Signer signs as Client does, for the requests sent otherwise, such as webhooks. It is nil when the endpoint has no signing.
+	Signer *Signer
 }
 
 const (
@@ -74,12 +102,22 @@ const (
 	delete = "DELETE"
 )
 
+const HeaderApiKey = "X-API-Key"
+
 // var conf3 LogConfig
 var sClient *http.Client
+var observe func(method string, host string, status int, duration time.Duration)
+var maskBody func(string) string
 
 func SetClient(c *http.Client) {
 	sClient = c
 }
+func SetObserver(f func(method string, host string, status int, duration time.Duration)) {
+	observe = f
+}
+func SetMask(f func(string) string) {
+	maskBody = f
+}
 func InitializeLog(c *LogConfig) *LogConfig {
 	var c2 LogConfig
 	if c == nil {
@@ -106,6 +144,7 @@ func InitializeLog(c *LogConfig) *LogConfig {
 	}
 	c2.Request = c.Request
 	c2.Response = c.Response
+	c2.Sample = c.Sample
 	return &c2
 }
 func InitializeParams(config ClientConfig, opts ...func(context.Context, string, map[string]interface{})) (*Params, error) {
@@ -121,7 +160,7 @@ func InitializeParams(config ClientConfig, opts ...func(context.Context, string,
 	if len(opts) > 1 && opts[1] != nil {
 		logInfo = opts[1]
 	}
-	return &Params{Client: c, Url: config.Endpoint.Url, Header: header, Config: conf, LogError: logError, LogInfo: logInfo}, nil
+	return newParams(c, config.Endpoint.Url, header, conf, config.Endpoint.Signing, logError, logInfo)
 }
 func InitParams(config ClientConf, opts ...func(context.Context, string, map[string]interface{})) (*Params, error) {
 	c, header, conf, err := InitClient(config)
@@ -136,25 +175,40 @@ func InitParams(config ClientConf, opts ...func(context.Context, string, map[str
 	if len(opts) > 1 && opts[1] != nil {
 		logInfo = opts[1]
 	}
-	return &Params{Client: c, Url: config.Endpoint.Url, Header: header, Config: conf, LogError: logError, LogInfo: logInfo}, nil
+	return newParams(c, config.Endpoint.Url, header, conf, config.Config.Signing, logError, logInfo)
+}
+func newParams(c *http.Client, url string, header map[string]string, conf *LogConfig, signing *SigningConfig, logError func(context.Context, string, map[string]interface{}), logInfo func(context.Context, string, map[string]interface{})) (*Params, error) {
+	p := &Params{Client: c, Url: url, Header: header, Config: conf, LogError: logError, LogInfo: logInfo}
+	if signing != nil && len(signing.KeyId) > 0 {
+		signer, err := NewSigner(*signing)
+		if err != nil {
+			return nil, err
+		}
+		p.Signer = signer
+	}
+	return p, nil
 }
 func InitializeClient(config ClientConfig) (*http.Client, map[string]string, *LogConfig, error) {
 	e := config.Endpoint
 	conf := Conf{
-		Insecure: e.Insecure,
-		Timeout:  e.Timeout,
-		CertFile: e.CertFile,
-		KeyFile:  e.KeyFile,
-		PEMFile:  e.PEMFile,
-	}
-	c, err := NewClient(conf)
-	if err != nil {
-		return nil, nil, nil, err
-	}
-	header := CreateHeaderFromConfig(config.Endpoint)
-	l := InitializeLog(config.Log)
-	return c, header, l, nil
+		Insecure:    e.Insecure,
+		Timeout:     e.Timeout,
+		CertFile:    e.CertFile,
+		KeyFile:     e.KeyFile,
+		PEMFile:     e.PEMFile,
+		Retry:       e.Retry,
+		Breaker:     e.Breaker,
+		Auth:        e.Auth,
+		OAuth2:      e.OAuth2,
+		Middleware:  e.Middleware,
+		TLS:         e.TLS,
+		MaxBodySize: e.MaxBodySize,
+		Signing:     e.Signing,
+	}
+	return InitClient(ClientConf{Config: conf, Log: config.Log, Endpoint: Endpoint{Url: e.Url, Username: e.Username, Password: e.Password, ApiKey: e.ApiKey}})
 }
+
+// InitClient builds the client with the middlewares of the config, around a transport applying the timeout of the config.
 func InitClient(config ClientConf) (*http.Client, map[string]string, *LogConfig, error) {
 	c, err := NewClient(config.Config)
 	if err != nil {
@@ -162,11 +216,20 @@ func InitClient(config ClientConf) (*http.Client, map[string]string, *LogConfig,
 	}
 	header := CreateHeaderFromConf(config.Endpoint)
 	l := InitializeLog(config.Log)
-	return c, header, l, nil
+	ms, err := Middlewares(config.Config, l)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	c.Transport = NewTimeoutTransport(c.Transport, c.Timeout)
+	c.Timeout = 0
+	return Use(c, ms...), header, l, nil
 }
 func NewClient(c Conf) (*http.Client, error) {
+	if c.TLS != nil {
+		return NewTLSConfigClient(*c.TLS, c.Timeout)
+	}
 	if len(c.CertFile) > 0 && len(c.KeyFile) > 0 {
-		return NewTLSClient(c.CertFile, c.KeyFile, c.Timeout)
+		return NewTLSConfigClient(tlsconfig.Config{CertFile: c.CertFile, KeyFile: c.KeyFile, Insecure: c.Insecure != nil && *c.Insecure}, c.Timeout)
 	} else {
 		if c.Insecure != nil {
 			if c.Timeout != nil {
@@ -190,27 +253,40 @@ func NewClient(c Conf) (*http.Client, error) {
 		}
 	}
 }
+
+// NewTLSClient presents the certificate, and verifies the server against the CA file of options, or else the system roots.
 func NewTLSClient(certFile, keyFile string, timeout *time.Duration, options ...string) (*http.Client, error) {
-	clientCert, er1 := tls.LoadX509KeyPair(certFile, keyFile)
-	if er1 != nil {
-		return nil, er1
+	c := tlsconfig.Config{CertFile: certFile, KeyFile: keyFile}
+	if len(options) > 0 && len(options[0]) > 0 {
+		c.CAFiles = []string{options[0]}
 	}
-	conf, er2 := GetTLSClientConfig(clientCert, options...)
-	if er2 != nil {
-		return nil, er2
+	return NewTLSConfigClient(c, timeout)
+}
+
+// NewTLSConfigClient returns a client verifying the server with the CA files, the pins and the minimum version of the config.
+// The certificate and the CA files are loaded again when they change on disk.
+func NewTLSConfigClient(c tlsconfig.Config, timeout *time.Duration) (*http.Client, error) {
+	store, err := tlsconfig.NewStore(c, func(ctx context.Context, msg string, fields ...map[string]interface{}) {
+		if defaultLogError != nil {
+			var m map[string]interface{}
+			if len(fields) > 0 {
+				m = fields[0]
+			}
+			defaultLogError(ctx, msg, m)
+		}
+	})
+	if err != nil {
+		return nil, err
 	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialTLSContext = store.DialTLSContext
+	// used through a proxy only, which DialTLSContext does not support
+	transport.TLSClientConfig = store.ClientConfig()
+	client0 := &http.Client{Transport: transport}
 	if timeout != nil {
-		client0 := &http.Client{Transport: &http.Transport{TLSClientConfig: conf}}
-		// sClient = client0
-		return client0, nil
-	} else {
-		client0 := &http.Client{
-			Transport: &http.Transport{TLSClientConfig: conf},
-			Timeout:   *timeout,
-		}
-		// sClient = client0
-		return client0, nil
+		client0.Timeout = *timeout
 	}
+	return client0, nil
 }
 func BasicAuth(username, password string) string {
 	auth := username + ":" + password
@@ -221,7 +297,15 @@ func CreateHeader(username, password string) map[string]string {
 	h["Authorization"] = "Basic " + BasicAuth(username, password)
 	return h
 }
+func CreateApiKeyHeader(key string) map[string]string {
+	h := make(map[string]string, 0)
+	h[HeaderApiKey] = key
+	return h
+}
 func CreateHeaderFromConf(c Endpoint) map[string]string {
+	if c.ApiKey != nil && len(*c.ApiKey) > 0 {
+		return CreateApiKeyHeader(*c.ApiKey)
+	}
 	if c.Username == nil || c.Password == nil || len(*c.Username) == 0 {
 		return nil
 	}
@@ -230,6 +314,9 @@ func CreateHeaderFromConf(c Endpoint) map[string]string {
 	return h
 }
 func CreateHeaderFromConfig(c Config) map[string]string {
+	if c.ApiKey != nil && len(*c.ApiKey) > 0 {
+		return CreateApiKeyHeader(*c.ApiKey)
+	}
 	if c.Username == nil || c.Password == nil || len(*c.Username) == 0 {
 		return nil
 	}
@@ -237,12 +324,13 @@ func CreateHeaderFromConfig(c Config) map[string]string {
 	h["Authorization"] = "Basic " + BasicAuth(*c.Username, *c.Password)
 	return h
 }
+
+// GetTLSClientConfig presents the certificate, and verifies the server against the CA file of options, or else the system roots.
+// Unlike NewTLSConfigClient, the files are not loaded again when they change.
 func GetTLSClientConfig(clientCert tls.Certificate, options ...string) (*tls.Config, error) {
 	c := &tls.Config{
-		InsecureSkipVerify: true,
-		Certificates:       []tls.Certificate{clientCert},
-		MinVersion:         tls.VersionTLS10,
-		MaxVersion:         tls.VersionTLS13,
+		Certificates: []tls.Certificate{clientCert},
+		MinVersion:   tls.VersionTLS12,
 	}
 	if len(options) > 0 && len(options[0]) > 0 {
 		pem, err := ioutil.ReadFile(options[0])
@@ -257,20 +345,15 @@ func GetTLSClientConfig(clientCert tls.Certificate, options ...string) (*tls.Con
 }
 
 func DoJSON(ctx context.Context, client *http.Client, method string, url string, body []byte, headers map[string]string) (*http.Response, error) {
+	var rq io.Reader
 	if body != nil {
-		b := body
-		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(b))
-		if err != nil {
-			return nil, err
-		}
-		return AddHeaderAndDoJSON(client, req, headers)
-	} else {
-		req, err := http.NewRequestWithContext(ctx, method, url, nil)
-		if err != nil {
-			return nil, err
-		}
-		return AddHeaderAndDoJSON(client, req, headers)
+		rq = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, rq)
+	if err != nil {
+		return nil, err
 	}
+	return AddHeaderAndDoJSON(client, req, headers)
 }
 func DoJSONWithClient(ctx context.Context, client *http.Client, method string, url string, obj interface{}, headers map[string]string, errorStatus int) (*json.Decoder, error) {
 	if client == nil {
@@ -306,23 +389,33 @@ func DoJSONAndDecode(ctx context.Context, client *http.Client, method string, ur
 	return res, nil
 }
 func AddHeaderAndDoJSON(client *http.Client, req *http.Request, headers map[string]string) (*http.Response, error) {
-	if headers != nil {
-		for k, v := range headers {
-			req.Header.Add(k, v)
-		}
-	}
 	req.Header.Add("Content-Type", "application/json")
-	resp, err := client.Do(req)
-	return resp, err
+	return AddHeaderAndDo(client, req, headers)
 }
+
+// AddHeaderAndDo sends the request through the middlewares of the client. A client without the Tracing, RequestId or Metrics
+// middleware, such as a plain *http.Client, still propagates the trace and the request id, and is observed.
 func AddHeaderAndDo(client *http.Client, req *http.Request, headers map[string]string) (*http.Response, error) {
-	if headers != nil {
-		for k, v := range headers {
-			req.Header.Add(k, v)
-		}
+	for k, v := range headers {
+		req.Header.Add(k, v)
+	}
+	if !Has[*tracing.Transport](client) {
+		tracing.Inject(req.Context(), req.Header)
+	}
+	if !Has[*requestid.Transport](client) {
+		requestid.Inject(req.Context(), req.Header)
+	}
+	if observe == nil || Has[*MetricsTransport](client) {
+		return client.Do(req)
+	}
+	start := time.Now()
+	res, err := client.Do(req)
+	status := 0
+	if err == nil {
+		status = res.StatusCode
 	}
-	resp, err := client.Do(req)
-	return resp, err
+	observe(req.Method, req.URL.Host, status, time.Since(start))
+	return res, err
 }
 func DoGet(ctx context.Context, client *http.Client, url string, headers map[string]string) (*http.Response, error) {
 	return DoJSON(ctx, client, get, url, nil, headers)
@@ -349,12 +442,7 @@ func Get(ctx context.Context, client *http.Client, url string, result interface{
 	return GetWithHeader(ctx, client, url, nil, result, conf, options...)
 }
 func GetWithHeader(ctx context.Context, client *http.Client, url string, headers map[string]string, result interface{}, conf *LogConfig, options ...func(context.Context, string, map[string]interface{})) error {
-	decoder, er1 := DoWithClient(ctx, client, get, url, nil, headers, conf, options...)
-	if er1 != nil {
-		return er1
-	}
-	er2 := decoder.Decode(result)
-	return er2
+	return decodeWithClient(ctx, client, get, url, nil, headers, result, conf, options...)
 }
 func DeleteDecoder(ctx context.Context, client *http.Client, url string, conf *LogConfig, options ...func(context.Context, string, map[string]interface{})) (*json.Decoder, error) {
 	return DoWithClient(ctx, client, delete, url, nil, nil, conf, options...)
@@ -366,12 +454,7 @@ func Delete(ctx context.Context, client *http.Client, url string, result interfa
 	return DeleteWithHeader(ctx, client, url, nil, result, conf, options...)
 }
 func DeleteWithHeader(ctx context.Context, client *http.Client, url string, headers map[string]string, result interface{}, conf *LogConfig, options ...func(context.Context, string, map[string]interface{})) error {
-	decoder, er1 := DoWithClient(ctx, client, delete, url, nil, headers, conf, options...)
-	if er1 != nil {
-		return er1
-	}
-	er2 := decoder.Decode(result)
-	return er2
+	return decodeWithClient(ctx, client, delete, url, nil, headers, result, conf, options...)
 }
 func PostDecoder(ctx context.Context, client *http.Client, url string, obj interface{}, conf *LogConfig, options ...func(context.Context, string, map[string]interface{})) (*json.Decoder, error) {
 	return DoWithClient(ctx, client, post, url, obj, nil, conf, options...)
@@ -383,12 +466,7 @@ func Post(ctx context.Context, client *http.Client, url string, obj interface{},
 	return PostWithHeader(ctx, client, url, obj, nil, result, conf, options...)
 }
 func PostWithHeader(ctx context.Context, client *http.Client, url string, obj interface{}, headers map[string]string, result interface{}, conf *LogConfig, options ...func(context.Context, string, map[string]interface{})) error {
-	decoder, er1 := DoWithClient(ctx, client, post, url, obj, headers, conf, options...)
-	if er1 != nil {
-		return er1
-	}
-	er2 := decoder.Decode(result)
-	return er2
+	return decodeWithClient(ctx, client, post, url, obj, headers, result, conf, options...)
 }
 func PutDecoder(ctx context.Context, client *http.Client, url string, obj interface{}, conf *LogConfig, options ...func(context.Context, string, map[string]interface{})) (*json.Decoder, error) {
 	return DoWithClient(ctx, client, put, url, obj, nil, conf, options...)
@@ -400,12 +478,7 @@ func Put(ctx context.Context, client *http.Client, url string, obj interface{},
 	return PutWithHeader(ctx, client, url, obj, nil, result, conf, options...)
 }
 func PutWithHeader(ctx context.Context, client *http.Client, url string, obj interface{}, headers map[string]string, result interface{}, conf *LogConfig, options ...func(context.Context, string, map[string]interface{})) error {
-	decoder, er1 := DoWithClient(ctx, client, put, url, obj, headers, conf, options...)
-	if er1 != nil {
-		return er1
-	}
-	er2 := decoder.Decode(result)
-	return er2
+	return decodeWithClient(ctx, client, put, url, obj, headers, result, conf, options...)
 }
 func PatchDecoder(ctx context.Context, client *http.Client, url string, obj interface{}, conf *LogConfig, options ...func(context.Context, string, map[string]interface{})) (*json.Decoder, error) {
 	return DoWithClient(ctx, client, patch, url, obj, nil, conf, options...)
@@ -417,12 +490,7 @@ func Patch(ctx context.Context, client *http.Client, url string, obj interface{}
 	return PatchWithHeader(ctx, client, url, obj, nil, result, conf, options...)
 }
 func PatchWithHeader(ctx context.Context, client *http.Client, url string, obj interface{}, headers map[string]string, result interface{}, conf *LogConfig, options ...func(context.Context, string, map[string]interface{})) error {
-	decoder, er1 := DoWithClient(ctx, client, patch, url, obj, headers, conf, options...)
-	if er1 != nil {
-		return er1
-	}
-	er2 := decoder.Decode(result)
-	return er2
+	return decodeWithClient(ctx, client, patch, url, obj, headers, result, conf, options...)
 }
 func Marshal(obj interface{}) ([]byte, error) {
 	b, ok := obj.([]byte)
@@ -457,183 +525,82 @@ func DoWithClient(ctx context.Context, client *http.Client, method string, url s
 	}
 	return DoAndBuildDecoder(ctx, client, method, url, rq, headers, conf, options...)
 }
+
+// decodeWithClient is DoWithClient decoding the response into result as it is read.
+func decodeWithClient(ctx context.Context, client *http.Client, method string, url string, obj interface{}, headers map[string]string, result interface{}, conf *LogConfig, options ...func(context.Context, string, map[string]interface{})) error {
+	if client == nil {
+		client = sClient
+	}
+	rq, err := Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return DoAndDecode(ctx, client, method, url, rq, headers, result, conf, options...)
+}
+
+// DoAndBuildDecoder sends the request through the middlewares of the client, then shapes the failures as *HttpError:
+// transport errors with status 0, and responses from 400 with their body. The body is buffered for the decoder:
+// DoAndDecode and DoStream decode large responses as they are read.
 func DoAndBuildDecoder(ctx context.Context, client *http.Client, method string, url string, body []byte, headers map[string]string, conf *LogConfig, options ...func(context.Context, string, map[string]interface{})) (*json.Decoder, error) {
-	var logError func(context.Context, string, map[string]interface{})
-	var logInfo func(context.Context, string, map[string]interface{})
-	if len(options) > 0 {
-		logError = options[0]
+	res, dur, er1 := do(ctx, client, method, url, body, headers, conf, options...)
+	if er1 != nil {
+		return nil, er1
 	}
-	if len(options) > 1 {
-		logInfo = options[1]
+	rs, er2 := io.ReadAll(res.Body)
+	res.Body.Close()
+	if er2 != nil {
+		return nil, bodyError(res.StatusCode, er2, dur, url, body)
 	}
+	return json.NewDecoder(bytes.NewReader(rs)), nil
+}
+
+// do returns the response of a status below 400, whose body is left to the caller, and the duration until its headers.
+// The body of an error response is read up to 1 MB, or up to the limit of the client.
+func do(ctx context.Context, client *http.Client, method string, url string, body []byte, headers map[string]string, conf *LogConfig, options ...func(context.Context, string, map[string]interface{})) (*http.Response, int64, error) {
 	start := time.Now()
-	res, er1 := DoJSON(ctx, client, method, url, body, headers)
-	end := time.Now()
-	dur := end.Sub(start).Milliseconds()
-	if logError != nil && (er1 != nil || res.StatusCode >= 400) {
-		fs3 := make(map[string]interface{}, 0)
-		var c2 LogConfig
-		if conf != nil {
-			c2 = *conf
-		} else {
-			c2.Duration = "duration"
-			c2.Request = "request"
-			c2.Response = "response"
-			c2.ResponseStatus = "status"
-			c2.Error = "error"
-		}
-		if body != nil {
-			rq := string(body)
-			if len(rq) > 0 {
-				fs3[c2.Request] = rq
-			}
-		}
-		if er1 != nil {
-			if len(c2.Error) > 0 {
-				fs3[c2.Error] = er1.Error()
-			}
-			logError(ctx, method+" "+url, fs3)
-			return nil, er1
-		}
-		if len(c2.ResponseStatus) > 0 {
-			fs3[c2.ResponseStatus] = res.StatusCode
-		}
-		if len(c2.Size) > 0 && res.ContentLength > 0 {
-			fs3[c2.Size] = res.ContentLength
-		}
-		if len(c2.Response) > 0 {
-			dump, er3 := httputil.DumpResponse(res, true)
-			if er3 != nil {
-				if len(c2.Error) > 0 {
-					fs3[c2.Error] = er3.Error()
-				}
-				logError(ctx, method+" "+url, fs3)
-				return nil, er3
-			}
-			s := string(dump)
-			if len(c2.Size) > 0 {
-				fs3[c2.Size] = len(s)
-			}
-			if len(c2.Response) > 0 {
-				fs3[c2.Response] = s
-			}
-			if res.StatusCode == 503 {
-				logError(ctx, method+" "+url, fs3)
-				var rq string
-				if body != nil {
-					rq = string(body)
-				}
-				er2 := NewHttpError(http.StatusServiceUnavailable, er1, dur, "503 Service Unavailable", url, rq, s)
-				return nil, er2
-			}
-			logError(ctx, method+" "+url, fs3)
-			return json.NewDecoder(strings.NewReader(s)), nil
-		} else {
-			if res.StatusCode == 503 {
-				logError(ctx, method+" "+url, fs3)
-				var rq string
-				if body != nil {
-					rq = string(body)
-				}
-				er2 := NewHttpError(http.StatusServiceUnavailable, er1, dur, "503 Service Unavailable", url, rq)
-				return nil, er2
-			}
-			logError(ctx, method+" "+url, fs3)
-			return json.NewDecoder(res.Body), nil
-		}
+	res, er1 := DoJSON(ctx, withLog(client, conf, options...), method, url, body, headers)
+	dur := time.Since(start).Milliseconds()
+	var rq string
+	if body != nil {
+		rq = string(body)
 	}
-	if conf != nil && conf.Log == true && logInfo != nil {
-		canRequest := false
-		if method != "GET" && method != "DELETE" && method != "OPTIONS" {
-			canRequest = true
-		}
-		if conf.Separate && len(conf.Request) > 0 && body != nil && canRequest {
-			fs1 := make(map[string]interface{}, 0)
-			rq := string(body)
-			if len(rq) > 0 {
-				fs1[conf.Request] = rq
-			}
-			logInfo(ctx, method+" "+url, fs1)
-		}
-		fs3 := make(map[string]interface{}, 0)
-		fs3[conf.Duration] = dur
-		if !conf.Separate && len(conf.Request) > 0 && body != nil && canRequest {
-			rq := string(body)
-			if len(rq) > 0 {
-				fs3[conf.Request] = rq
-			}
-		}
-		if er1 != nil {
-			if len(conf.Error) > 0 {
-				fs3[conf.Error] = er1.Error()
-			}
-			logInfo(ctx, method+" "+url, fs3)
-			return nil, er1
-		}
-		if len(conf.ResponseStatus) > 0 {
-			fs3[conf.ResponseStatus] = res.StatusCode
-		}
-		if len(conf.Size) > 0 && res.ContentLength > 0 {
-			fs3[conf.Size] = res.ContentLength
-		}
-		if len(conf.Response) > 0 {
-			buf := new(bytes.Buffer)
-			_, er3 := buf.ReadFrom(res.Body)
-			if er3 != nil {
-				if len(conf.Error) > 0 {
-					fs3[conf.Error] = er3.Error()
-				}
-				logInfo(ctx, method+" "+url, fs3)
-				return nil, er3
-			}
-			s := buf.String()
-			if len(conf.Size) > 0 {
-				fs3[conf.Size] = len(s)
-			}
-			if len(conf.Response) > 0 {
-				fs3[conf.Response] = s
-			}
-			if res.StatusCode == 503 {
-				logInfo(ctx, method+" "+url, fs3)
-				var rq string
-				if body != nil {
-					rq = string(body)
-				}
-				er2 := NewHttpError(http.StatusServiceUnavailable, er1, dur, "503 Service Unavailable", url, rq)
-				return nil, er2
-			}
-			logInfo(ctx, method+" "+url, fs3)
-			return json.NewDecoder(strings.NewReader(s)), nil
-		} else {
-			if res.StatusCode == 503 {
-				logInfo(ctx, method+" "+url, fs3)
-				var rq string
-				if body != nil {
-					rq = string(body)
-				}
-				er2 := NewHttpError(http.StatusServiceUnavailable, er1, dur, "503 Service Unavailable", url, rq)
-				return nil, er2
-			}
-			logInfo(ctx, method+" "+url, fs3)
-			return json.NewDecoder(res.Body), nil
-		}
-	} else {
-		if er1 != nil {
-			return nil, er1
-		}
-		if res.StatusCode == 503 {
-			var rq string
-			if body != nil {
-				rq = string(body)
-			}
-			er2 := NewHttpError(http.StatusServiceUnavailable, er1, dur, "503 Service Unavailable", url, rq)
-			return nil, er2
+	if er1 != nil {
+		errorType := ErrorTypeNetwork
+		if errors.Is(er1, ErrCircuitOpen) {
+			errorType = ErrorTypeCircuitOpen
 		}
-		return json.NewDecoder(res.Body), nil
+		return nil, dur, NewHttpError(0, er1, dur, er1.Error(), url, rq, "", errorType)
+	}
+	if res.StatusCode < http.StatusBadRequest {
+		return res, dur, nil
+	}
+	rs, er2 := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+	res.Body.Close()
+	var tooLarge *BodyTooLargeError
+	if er2 != nil && !errors.As(er2, &tooLarge) {
+		return nil, dur, bodyError(res.StatusCode, er2, dur, url, body)
+	}
+	return nil, dur, NewResponseError(res.StatusCode, dur, url, rq, rs)
+}
+
+// bodyError is the error of reading a body, of type body_too_large when it exceeds the limit of the client.
+func bodyError(statusCode int, err error, duration int64, url string, body []byte) error {
+	errorType := ErrorTypeNetwork
+	var tooLarge *BodyTooLargeError
+	if errors.As(err, &tooLarge) {
+		errorType = ErrorTypeBodyTooLarge
 	}
+	return NewHttpError(statusCode, err, duration, err.Error(), url, string(body), "", errorType)
 }
 
+// DoAndLog is DoAndBuildDecoder returning the response, whose body is left to the caller.
 func DoAndLog(ctx context.Context, client *http.Client, method string, url string, body []byte, headers map[string]string, conf *LogConfig, options ...func(context.Context, string, map[string]interface{})) (*http.Response, error) {
+	res, _, err := do(ctx, client, method, url, body, headers, conf, options...)
+	return res, err
+}
+
+// withLog adds the Logging middleware of the options, unless the client already logs.
+func withLog(client *http.Client, conf *LogConfig, options ...func(context.Context, string, map[string]interface{})) *http.Client {
 	var logError func(context.Context, string, map[string]interface{})
 	var logInfo func(context.Context, string, map[string]interface{})
 	if len(options) > 0 {
@@ -642,170 +609,10 @@ func DoAndLog(ctx context.Context, client *http.Client, method string, url strin
 	if len(options) > 1 {
 		logInfo = options[1]
 	}
-	start := time.Now()
-	res, er1 := DoJSON(ctx, client, method, url, body, headers)
-	end := time.Now()
-	dur := end.Sub(start).Milliseconds()
-	if logError != nil && (er1 != nil || res.StatusCode >= 400) {
-		fs3 := make(map[string]interface{}, 0)
-		var c2 LogConfig
-		if conf != nil {
-			c2 = *conf
-		} else {
-			c2.Duration = "duration"
-			c2.Request = "request"
-			c2.Response = "response"
-			c2.ResponseStatus = "status"
-			c2.Error = "error"
-		}
-		if body != nil {
-			rq := string(body)
-			if len(rq) > 0 {
-				fs3[c2.Request] = rq
-			}
-		}
-		if er1 != nil {
-			if len(c2.Error) > 0 {
-				fs3[c2.Error] = er1.Error()
-			}
-			logError(ctx, method+" "+url, fs3)
-			return res, er1
-		}
-		if len(c2.ResponseStatus) > 0 {
-			fs3[c2.ResponseStatus] = res.StatusCode
-		}
-		if len(c2.Size) > 0 && res.ContentLength > 0 {
-			fs3[c2.Size] = res.ContentLength
-		}
-		if len(c2.Response) > 0 {
-			dump, er3 := httputil.DumpResponse(res, true)
-			if er3 != nil {
-				if len(c2.Error) > 0 {
-					fs3[c2.Error] = er3.Error()
-				}
-				logError(ctx, method+" "+url, fs3)
-				return res, er3
-			}
-			s := string(dump)
-			if len(c2.Size) > 0 {
-				fs3[c2.Size] = len(s)
-			}
-			if len(c2.Response) > 0 {
-				fs3[c2.Response] = s
-			}
-			if res.StatusCode == 503 {
-				logError(ctx, method+" "+url, fs3)
-				var rq string
-				if body != nil {
-					rq = string(body)
-				}
-				er2 := NewHttpError(http.StatusServiceUnavailable, er1, dur, "503 Service Unavailable", url, rq, s)
-				return res, er2
-			}
-			logError(ctx, method+" "+url, fs3)
-			return res, nil
-		} else {
-			if res.StatusCode == 503 {
-				logError(ctx, method+" "+url, fs3)
-				var rq string
-				if body != nil {
-					rq = string(body)
-				}
-				er2 := NewHttpError(http.StatusServiceUnavailable, er1, dur, "503 Service Unavailable", url, rq)
-				return res, er2
-			}
-			logError(ctx, method+" "+url, fs3)
-			return res, nil
-		}
-	}
-	if conf != nil && conf.Log == true && logInfo != nil {
-		canRequest := false
-		if method != "GET" && method != "DELETE" && method != "OPTIONS" {
-			canRequest = true
-		}
-		if conf.Separate && len(conf.Request) > 0 && body != nil && canRequest {
-			fs1 := make(map[string]interface{}, 0)
-			rq := string(body)
-			if len(rq) > 0 {
-				fs1[conf.Request] = rq
-			}
-			logInfo(ctx, method+" "+url, fs1)
-		}
-		fs3 := make(map[string]interface{}, 0)
-		fs3[conf.Duration] = dur
-		if !conf.Separate && len(conf.Request) > 0 && body != nil && canRequest {
-			rq := string(body)
-			if len(rq) > 0 {
-				fs3[conf.Request] = rq
-			}
-		}
-		if er1 != nil {
-			if len(conf.Error) > 0 {
-				fs3[conf.Error] = er1.Error()
-			}
-			logInfo(ctx, method+" "+url, fs3)
-			return res, er1
-		}
-		if len(conf.ResponseStatus) > 0 {
-			fs3[conf.ResponseStatus] = res.StatusCode
-		}
-		if len(conf.Size) > 0 && res.ContentLength > 0 {
-			fs3[conf.Size] = res.ContentLength
-		}
-		if len(conf.Response) > 0 {
-			dump, er3 := httputil.DumpResponse(res, true)
-			if er3 != nil {
-				if len(conf.Error) > 0 {
-					fs3[conf.Error] = er3.Error()
-				}
-				logInfo(ctx, method+" "+url, fs3)
-				return res, er3
-			}
-			s := string(dump)
-			if len(conf.Size) > 0 {
-				fs3[conf.Size] = len(s)
-			}
-			if len(conf.Response) > 0 {
-				fs3[conf.Response] = s
-			}
-			if res.StatusCode == 503 {
-				logInfo(ctx, method+" "+url, fs3)
-				var rq string
-				if body != nil {
-					rq = string(body)
-				}
-				er2 := NewHttpError(http.StatusServiceUnavailable, er1, dur, "503 Service Unavailable", url, rq, s)
-				return res, er2
-			}
-			logInfo(ctx, method+" "+url, fs3)
-			return res, nil
-		} else {
-			if res.StatusCode == 503 {
-				logInfo(ctx, method+" "+url, fs3)
-				var rq string
-				if body != nil {
-					rq = string(body)
-				}
-				er2 := NewHttpError(http.StatusServiceUnavailable, er1, dur, "503 Service Unavailable", url, rq)
-				return res, er2
-			}
-			logInfo(ctx, method+" "+url, fs3)
-			return res, nil
-		}
-	} else {
-		if er1 != nil {
-			return nil, er1
-		}
-		if res.StatusCode == 503 {
-			var rq string
-			if body != nil {
-				rq = string(body)
-			}
-			er2 := NewHttpError(http.StatusServiceUnavailable, er1, dur, "503 Service Unavailable", url, rq)
-			return res, er2
-		}
-		return res, nil
+	if (logError == nil && logInfo == nil) || Has[*LogTransport](client) {
+		return client
 	}
+	return Use(client, Logging(conf, logError, logInfo))
 }
 
 type HttpError struct {
@@ -820,6 +627,32 @@ type HttpError struct {
 	ErrorCode    string
 	Service      string
 	Severity     string
+	Errors       []core.ErrorMessage
+}
+
+const (
+	ErrorTypeNetwork      = "network"
+	ErrorTypeResponse     = "response"
+	ErrorTypeValidation   = "validation"
+	ErrorTypeCircuitOpen  = "circuit_open"
+	ErrorTypeBodyTooLarge = "body_too_large"
+
+	maxErrorBody = 1 << 20
+)
+
+// NewResponseError builds the error of a response with status 400 or above. The errors of a 422 response are decoded,
+// when its body is an array such as [{"field":"email","code":"email"}].
+func NewResponseError(statusCode int, duration int64, url string, request string, response []byte) *HttpError {
+	err := &HttpError{StatusCode: statusCode, Duration: duration, Url: url, Request: request, Response: string(response), ErrorType: ErrorTypeResponse}
+	err.ErrorMessage = fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode))
+	if statusCode == http.StatusUnprocessableEntity {
+		var messages []core.ErrorMessage
+		if json.Unmarshal(response, &messages) == nil && len(messages) > 0 {
+			err.Errors = messages
+			err.ErrorType = ErrorTypeValidation
+		}
+	}
+	return err
 }
 
 func NewHttpError(statusCode int, rootError error, duration int64, opts ...string) error {
@@ -858,6 +691,9 @@ func (e *HttpError) Error() string {
 	}
 	return e.GetRootError()
 }
+func (e *HttpError) Unwrap() error {
+	return e.RootError
+}
 func (e *HttpError) GetRootError() string {
 	if e.RootError != nil {
 		return e.RootError.Error()
@@ -865,43 +701,72 @@ func (e *HttpError) GetRootError() string {
 	return ""
 }
 func IsHttpError(err error) (*HttpError, bool) {
-	httpErr, ok := err.(*HttpError)
-	if ok {
-		return httpErr, ok
-	} else {
-		return nil, ok
+	var httpErr *HttpError
+	if errors.As(err, &httpErr) {
+		return httpErr, true
 	}
+	return nil, false
+}
+
+// IsStatus reports whether err is an HttpError of a response with the given status.
+func IsStatus(err error, statusCode int) bool {
+	httpErr, ok := IsHttpError(err)
+	return ok && httpErr.StatusCode == statusCode
 }
 func MakeMap(err *HttpError, prefix string) map[string]interface{} {
 	mp := make(map[string]interface{})
-	mp[prefix + "Duration"] = err.Duration
-	mp[prefix + "Status"] = err.StatusCode
+	mp[prefix+"Duration"] = err.Duration
+	mp[prefix+"Status"] = err.StatusCode
 	if len(err.Request) > 0 {
-		mp[prefix + "Request"] = err.Request
+		mp[prefix+"Request"] = err.Request
 	}
 	if len(err.Response) > 0 {
-		mp[prefix + "Response"] = err.Response
+		mp[prefix+"Response"] = err.Response
 	}
 	if len(err.Url) > 0 {
-		mp[prefix + "Url"] = err.Url
+		mp[prefix+"Url"] = err.Url
 	}
 	if len(err.ErrorMessage) > 0 {
-		mp[prefix + "Error"] = err.ErrorMessage
+		mp[prefix+"Error"] = err.ErrorMessage
 	}
 	if err.RootError != nil && err.Error() != err.ErrorMessage {
-		mp[prefix + "RootError"] = err.Error()
+		mp[prefix+"RootError"] = err.Error()
 	}
 	if len(err.ErrorType) > 0 {
-		mp[prefix + "ErrorType"] = err.ErrorType
+		mp[prefix+"ErrorType"] = err.ErrorType
 	}
 	if len(err.ErrorCode) > 0 {
-		mp[prefix + "ErrorCode"] = err.ErrorCode
+		mp[prefix+"ErrorCode"] = err.ErrorCode
 	}
 	if len(err.Service) > 0 {
-		mp[prefix + "Service"] = err.Service
+		mp[prefix+"Service"] = err.Service
 	}
 	if len(err.Severity) > 0 {
-		mp[prefix + "Severity"] = err.Severity
+		mp[prefix+"Severity"] = err.Severity
 	}
 	return mp
 }
+func host(s string) string {
+	u, err := neturl.Parse(s)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+func maskLog(log func(context.Context, string, map[string]interface{}), conf *LogConfig) func(context.Context, string, map[string]interface{}) {
+	if log == nil || maskBody == nil {
+		return log
+	}
+	keys := []string{"request", "response"}
+	if conf != nil {
+		keys = []string{conf.Request, conf.Response}
+	}
+	return func(ctx context.Context, msg string, fields map[string]interface{}) {
+		for _, key := range keys {
+			if v, ok := fields[key].(string); ok && len(key) > 0 {
+				fields[key] = maskBody(v)
+			}
+		}
+		log(ctx, msg, fields)
+	}
+}
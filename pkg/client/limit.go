@@ -0,0 +1,69 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// BodyTooLargeError is the error of reading a response body beyond the limit of the client.
+type BodyTooLargeError struct {
+	Limit int64
+	Url   string
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("the response body of %s exceeds %d bytes", e.Url, e.Limit)
+}
+
+// LimitTransport fails the reads of a response body beyond Limit bytes with a *BodyTooLargeError.
+// A body whose Content-Length is above the limit fails on the first read. Outside the decompression, the limit applies to the decompressed body.
+type LimitTransport struct {
+	Base  http.RoundTripper
+	Limit int64
+}
+
+func Limit(n int64) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &LimitTransport{Base: next, Limit: n}
+	}
+}
+func (t *LimitTransport) Unwrap() http.RoundTripper {
+	return t.Base
+}
+func (t *LimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.Base.RoundTrip(req)
+	if err != nil || t.Limit <= 0 {
+		return res, err
+	}
+	res.Body = &limitBody{body: res.Body, remaining: t.Limit, exceeded: res.ContentLength > t.Limit, err: &BodyTooLargeError{Limit: t.Limit, Url: req.URL.Redacted()}}
+	return res, nil
+}
+
+type limitBody struct {
+	body      io.ReadCloser
+	remaining int64
+	exceeded  bool
+	err       *BodyTooLargeError
+}
+
+func (b *limitBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, b.err
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.body.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = 0
+		b.exceeded = true
+		return n, b.err
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+func (b *limitBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,272 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var defaultLogError func(context.Context, string, map[string]interface{})
+var defaultLogInfo func(context.Context, string, map[string]interface{})
+
+// SetLog sets the log functions of the Logging middleware built from the config, which has no log functions of its own.
+func SetLog(logError func(context.Context, string, map[string]interface{}), logInfo func(context.Context, string, map[string]interface{})) {
+	defaultLogError, defaultLogInfo = logError, logInfo
+}
+
+// DefaultSample is the number of bytes logged of a body, when LogConfig.Sample is not set.
+const DefaultSample = 4096
+
+// LogTransport logs transport errors and responses from 400 with LogError, and the other responses with LogInfo when Config.Log is set.
+// The request and response bodies are masked by the function set by SetMask. Of the response body, the first Sample bytes are copied
+// as the caller reads it, and the log is written when the body is read to its end or closed, with the size read.
+type LogTransport struct {
+	Base     http.RoundTripper
+	Config   *LogConfig
+	LogError func(context.Context, string, map[string]interface{})
+	LogInfo  func(context.Context, string, map[string]interface{})
+}
+
+// Logging returns the log middleware. Nil log functions fall back to the ones set by SetLog.
+func Logging(conf *LogConfig, logError func(context.Context, string, map[string]interface{}), logInfo func(context.Context, string, map[string]interface{})) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &LogTransport{Base: next, Config: conf, LogError: logError, LogInfo: logInfo}
+	}
+}
+func (t *LogTransport) Unwrap() http.RoundTripper {
+	return t.Base
+}
+func (t *LogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	logError, logInfo := t.LogError, t.LogInfo
+	if logError == nil {
+		logError = defaultLogError
+	}
+	if logInfo == nil {
+		logInfo = defaultLogInfo
+	}
+	logError, logInfo = maskLog(logError, t.Config), maskLog(logInfo, t.Config)
+	if logError == nil && logInfo == nil {
+		return t.Base.RoundTrip(req)
+	}
+	var c LogConfig
+	if t.Config != nil {
+		c = *t.Config
+	} else {
+		c.Duration = "duration"
+		c.Request = "request"
+		c.Response = "response"
+		c.ResponseStatus = "status"
+		c.Error = "error"
+	}
+	sample := c.Sample
+	if sample <= 0 {
+		sample = DefaultSample
+	}
+	var rq string
+	if len(c.Request) > 0 {
+		body, err := readBody(req)
+		if err != nil {
+			closeBody(req)
+			return nil, err
+		}
+		if len(body) > sample {
+			rq = cutJSON(body[:sample])
+		} else {
+			rq = string(body)
+		}
+	}
+	ctx := req.Context()
+	msg := req.Method + " " + req.URL.String()
+	start := time.Now()
+	res, err := t.Base.RoundTrip(req)
+	dur := time.Since(start).Milliseconds()
+	if err != nil {
+		if logError != nil {
+			fs := make(map[string]interface{}, 0)
+			if len(c.Duration) > 0 {
+				fs[c.Duration] = dur
+			}
+			if len(rq) > 0 && len(c.Request) > 0 {
+				fs[c.Request] = rq
+			}
+			if len(c.Error) > 0 {
+				fs[c.Error] = err.Error()
+			}
+			logError(ctx, msg, fs)
+		}
+		return res, err
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		if logError != nil {
+			fs := make(map[string]interface{}, 0)
+			if len(c.Duration) > 0 {
+				fs[c.Duration] = dur
+			}
+			if len(rq) > 0 && len(c.Request) > 0 {
+				fs[c.Request] = rq
+			}
+			if len(c.ResponseStatus) > 0 {
+				fs[c.ResponseStatus] = res.StatusCode
+			}
+			logResponse(ctx, msg, res, c, sample, fs, logError)
+		}
+		return res, nil
+	}
+	if t.Config == nil || !t.Config.Log || logInfo == nil {
+		return res, nil
+	}
+	canRequest := req.Method != http.MethodGet && req.Method != http.MethodDelete && req.Method != http.MethodOptions
+	if c.Separate && len(c.Request) > 0 && len(rq) > 0 && canRequest {
+		logInfo(ctx, msg, map[string]interface{}{c.Request: rq})
+	}
+	fs := make(map[string]interface{}, 0)
+	if len(c.Duration) > 0 {
+		fs[c.Duration] = dur
+	}
+	if !c.Separate && len(c.Request) > 0 && len(rq) > 0 && canRequest {
+		fs[c.Request] = rq
+	}
+	if len(c.ResponseStatus) > 0 {
+		fs[c.ResponseStatus] = res.StatusCode
+	}
+	logResponse(ctx, msg, res, c, sample, fs, logInfo)
+	return res, nil
+}
+
+// logResponse writes the log when the body of the response is read to its end or closed, with its size and its first bytes,
+// and the error that ended the reading, such as a *BodyTooLargeError.
+func logResponse(ctx context.Context, msg string, res *http.Response, c LogConfig, sample int, fs map[string]interface{}, log func(context.Context, string, map[string]interface{})) {
+	if len(c.Size) == 0 && len(c.Response) == 0 {
+		log(ctx, msg, fs)
+		return
+	}
+	res.Body = &sampleBody{ReadCloser: res.Body, limit: sample, done: func(rs []byte, size int64, err error) {
+		if err != nil && len(c.Error) > 0 {
+			fs[c.Error] = err.Error()
+		}
+		if len(c.Size) > 0 {
+			fs[c.Size] = size
+		}
+		if len(c.Response) > 0 && len(rs) > 0 {
+			if size > int64(len(rs)) {
+				fs[c.Response] = cutJSON(rs)
+			} else {
+				fs[c.Response] = string(rs)
+			}
+		}
+		log(ctx, msg, fs)
+	}}
+}
+
+// sampleBody copies the first bytes of the body as it is read, and calls done once, at the end of the body or when it is closed.
+type sampleBody struct {
+	io.ReadCloser
+	limit  int
+	sample []byte
+	size   int64
+	err    error
+	once   sync.Once
+	done   func(sample []byte, size int64, err error)
+}
+
+func (b *sampleBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if room := b.limit - len(b.sample); room > 0 {
+		b.sample = append(b.sample, p[:min(n, room)]...)
+	}
+	if err != nil {
+		if err != io.EOF {
+			b.err = err
+		}
+		b.finish()
+	}
+	return n, err
+}
+func (b *sampleBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish()
+	return err
+}
+func (b *sampleBody) finish() {
+	b.once.Do(func() {
+		b.done(b.sample, b.size, b.err)
+	})
+}
+
+// cutJSON ends a JSON document cut short after its last complete token, with null for a key left without value,
+// and closes its objects and arrays, so that it can still be masked. Anything that is not JSON is returned as is.
+func cutJSON(b []byte) string {
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return string(b)
+	}
+	type level struct {
+		delim json.Delim
+		n     int
+		key   bool
+	}
+	var stack []level
+	var buf bytes.Buffer
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.UseNumber()
+	for {
+		t, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		if d, ok := t.(json.Delim); ok && (d == '}' || d == ']') {
+			stack = stack[:len(stack)-1]
+			buf.WriteByte(byte(d))
+			if len(stack) == 0 {
+				return buf.String()
+			}
+			continue
+		}
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if top.delim == '{' && !top.key {
+				if top.n > 0 {
+					buf.WriteByte(',')
+				}
+				top.n++
+				top.key = true
+				writeToken(&buf, t)
+				buf.WriteByte(':')
+				continue
+			}
+			if top.delim == '[' {
+				if top.n > 0 {
+					buf.WriteByte(',')
+				}
+				top.n++
+			}
+			top.key = false
+		}
+		if d, ok := t.(json.Delim); ok {
+			buf.WriteByte(byte(d))
+			stack = append(stack, level{delim: d})
+			continue
+		}
+		writeToken(&buf, t)
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].delim == '{' {
+			if stack[i].key {
+				buf.WriteString("null")
+			}
+			buf.WriteByte('}')
+		} else {
+			buf.WriteByte(']')
+		}
+	}
+	return buf.String()
+}
+func writeToken(buf *bytes.Buffer, t json.Token) {
+	b, _ := json.Marshal(t)
+	buf.Write(b)
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCutJSON(t *testing.T) {
+	tests := map[string]string{
+		`{"id":"ironman","email":"tony@st`:         `{"id":"ironman","email":null}`,
+		`{"id":"ironman","tags":["a","b`:           `{"id":"ironman","tags":["a"]}`,
+		`[{"id":"a"},{"id":"b","phone":"0987`:      `[{"id":"a"},{"id":"b","phone":null}]`,
+		`{"list":[{"id":"a","age":12},{"id"`:       `{"list":[{"id":"a","age":12},{"id":null}]}`,
+		`{"total":1.50,"ok":true,"n":null,"x":{"y`: `{"total":1.50,"ok":true,"n":null,"x":{}}`,
+		`{"id":"a"} trailing`:                      `{"id":"a"}`,
+		`plain text, cut`:                          `plain text, cut`,
+		`  `:                                       `  `,
+	}
+	for in, want := range tests {
+		got := cutJSON([]byte(in))
+		if got != want {
+			t.Errorf("cutJSON(%q) = %q, want %q", in, got, want)
+		}
+		if in[0] == '{' || in[0] == '[' {
+			if !json.Valid([]byte(got)) {
+				t.Errorf("cutJSON(%q) = %q is not valid JSON", in, got)
+			}
+		}
+	}
+}
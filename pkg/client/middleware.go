@@ -0,0 +1,280 @@
+package client
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-service/pkg/requestid"
+	"go-service/pkg/tracing"
+)
+
+// Middleware wraps a RoundTripper, as the middlewares of the server wrap an http.Handler.
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type MiddlewareConfig struct {
+	Log        bool `yaml:"log" mapstructure:"log" json:"log,omitempty" gorm:"column:log" bson:"log,omitempty" dynamodbav:"log,omitempty" firestore:"log,omitempty"`
+	Tracing    bool `yaml:"tracing" mapstructure:"tracing" json:"tracing,omitempty" gorm:"column:tracing" bson:"tracing,omitempty" dynamodbav:"tracing,omitempty" firestore:"tracing,omitempty"`
+	Metrics    bool `yaml:"metrics" mapstructure:"metrics" json:"metrics,omitempty" gorm:"column:metrics" bson:"metrics,omitempty" dynamodbav:"metrics,omitempty" firestore:"metrics,omitempty"`
+	RequestId  bool `yaml:"request_id" mapstructure:"request_id" json:"requestId,omitempty" gorm:"column:requestid" bson:"requestId,omitempty" dynamodbav:"requestId,omitempty" firestore:"requestId,omitempty"`
+	Decompress bool `yaml:"decompress" mapstructure:"decompress" json:"decompress,omitempty" gorm:"column:decompress" bson:"decompress,omitempty" dynamodbav:"decompress,omitempty" firestore:"decompress,omitempty"`
+}
+
+// DefaultMiddleware is used when the config has no middleware section: what the helpers of this package have always done.
+var DefaultMiddleware = MiddlewareConfig{Tracing: true, Metrics: true, RequestId: true}
+
+// Chain wraps base with the middlewares, the first one being the outermost.
+func Chain(base http.RoundTripper, middlewares ...Middleware) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			base = middlewares[i](base)
+		}
+	}
+	return base
+}
+
+// Use returns a copy of the client whose transport is wrapped with the middlewares.
+func Use(client *http.Client, middlewares ...Middleware) *http.Client {
+	c := *client
+	c.Transport = Chain(client.Transport, middlewares...)
+	return &c
+}
+
+// Middlewares builds the chain of the config, from the outermost: tracing, metrics, log, request id, breaker, retry, auth or oauth2,
+// signing, body size limit, decompression.
+// The timeout of the client is applied by the caller, inside all of them, so with retries it applies to each attempt.
+func Middlewares(c Conf, conf *LogConfig) ([]Middleware, error) {
+	m := DefaultMiddleware
+	if c.Middleware != nil {
+		m = *c.Middleware
+	}
+	var ms []Middleware
+	if m.Tracing {
+		ms = append(ms, Tracing())
+	}
+	if m.Metrics {
+		ms = append(ms, Metrics())
+	}
+	if m.Log {
+		ms = append(ms, Logging(conf, nil, nil))
+	}
+	if m.RequestId {
+		ms = append(ms, RequestId())
+	}
+	if c.Breaker != nil && c.Breaker.Enabled {
+		b, err := NewBreaker(nil, *c.Breaker)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, func(next http.RoundTripper) http.RoundTripper {
+			b.Base = next
+			return b
+		})
+	}
+	if c.Retry != nil && c.Retry.MaxAttempts > 1 {
+		r, err := NewRetryTransport(nil, *c.Retry, conf)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, func(next http.RoundTripper) http.RoundTripper {
+			r.Base = next
+			return r
+		})
+	}
+	if c.OAuth2 != nil && len(c.OAuth2.TokenUrl) > 0 {
+		if c.Auth != nil {
+			return nil, errors.New("auth and oauth2 cannot be both set")
+		}
+		// the token endpoint is called with the TLS settings and the timeout of the endpoint, but none of its middlewares
+		tokenClient, err := NewClient(c)
+		if err != nil {
+			return nil, err
+		}
+		source, err := NewClientCredentials(*c.OAuth2, tokenClient)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, OAuth2(source))
+	} else if c.Auth != nil {
+		auth, err := Auth(*c.Auth)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, auth)
+	}
+	if c.Signing != nil && len(c.Signing.KeyId) > 0 {
+		if c.Auth != nil && c.Auth.Type == AuthHmac {
+			return nil, errors.New("auth of type hmac and signing cannot be both set")
+		}
+		signer, err := NewSigner(*c.Signing)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, Signing(signer))
+	}
+	if c.MaxBodySize > 0 {
+		ms = append(ms, Limit(c.MaxBodySize))
+	}
+	if m.Decompress {
+		ms = append(ms, Decompress())
+	}
+	return ms, nil
+}
+
+func Tracing() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return tracing.NewTransport(next)
+	}
+}
+func RequestId() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return requestid.NewTransport(next)
+	}
+}
+
+// HeaderTransport sets the headers on every request, unless the request already has them.
+type HeaderTransport struct {
+	Base    http.RoundTripper
+	Headers map[string]string
+}
+
+func Header(headers map[string]string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &HeaderTransport{Base: next, Headers: headers}
+	}
+}
+func (t *HeaderTransport) Unwrap() http.RoundTripper {
+	return t.Base
+}
+func (t *HeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req
+	for k, v := range t.Headers {
+		if len(req.Header.Get(k)) > 0 {
+			continue
+		}
+		if r == req {
+			r = req.Clone(req.Context())
+		}
+		r.Header.Set(k, v)
+	}
+	return t.Base.RoundTrip(r)
+}
+
+// MetricsTransport reports every call to the observer set by SetObserver, with status 0 for transport errors.
+type MetricsTransport struct {
+	Base http.RoundTripper
+}
+
+func Metrics() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &MetricsTransport{Base: next}
+	}
+}
+func (t *MetricsTransport) Unwrap() http.RoundTripper {
+	return t.Base
+}
+func (t *MetricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := t.Base.RoundTrip(req)
+	if observe != nil {
+		status := 0
+		if err == nil {
+			status = res.StatusCode
+		}
+		observe(req.Method, req.URL.Host, status, time.Since(start))
+	}
+	return res, err
+}
+
+// DecompressTransport asks for gzip or deflate and decompresses the response, when the caller has not set Accept-Encoding itself.
+type DecompressTransport struct {
+	Base http.RoundTripper
+}
+
+func Decompress() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &DecompressTransport{Base: next}
+	}
+}
+func (t *DecompressTransport) Unwrap() http.RoundTripper {
+	return t.Base
+}
+func (t *DecompressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(req.Header.Get("Accept-Encoding")) > 0 || req.Method == http.MethodHead {
+		return t.Base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	res, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+	var body io.ReadCloser
+	switch strings.ToLower(res.Header.Get("Content-Encoding")) {
+	case "gzip":
+		r, er1 := gzip.NewReader(res.Body)
+		if er1 != nil {
+			res.Body.Close()
+			return nil, er1
+		}
+		body = &decompressBody{Reader: r, body: res.Body}
+	case "deflate":
+		body = &decompressBody{Reader: flate.NewReader(res.Body), body: res.Body}
+	default:
+		return res, nil
+	}
+	res.Body = body
+	res.Header.Del("Content-Encoding")
+	res.Header.Del("Content-Length")
+	res.ContentLength = -1
+	res.Uncompressed = true
+	return res, nil
+}
+
+type decompressBody struct {
+	io.Reader
+	body io.ReadCloser
+}
+
+func (b *decompressBody) Close() error {
+	if c, ok := b.Reader.(io.Closer); ok {
+		c.Close()
+	}
+	return b.body.Close()
+}
+
+// Find returns the first transport of type T in the chain of the client, following the transports having an Unwrap method.
+func Find[T http.RoundTripper](client *http.Client) (T, bool) {
+	t := client.Transport
+	for t != nil {
+		if found, ok := t.(T); ok {
+			return found, true
+		}
+		u, ok := t.(interface{ Unwrap() http.RoundTripper })
+		if !ok {
+			break
+		}
+		t = u.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// Has reports whether the chain of the client has a transport of type T.
+func Has[T http.RoundTripper](client *http.Client) bool {
+	_, ok := Find[T](client)
+	return ok
+}
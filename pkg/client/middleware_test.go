@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-service/pkg/requestid"
+)
+
+func TestAddHeaderAndDoWithoutMiddlewares(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Values(requestid.HeaderRequestId)
+	}))
+	defer server.Close()
+	calls := 0
+	SetObserver(func(method string, host string, status int, duration time.Duration) {
+		calls++
+	})
+	defer SetObserver(nil)
+	ctx := requestid.WithRequestId(context.Background(), "req-1")
+
+	res, err := DoGet(ctx, &http.Client{}, server.URL+"/users/ironman", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if len(got) != 1 || got[0] != "req-1" || calls != 1 {
+		t.Errorf("plain client: X-Request-ID %v, observed %d times; want req-1 once, observed once", got, calls)
+	}
+
+	calls = 0
+	c := Use(&http.Client{}, Metrics(), RequestId())
+	res, err = DoGet(ctx, c, server.URL+"/users/ironman", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if len(got) != 1 || got[0] != "req-1" || calls != 1 {
+		t.Errorf("client with the middlewares: X-Request-ID %v, observed %d times; want req-1 once, observed once", got, calls)
+	}
+}
+
+func TestFind(t *testing.T) {
+	c := Use(&http.Client{}, Metrics(), RequestId(), Decompress())
+	if !Has[*MetricsTransport](c) || !Has[*requestid.Transport](c) || !Has[*DecompressTransport](c) || Has[*Breaker](c) {
+		t.Error("Has() must follow the chain")
+	}
+	if Has[*MetricsTransport](&http.Client{}) {
+		t.Error("a plain client has no middleware")
+	}
+}
+
+func TestDoAndLog(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"id":"ironman"}`))
+	}))
+	defer server.Close()
+	var logged []string
+	logError := func(ctx context.Context, msg string, fields map[string]interface{}) {
+		logged = append(logged, msg)
+	}
+	res, err := DoAndLog(context.Background(), &http.Client{}, get, server.URL+"/users/ironman", nil, nil, &LogConfig{}, logError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(b) != `{"id":"ironman"}` {
+		t.Errorf("body %q", b)
+	}
+	for _, status = range []int{http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable} {
+		logged = nil
+		res, err = DoAndLog(context.Background(), &http.Client{}, get, server.URL+"/users/ironman", nil, nil, &LogConfig{}, logError)
+		if res != nil || !IsStatus(err, status) || len(logged) != 1 {
+			t.Errorf("status %d: response %v, error %v, logged %v", status, res, err, logged)
+		}
+	}
+}
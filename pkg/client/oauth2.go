@@ -0,0 +1,251 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AuthStyleHeader = "header"
+	AuthStyleParams = "params"
+)
+
+type OAuth2Config struct {
+	TokenUrl         string        `yaml:"token_url" mapstructure:"token_url" json:"tokenUrl,omitempty" gorm:"column:tokenurl" bson:"tokenUrl,omitempty" dynamodbav:"tokenUrl,omitempty" firestore:"tokenUrl,omitempty"`
+	ClientId         string        `yaml:"client_id" mapstructure:"client_id" json:"clientId,omitempty" gorm:"column:clientid" bson:"clientId,omitempty" dynamodbav:"clientId,omitempty" firestore:"clientId,omitempty"`
+	ClientSecret     string        `yaml:"client_secret" mapstructure:"client_secret" json:"clientSecret,omitempty" gorm:"column:clientsecret" bson:"clientSecret,omitempty" dynamodbav:"clientSecret,omitempty" firestore:"clientSecret,omitempty"`
+	ClientSecretFile string        `yaml:"client_secret_file" mapstructure:"client_secret_file" json:"clientSecretFile,omitempty" gorm:"column:clientsecretfile" bson:"clientSecretFile,omitempty" dynamodbav:"clientSecretFile,omitempty" firestore:"clientSecretFile,omitempty"`
+	Scopes           []string      `yaml:"scopes" mapstructure:"scopes" json:"scopes,omitempty" gorm:"column:scopes" bson:"scopes,omitempty" dynamodbav:"scopes,omitempty" firestore:"scopes,omitempty"`
+	Audience         string        `yaml:"audience" mapstructure:"audience" json:"audience,omitempty" gorm:"column:audience" bson:"audience,omitempty" dynamodbav:"audience,omitempty" firestore:"audience,omitempty"`
+	AuthStyle        string        `yaml:"auth_style" mapstructure:"auth_style" json:"authStyle,omitempty" gorm:"column:authstyle" bson:"authStyle,omitempty" dynamodbav:"authStyle,omitempty" firestore:"authStyle,omitempty"`
+	RefreshBefore    time.Duration `yaml:"refresh_before" mapstructure:"refresh_before" json:"refreshBefore,omitempty" gorm:"column:refreshbefore" bson:"refreshBefore,omitempty" dynamodbav:"refreshBefore,omitempty" firestore:"refreshBefore,omitempty"`
+	Timeout          time.Duration `yaml:"timeout" mapstructure:"timeout" json:"timeout,omitempty" gorm:"column:timeout" bson:"timeout,omitempty" dynamodbav:"timeout,omitempty" firestore:"timeout,omitempty"`
+}
+
+type Token struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type,omitempty"`
+	ExpiresIn   int64     `json:"expires_in,omitempty"`
+	Expiry      time.Time `json:"-"`
+}
+
+// ClientCredentials is a TokenSource of the OAuth2 client credentials grant. The token is cached until RefreshBefore its expiry,
+// then refreshed in the background while it is still used. Concurrent callers share a single request to the token endpoint.
+type ClientCredentials struct {
+	Config OAuth2Config
+	Client *http.Client
+	mu     sync.Mutex
+	token  *Token
+	flight *tokenFlight
+}
+
+type tokenFlight struct {
+	done  chan struct{}
+	token *Token
+	err   error
+}
+
+func NewClientCredentials(c OAuth2Config, client *http.Client) (*ClientCredentials, error) {
+	if len(c.TokenUrl) == 0 || len(c.ClientId) == 0 {
+		return nil, errors.New("oauth2.token_url and oauth2.client_id are required")
+	}
+	if len(c.ClientSecret) == 0 && len(c.ClientSecretFile) == 0 {
+		return nil, errors.New("oauth2.client_secret or oauth2.client_secret_file is required")
+	}
+	switch c.AuthStyle {
+	case "":
+		c.AuthStyle = AuthStyleHeader
+	case AuthStyleHeader, AuthStyleParams:
+	default:
+		return nil, fmt.Errorf("oauth2.auth_style %q must be %s or %s", c.AuthStyle, AuthStyleHeader, AuthStyleParams)
+	}
+	if c.RefreshBefore <= 0 {
+		c.RefreshBefore = time.Minute
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &ClientCredentials{Config: c, Client: client}, nil
+}
+
+func (s *ClientCredentials) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	now := time.Now()
+	if t := s.token; t != nil && (t.Expiry.IsZero() || now.Before(t.Expiry)) {
+		if !t.Expiry.IsZero() && now.After(t.Expiry.Add(-s.refreshBefore(t))) {
+			s.refresh()
+		}
+		s.mu.Unlock()
+		return t.AccessToken, nil
+	}
+	f := s.refresh()
+	s.mu.Unlock()
+	select {
+	case <-f.done:
+		if f.err != nil {
+			return "", f.err
+		}
+		return f.token.AccessToken, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Invalidate drops the cached token if it is still the given one, so that the next call fetches a new token.
+func (s *ClientCredentials) Invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != nil && s.token.AccessToken == token {
+		s.token = nil
+	}
+}
+
+// refreshBefore is capped at half the lifetime of the token, so that a short-lived token is not refreshed on every call.
+func (s *ClientCredentials) refreshBefore(t *Token) time.Duration {
+	if half := time.Duration(t.ExpiresIn) * time.Second / 2; half < s.Config.RefreshBefore {
+		return half
+	}
+	return s.Config.RefreshBefore
+}
+
+// refresh starts a request to the token endpoint, unless one is in flight. It must be called with the lock held.
+func (s *ClientCredentials) refresh() *tokenFlight {
+	if s.flight != nil {
+		return s.flight
+	}
+	f := &tokenFlight{done: make(chan struct{})}
+	s.flight = f
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.Config.Timeout)
+		defer cancel()
+		t, err := s.fetch(ctx)
+		s.mu.Lock()
+		f.token, f.err = t, err
+		if err == nil {
+			s.token = t
+		}
+		s.flight = nil
+		s.mu.Unlock()
+		close(f.done)
+	}()
+	return f
+}
+
+func (s *ClientCredentials) fetch(ctx context.Context) (*Token, error) {
+	c := s.Config
+	secret := c.ClientSecret
+	if len(c.ClientSecretFile) > 0 {
+		b, err := os.ReadFile(c.ClientSecretFile)
+		if err != nil {
+			return nil, err
+		}
+		secret = strings.TrimSpace(string(b))
+	}
+	form := neturl.Values{"grant_type": {"client_credentials"}}
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	if len(c.Audience) > 0 {
+		form.Set("audience", c.Audience)
+	}
+	if c.AuthStyle == AuthStyleParams {
+		form.Set("client_id", c.ClientId)
+		form.Set("client_secret", secret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.AuthStyle == AuthStyleHeader {
+		req.SetBasicAuth(neturl.QueryEscape(c.ClientId), neturl.QueryEscape(secret))
+	}
+	start := time.Now()
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2 token: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("oauth2 token: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		var e struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		json.Unmarshal(body, &e)
+		return nil, NewHttpError(res.StatusCode, nil, time.Since(start).Milliseconds(), strings.TrimSpace("oauth2 token: "+res.Status+" "+e.Error+" "+e.Description), c.TokenUrl, "", "", ErrorTypeResponse, e.Error)
+	}
+	var t Token
+	if err = json.Unmarshal(body, &t); err != nil {
+		return nil, fmt.Errorf("oauth2 token: %w", err)
+	}
+	if len(t.AccessToken) == 0 {
+		return nil, errors.New("oauth2 token: no access_token in the response")
+	}
+	if t.ExpiresIn > 0 {
+		t.Expiry = start.Add(time.Duration(t.ExpiresIn) * time.Second)
+	}
+	return &t, nil
+}
+
+// OAuth2Transport sets the Bearer token of the source. On 401, it drops the token and sends the request once more with a new one,
+// when the body of the request can be replayed.
+type OAuth2Transport struct {
+	Base   http.RoundTripper
+	Source *ClientCredentials
+}
+
+func OAuth2(source *ClientCredentials) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &OAuth2Transport{Base: next, Source: source}
+	}
+}
+func (t *OAuth2Transport) Unwrap() http.RoundTripper {
+	return t.Base
+}
+func (t *OAuth2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.Source.Token(req.Context())
+	if err != nil {
+		closeBody(req)
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	res, err := t.Base.RoundTrip(r)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return res, nil
+	}
+	t.Source.Invalidate(token)
+	if token, err = t.Source.Token(req.Context()); err != nil {
+		return res, nil
+	}
+	r = req.Clone(req.Context())
+	if req.GetBody != nil {
+		if r.Body, err = req.GetBody(); err != nil {
+			return res, nil
+		}
+	}
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	res.Body.Close()
+	r.Header.Set("Authorization", "Bearer "+token)
+	return t.Base.RoundTrip(r)
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOAuth2TokenClientUsesTheTLSOfTheEndpoint(t *testing.T) {
+	tokens := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/token":
+			tokens++
+			if id, secret, ok := r.BasicAuth(); !ok || id != "users" || secret != "s3cret" || r.FormValue("grant_type") != "client_credentials" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"t1","token_type":"Bearer","expires_in":3600}`))
+		default:
+			if r.Header.Get("Authorization") != "Bearer t1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"id":"ironman"}`))
+		}
+	}))
+	defer server.Close()
+
+	// The certificate of the test server is self-signed, so a token client built without the config of the endpoint would fail.
+	insecure := true
+	c, _, _, err := InitClient(ClientConf{Config: Conf{
+		Insecure: &insecure,
+		OAuth2:   &OAuth2Config{TokenUrl: server.URL + "/oauth2/token", ClientId: "users", ClientSecret: "s3cret"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := DoGet(context.Background(), c, server.URL+"/users/ironman", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || tokens != 1 {
+		t.Errorf("status %d after %d token requests, want 200 after 1", res.StatusCode, tokens)
+	}
+}
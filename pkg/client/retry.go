@@ -0,0 +1,230 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+type RetryConfig struct {
+	MaxAttempts int           `yaml:"max_attempts" mapstructure:"max_attempts" json:"maxAttempts,omitempty" gorm:"column:maxattempts" bson:"maxAttempts,omitempty" dynamodbav:"maxAttempts,omitempty" firestore:"maxAttempts,omitempty"`
+	BaseDelay   time.Duration `yaml:"base_delay" mapstructure:"base_delay" json:"baseDelay,omitempty" gorm:"column:basedelay" bson:"baseDelay,omitempty" dynamodbav:"baseDelay,omitempty" firestore:"baseDelay,omitempty"`
+	MaxDelay    time.Duration `yaml:"max_delay" mapstructure:"max_delay" json:"maxDelay,omitempty" gorm:"column:maxdelay" bson:"maxDelay,omitempty" dynamodbav:"maxDelay,omitempty" firestore:"maxDelay,omitempty"`
+	Jitter      float64       `yaml:"jitter" mapstructure:"jitter" json:"jitter,omitempty" gorm:"column:jitter" bson:"jitter,omitempty" dynamodbav:"jitter,omitempty" firestore:"jitter,omitempty"`
+	Statuses    string        `yaml:"statuses" mapstructure:"statuses" json:"statuses,omitempty" gorm:"column:statuses" bson:"statuses,omitempty" dynamodbav:"statuses,omitempty" firestore:"statuses,omitempty"`
+	Network     *bool         `yaml:"network" mapstructure:"network" json:"network,omitempty" gorm:"column:network" bson:"network,omitempty" dynamodbav:"network,omitempty" firestore:"network,omitempty"`
+	Idempotency string        `yaml:"idempotency" mapstructure:"idempotency" json:"idempotency,omitempty" gorm:"column:idempotency" bson:"idempotency,omitempty" dynamodbav:"idempotency,omitempty" firestore:"idempotency,omitempty"`
+}
+
+var logRetry func(context.Context, string, map[string]interface{})
+
+// SetRetryLog sets the function logging each failed attempt, with the Duration, ResponseStatus and Error fields of the LogConfig.
+func SetRetryLog(f func(context.Context, string, map[string]interface{})) {
+	logRetry = f
+}
+
+// RetryTransport retries requests failing with a retryable status or network error, with exponential backoff and jitter.
+// GET, HEAD, OPTIONS, PUT and DELETE are retried; POST and PATCH only when they carry an idempotency key.
+type RetryTransport struct {
+	Base        http.RoundTripper
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      float64
+	Statuses    map[int]bool
+	Network     bool
+	Idempotency string
+	Config      *LogConfig
+}
+
+func NewRetryTransport(base http.RoundTripper, c RetryConfig, conf *LogConfig) (*RetryTransport, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &RetryTransport{Base: base, MaxAttempts: c.MaxAttempts, BaseDelay: c.BaseDelay, MaxDelay: c.MaxDelay, Jitter: c.Jitter, Network: true, Idempotency: c.Idempotency, Config: conf}
+	if t.BaseDelay <= 0 {
+		t.BaseDelay = 100 * time.Millisecond
+	}
+	if t.MaxDelay <= 0 {
+		t.MaxDelay = 2 * time.Second
+	}
+	if t.Jitter < 0 || t.Jitter > 1 {
+		return nil, errors.New("retry.jitter must be between 0 and 1")
+	}
+	if c.Network != nil {
+		t.Network = *c.Network
+	}
+	if len(t.Idempotency) == 0 {
+		t.Idempotency = HeaderIdempotencyKey
+	}
+	statuses, err := ParseStatuses(c.Statuses, "408,429,502,503,504")
+	if err != nil {
+		return nil, errors.New("retry.statuses: " + err.Error())
+	}
+	t.Statuses = statuses
+	return t, nil
+}
+
+// ParseStatuses parses a comma separated list of status codes, or the default list when s is empty.
+func ParseStatuses(s string, defaultStatuses string) (map[int]bool, error) {
+	if len(s) == 0 {
+		s = defaultStatuses
+	}
+	statuses := make(map[int]bool)
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		status, err := strconv.Atoi(v)
+		if err != nil || status < 100 || status > 599 {
+			return nil, errors.New(v + " is not a status code")
+		}
+		statuses[status] = true
+	}
+	return statuses, nil
+}
+
+// WithRetry returns a copy of the client retrying its requests. The timeout of the client applies to each attempt rather than to all of them.
+func WithRetry(client *http.Client, c RetryConfig, conf *LogConfig) (*http.Client, error) {
+	r, err := NewRetryTransport(NewTimeoutTransport(client.Transport, client.Timeout), c, conf)
+	if err != nil {
+		return nil, err
+	}
+	retried := *client
+	retried.Transport = r
+	retried.Timeout = 0
+	return &retried, nil
+}
+
+func (t *RetryTransport) Unwrap() http.RoundTripper {
+	return t.Base
+}
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.MaxAttempts <= 1 || !t.Retryable(req) {
+		return t.Base.RoundTrip(req)
+	}
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		r := req
+		if attempt > 1 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(ctx)
+			r.Body = body
+		}
+		start := time.Now()
+		res, err := t.Base.RoundTrip(r)
+		if attempt >= t.MaxAttempts || !t.retry(ctx, res, err) {
+			return res, err
+		}
+		delay, ok := t.Delay(attempt, res)
+		if !ok {
+			return res, err
+		}
+		t.log(ctx, req, attempt, time.Since(start), delay, res, err)
+		if res != nil {
+			io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+			res.Body.Close()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Retryable reports whether the request can be sent again: it is idempotent, or has an idempotency key, and its body can be replayed.
+func (t *RetryTransport) Retryable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost, http.MethodPatch:
+		return len(req.Header.Get(t.Idempotency)) > 0
+	}
+	return false
+}
+
+func (t *RetryTransport) retry(ctx context.Context, res *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return t.Network
+	}
+	return t.Statuses[res.StatusCode]
+}
+
+// Delay returns the backoff of the attempt: BaseDelay * 2^(attempt-1), capped by MaxDelay, of which the Jitter fraction is random.
+// A Retry-After header takes precedence; false means that it asks to wait longer than MaxDelay, and the response is returned as is.
+func (t *RetryTransport) Delay(attempt int, res *http.Response) (time.Duration, bool) {
+	if res != nil {
+		if after, ok := RetryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+			return after, after <= t.MaxDelay
+		}
+	}
+	delay := time.Duration(math.Min(float64(t.MaxDelay), float64(t.BaseDelay)*math.Pow(2, float64(attempt-1))))
+	if t.Jitter > 0 {
+		j := float64(delay) * t.Jitter
+		delay = time.Duration(float64(delay) - j + rand.Float64()*j)
+	}
+	return delay, true
+}
+
+// RetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func RetryAfter(s string, now time.Time) (time.Duration, bool) {
+	if len(s) == 0 {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(s); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func (t *RetryTransport) log(ctx context.Context, req *http.Request, attempt int, duration time.Duration, delay time.Duration, res *http.Response, err error) {
+	if logRetry == nil {
+		return
+	}
+	var c LogConfig
+	if t.Config != nil {
+		c = *t.Config
+	} else {
+		c.Duration = "duration"
+		c.ResponseStatus = "status"
+		c.Error = "error"
+	}
+	fs := map[string]interface{}{"attempt": attempt, "delay": delay.Milliseconds()}
+	if len(c.Duration) > 0 {
+		fs[c.Duration] = duration.Milliseconds()
+	}
+	if res != nil && len(c.ResponseStatus) > 0 {
+		fs[c.ResponseStatus] = res.StatusCode
+	}
+	if err != nil && len(c.Error) > 0 {
+		fs[c.Error] = err.Error()
+	}
+	logRetry(ctx, "retry "+req.Method+" "+req.URL.Redacted(), fs)
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// statuses returns a transport answering with the statuses in turn, the last one repeated, and counting the attempts.
+func statuses(attempts *int, bodies *[]string, codes ...int) RoundTripperFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		i := *attempts
+		*attempts++
+		if bodies != nil && req.Body != nil {
+			b, _ := io.ReadAll(req.Body)
+			*bodies = append(*bodies, string(b))
+		}
+		if i >= len(codes) {
+			i = len(codes) - 1
+		}
+		if codes[i] == 0 {
+			return nil, errors.New("connection reset by peer")
+		}
+		return &http.Response{StatusCode: codes[i], Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+	}
+}
+
+func newRetry(t *testing.T, base http.RoundTripper, c RetryConfig) *RetryTransport {
+	t.Helper()
+	if c.BaseDelay == 0 {
+		c.BaseDelay = time.Millisecond
+	}
+	r, err := NewRetryTransport(base, c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRetryStatusesAndNetworkErrors(t *testing.T) {
+	attempts := 0
+	r := newRetry(t, statuses(&attempts, nil, 503, 0, 200), RetryConfig{MaxAttempts: 3})
+	req, _ := http.NewRequest(http.MethodGet, "http://users.internal/users/ironman", nil)
+	res, err := r.RoundTrip(req)
+	if err != nil || res.StatusCode != 200 || attempts != 3 {
+		t.Errorf("RoundTrip() = %v, %v after %d attempts, want 200 after 3", res, err, attempts)
+	}
+
+	attempts = 0
+	r = newRetry(t, statuses(&attempts, nil, 503), RetryConfig{MaxAttempts: 3})
+	if res, _ := r.RoundTrip(req); res.StatusCode != 503 || attempts != 3 {
+		t.Errorf("status %d after %d attempts, want the last response after 3", res.StatusCode, attempts)
+	}
+
+	attempts = 0
+	r = newRetry(t, statuses(&attempts, nil, 500), RetryConfig{MaxAttempts: 3})
+	if res, _ := r.RoundTrip(req); res.StatusCode != 500 || attempts != 1 {
+		t.Errorf("status %d after %d attempts, want 500 not retried", res.StatusCode, attempts)
+	}
+
+	attempts = 0
+	network := false
+	r = newRetry(t, statuses(&attempts, nil, 0, 200), RetryConfig{MaxAttempts: 3, Network: &network})
+	if _, err := r.RoundTrip(req); err == nil || attempts != 1 {
+		t.Errorf("error %v after %d attempts, want the network error not retried", err, attempts)
+	}
+}
+
+func TestRetryReplaysBodyOnlyWithIdempotencyKey(t *testing.T) {
+	attempts := 0
+	var bodies []string
+	r := newRetry(t, statuses(&attempts, &bodies, 503, 201), RetryConfig{MaxAttempts: 3})
+	newPost := func() *http.Request {
+		req, _ := http.NewRequest(http.MethodPost, "http://users.internal/users", bytes.NewReader([]byte(`{"id":"hulk"}`)))
+		return req
+	}
+	if res, _ := r.RoundTrip(newPost()); res.StatusCode != 503 || attempts != 1 {
+		t.Errorf("status %d after %d attempts, want a POST without key sent once", res.StatusCode, attempts)
+	}
+
+	attempts, bodies = 0, nil
+	req := newPost()
+	req.Header.Set(HeaderIdempotencyKey, "k1")
+	if res, _ := r.RoundTrip(req); res.StatusCode != 201 || attempts != 2 {
+		t.Errorf("status %d after %d attempts, want 201 after 2", res.StatusCode, attempts)
+	}
+	if len(bodies) != 2 || bodies[1] != `{"id":"hulk"}` {
+		t.Errorf("bodies = %q, want the body sent again", bodies)
+	}
+
+	req = newPost()
+	req.Header.Set(HeaderIdempotencyKey, "k2")
+	req.GetBody = nil
+	if r.Retryable(req) {
+		t.Error("a body which cannot be replayed must not be retried")
+	}
+}
+
+func TestRetryStopsWhenContextIsDone(t *testing.T) {
+	attempts := 0
+	r := newRetry(t, statuses(&attempts, nil, 503), RetryConfig{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://users.internal/users/ironman", nil)
+	if _, err := r.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) || attempts != 1 {
+		t.Errorf("error %v after %d attempts, want the deadline while waiting", err, attempts)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	r := &RetryTransport{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 5: time.Second} {
+		if d, ok := r.Delay(attempt, nil); !ok || d != want {
+			t.Errorf("Delay(%d) = %s, want %s", attempt, d, want)
+		}
+	}
+	r.Jitter = 0.5
+	for i := 0; i < 20; i++ {
+		if d, _ := r.Delay(2, nil); d < 100*time.Millisecond || d > 200*time.Millisecond {
+			t.Fatalf("Delay(2) with jitter = %s, want between 100ms and 200ms", d)
+		}
+	}
+	res := &http.Response{Header: http.Header{"Retry-After": {"1"}}}
+	if d, ok := r.Delay(1, res); !ok || d != time.Second {
+		t.Errorf("Delay() with Retry-After 1 = %s, %v", d, ok)
+	}
+	res.Header.Set("Retry-After", "5")
+	if _, ok := r.Delay(1, res); ok {
+		t.Error("a Retry-After longer than MaxDelay must not be waited for")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"-1", 0, false},
+		{"Mon, 01 Jan 2024 12:00:10 GMT", 10 * time.Second, true},
+		{"Mon, 01 Jan 2024 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		if d, ok := RetryAfter(tt.header, now); d != tt.want || ok != tt.ok {
+			t.Errorf("RetryAfter(%q) = %s, %v; want %s, %v", tt.header, d, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNewRetryTransport(t *testing.T) {
+	r, err := NewRetryTransport(nil, RetryConfig{MaxAttempts: 3}, nil)
+	if err != nil || !r.Statuses[429] || r.Statuses[500] || !r.Network || r.Idempotency != HeaderIdempotencyKey || r.BaseDelay != 100*time.Millisecond {
+		t.Errorf("NewRetryTransport() = %+v, %v", r, err)
+	}
+	if _, err := NewRetryTransport(nil, RetryConfig{Jitter: 2}, nil); err == nil {
+		t.Error("a jitter over 1 must be rejected")
+	}
+	if _, err := NewRetryTransport(nil, RetryConfig{Statuses: "503,abc"}, nil); err == nil {
+		t.Error("an invalid status must be rejected")
+	}
+}
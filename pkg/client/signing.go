@@ -0,0 +1,75 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-service/pkg/signature"
+)
+
+type SigningConfig struct {
+	KeyId      string `yaml:"key_id" mapstructure:"key_id" json:"keyId,omitempty" gorm:"column:keyid" bson:"keyId,omitempty" dynamodbav:"keyId,omitempty" firestore:"keyId,omitempty"`
+	Secret     string `yaml:"secret" mapstructure:"secret" json:"secret,omitempty" gorm:"column:secret" bson:"secret,omitempty" dynamodbav:"secret,omitempty" firestore:"secret,omitempty"`
+	SecretFile string `yaml:"secret_file" mapstructure:"secret_file" json:"secretFile,omitempty" gorm:"column:secretfile" bson:"secretFile,omitempty" dynamodbav:"secretFile,omitempty" firestore:"secretFile,omitempty"`
+}
+
+// Signer signs a request with the HMAC-SHA256 of its signature.Canonical form, in the X-Key-Id, X-Timestamp, X-Nonce and X-Signature headers.
+type Signer struct {
+	KeyId  string
+	Secret []byte
+	Now    func() time.Time
+}
+
+// NewSigner takes the secret from secret_file, or else from secret.
+func NewSigner(c SigningConfig) (*Signer, error) {
+	if len(c.KeyId) == 0 {
+		return nil, errors.New("signing.key_id is required")
+	}
+	if len(c.Secret) > 0 && len(c.SecretFile) > 0 {
+		return nil, errors.New("signing.secret and signing.secret_file cannot be both set")
+	}
+	secret := c.Secret
+	if len(c.SecretFile) > 0 {
+		b, err := os.ReadFile(c.SecretFile)
+		if err != nil {
+			return nil, err
+		}
+		secret = strings.TrimSpace(string(b))
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("signing.secret or signing.secret_file is required")
+	}
+	return &Signer{KeyId: c.KeyId, Secret: []byte(secret)}, nil
+}
+
+// Sign sets the signature headers of the request. Its body stays readable.
+func (s *Signer) Sign(req *http.Request) error {
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	nonce, err := signature.NewNonce()
+	if err != nil {
+		return err
+	}
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	timestamp := strconv.FormatInt(now().Unix(), 10)
+	req.Header.Set(HeaderKeyId, s.KeyId)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, signature.Sign(s.Secret, signature.Canonical(req.Method, req.URL.RequestURI(), timestamp, nonce, body)))
+	return nil
+}
+
+// Signing signs every request. Like Auth, it is placed inside the retries, so each attempt has its own timestamp and nonce
+// and is not taken for a replay.
+func Signing(s *Signer) Middleware {
+	return authorize(s.Sign)
+}
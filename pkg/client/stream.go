@@ -0,0 +1,196 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// DoAndDecode sends the request as DoAndBuildDecoder does, then decodes the body into result as it is read, without buffering it.
+// A body failing to be read, or exceeding the limit of the client, is returned as *HttpError.
+func DoAndDecode(ctx context.Context, client *http.Client, method string, url string, body []byte, headers map[string]string, result interface{}, conf *LogConfig, options ...func(context.Context, string, map[string]interface{})) error {
+	res, dur, err := do(ctx, client, method, url, body, headers, conf, options...)
+	if err != nil {
+		return err
+	}
+	r := &bodyReader{Reader: res.Body}
+	defer closeResponse(res)
+	if result == nil {
+		return nil
+	}
+	if err = json.NewDecoder(r).Decode(result); err != nil {
+		if r.err != nil {
+			return bodyError(res.StatusCode, r.err, dur, url, body)
+		}
+		return err
+	}
+	return nil
+}
+
+// DoStream sends the request as DoAndBuildDecoder does, and returns the Stream of the items of the response:
+// NDJSON when the Content-Type says so, else a JSON array, found in the objects of the path, if any. The caller must close the Stream.
+func DoStream(ctx context.Context, client *http.Client, method string, url string, body []byte, headers map[string]string, conf *LogConfig, path []string, options ...func(context.Context, string, map[string]interface{})) (*Stream, error) {
+	res, _, err := do(ctx, client, method, url, body, headers, conf, options...)
+	if err != nil {
+		return nil, err
+	}
+	return NewStream(res.Body, IsNDJSON(res.Header.Get("Content-Type")), path...), nil
+}
+
+func IsNDJSON(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return true
+	}
+	return false
+}
+
+// Stream decodes the items of a JSON array, or the values of NDJSON, one at a time, as rows of database/sql are scanned:
+//
+//	for stream.Next() {
+//		var user User
+//		if err := stream.Decode(&user); err != nil {...}
+//	}
+//	if err := stream.Err(); err != nil {...}
+//
+// The path is the keys of the objects around the array, such as "list" for {"list":[...],"total":10}: the other fields are skipped.
+// An item not decoded is skipped by the next call to Next.
+type Stream struct {
+	body    io.ReadCloser
+	decoder *json.Decoder
+	ndjson  bool
+	path    []string
+	started bool
+	pending bool
+	done    bool
+	err     error
+}
+
+func NewStream(body io.ReadCloser, ndjson bool, path ...string) *Stream {
+	return &Stream{body: body, decoder: json.NewDecoder(body), ndjson: ndjson, path: path}
+}
+
+func (s *Stream) Next() bool {
+	if s.err != nil || s.done {
+		return false
+	}
+	if s.pending {
+		var skipped json.RawMessage
+		if s.err = s.decoder.Decode(&skipped); s.err != nil {
+			return false
+		}
+		s.pending = false
+	}
+	if !s.started {
+		s.started = true
+		if !s.ndjson {
+			if s.err = s.open(); s.err != nil || s.done {
+				return false
+			}
+		}
+	}
+	if s.decoder.More() {
+		s.pending = true
+		return true
+	}
+	// More also returns false on a read error, which the next token reports.
+	s.done = true
+	t, err := s.decoder.Token()
+	if s.ndjson {
+		if err != io.EOF {
+			s.err = unexpected(t, err)
+		}
+	} else if t != json.Delim(']') {
+		s.err = unexpected(t, err)
+	}
+	return false
+}
+
+// open reads up to the start of the array. A null array has no item.
+func (s *Stream) open() error {
+	for _, key := range s.path {
+		if t, err := s.decoder.Token(); t != json.Delim('{') {
+			return unexpected(t, err)
+		}
+		for {
+			t, err := s.decoder.Token()
+			if err != nil {
+				return err
+			}
+			if t == json.Delim('}') {
+				return fmt.Errorf("json: no %s field", key)
+			}
+			if t == key {
+				break
+			}
+			var skipped json.RawMessage
+			if err = s.decoder.Decode(&skipped); err != nil {
+				return err
+			}
+		}
+	}
+	t, err := s.decoder.Token()
+	if t == nil && err == nil {
+		s.done = true
+		return nil
+	}
+	if t != json.Delim('[') {
+		return unexpected(t, err)
+	}
+	return nil
+}
+
+// Decode decodes the item found by Next.
+func (s *Stream) Decode(v interface{}) error {
+	if !s.pending {
+		return errors.New("json: Decode called without Next")
+	}
+	s.pending = false
+	if err := s.decoder.Decode(v); err != nil {
+		s.err = err
+		return err
+	}
+	return nil
+}
+
+// Err returns the error that ended the iteration: a *BodyTooLargeError when the body exceeds the limit of the client, or a JSON error.
+func (s *Stream) Err() error {
+	return s.err
+}
+
+// Close drains a little of what is left of the body, so that the connection can be reused, then closes it.
+func (s *Stream) Close() error {
+	io.Copy(io.Discard, io.LimitReader(s.body, 4<<10))
+	return s.body.Close()
+}
+
+func unexpected(t json.Token, err error) error {
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("json: unexpected %v", t)
+}
+
+// bodyReader keeps the read error of the body, to tell it from a decoding error.
+type bodyReader struct {
+	io.Reader
+	err error
+}
+
+func (r *bodyReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+func closeResponse(res *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4<<10))
+	res.Body.Close()
+}
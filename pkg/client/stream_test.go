@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type item struct {
+	Id string `json:"id"`
+}
+
+func readAll(t *testing.T, s *Stream, decode func(int) bool) ([]string, error) {
+	t.Helper()
+	var ids []string
+	for i := 0; s.Next(); i++ {
+		if !decode(i) {
+			continue
+		}
+		var it item
+		if err := s.Decode(&it); err != nil {
+			return ids, err
+		}
+		ids = append(ids, it.Id)
+	}
+	return ids, s.Err()
+}
+
+func all(int) bool { return true }
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		ndjson bool
+		path   []string
+		want   string
+	}{
+		{"array", `[{"id":"a"},{"id":"b"}]`, false, nil, "a,b"},
+		{"empty array", ` [] `, false, nil, ""},
+		{"null", `null`, false, nil, ""},
+		{"path", `{"total":2,"meta":{"list":[{"id":"x"}]},"list":[{"id":"a"},{"id":"b"}]}`, false, []string{"list"}, "a,b"},
+		{"nested path", `{"data":{"skip":[1,2],"users":[{"id":"a"}]}}`, false, []string{"data", "users"}, "a"},
+		{"ndjson", "{\"id\":\"a\"}\n{\"id\":\"b\"}\n", true, nil, "a,b"},
+	}
+	for _, tt := range tests {
+		s := NewStream(io.NopCloser(strings.NewReader(tt.body)), tt.ndjson, tt.path...)
+		ids, err := readAll(t, s, all)
+		if err != nil || strings.Join(ids, ",") != tt.want {
+			t.Errorf("%s: items %v, error %v; want %s", tt.name, ids, err, tt.want)
+		}
+		if s.Next() {
+			t.Errorf("%s: Next() after the end", tt.name)
+		}
+	}
+}
+
+func TestStreamSkipsItemsNotDecoded(t *testing.T) {
+	s := NewStream(io.NopCloser(strings.NewReader(`[{"id":"a"},{"id":"b","tags":["x"]},{"id":"c"}]`)), false)
+	ids, err := readAll(t, s, func(i int) bool { return i != 1 })
+	if err != nil || strings.Join(ids, ",") != "a,c" {
+		t.Errorf("items %v, error %v; want a,c", ids, err)
+	}
+	s = NewStream(io.NopCloser(strings.NewReader(`[]`)), false)
+	if err := s.Decode(&item{}); err == nil {
+		t.Error("Decode() without Next must fail")
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		ndjson bool
+		path   []string
+	}{
+		{"not an array", `{"id":"a"}`, false, nil},
+		{"truncated", `[{"id":"a"},{"id":`, false, nil},
+		{"missing path", `{"total":0}`, false, []string{"list"}},
+		{"path of an array", `[{"id":"a"}]`, false, []string{"list"}},
+		{"ndjson garbage", "{\"id\":\"a\"}\n]", true, nil},
+	}
+	for _, tt := range tests {
+		s := NewStream(io.NopCloser(strings.NewReader(tt.body)), tt.ndjson, tt.path...)
+		if _, err := readAll(t, s, all); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
+
+func TestIsNDJSON(t *testing.T) {
+	for contentType, want := range map[string]bool{"application/x-ndjson": true, "application/jsonl; charset=utf-8": true, "application/json": false, "": false} {
+		if IsNDJSON(contentType) != want {
+			t.Errorf("IsNDJSON(%q) = %v", contentType, !want)
+		}
+	}
+}
+
+func TestDoStreamAndDoAndDecode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ndjson":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Write([]byte("{\"id\":\"a\"}\n{\"id\":\"b\"}\n"))
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"list":[{"id":"a"},{"id":"b"},{"id":"c"}],"total":3}`))
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	s, err := DoStream(ctx, server.Client(), http.MethodGet, server.URL+"/ndjson", nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err := readAll(t, s, all)
+	s.Close()
+	if err != nil || strings.Join(ids, ",") != "a,b" {
+		t.Errorf("ndjson: items %v, error %v", ids, err)
+	}
+	s, err = DoStream(ctx, server.Client(), http.MethodGet, server.URL+"/users", nil, nil, nil, []string{"list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err = readAll(t, s, all)
+	s.Close()
+	if err != nil || strings.Join(ids, ",") != "a,b,c" {
+		t.Errorf("array: items %v, error %v", ids, err)
+	}
+	if _, err = DoStream(ctx, server.Client(), http.MethodGet, server.URL+"/missing", nil, nil, nil, nil); !IsStatus(err, http.StatusNotFound) {
+		t.Errorf("missing: %v, want status 404", err)
+	}
+
+	limited := Use(server.Client(), Limit(20))
+	var result struct {
+		List []item `json:"list"`
+	}
+	err = DoAndDecode(ctx, limited, http.MethodGet, server.URL+"/users", nil, nil, &result, nil)
+	var tooLarge *BodyTooLargeError
+	httpErr, ok := IsHttpError(err)
+	if !ok || httpErr.ErrorType != ErrorTypeBodyTooLarge || !errors.As(err, &tooLarge) || tooLarge.Limit != 20 {
+		t.Errorf("DoAndDecode() over the limit = %v, want an error of type body_too_large", err)
+	}
+	if err = DoAndDecode(ctx, server.Client(), http.MethodGet, server.URL+"/users", nil, nil, &result, nil); err != nil || len(result.List) != 3 {
+		t.Errorf("DoAndDecode() = %v, %+v", err, result)
+	}
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// TimeoutTransport applies a per request timeout that can be changed while the client is in use,
+// unlike http.Client.Timeout which must not be modified after the first request.
+type TimeoutTransport struct {
+	Base    http.RoundTripper
+	timeout atomic.Int64
+}
+
+func NewTimeoutTransport(base http.RoundTripper, timeout time.Duration) *TimeoutTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &TimeoutTransport{Base: base}
+	t.SetTimeout(timeout)
+	return t
+}
+
+// WithTimeout returns a copy of the client whose timeout is controlled by the returned transport.
+// A client built WithRetry or WithBreaker already has one: with retries, it applies to each attempt.
+func WithTimeout(client *http.Client) (*http.Client, *TimeoutTransport) {
+	if t, ok := Find[*TimeoutTransport](client); ok {
+		return client, t
+	}
+	t := NewTimeoutTransport(client.Transport, client.Timeout)
+	c := *client
+	c.Transport = t
+	c.Timeout = 0
+	return &c, t
+}
+
+func (t *TimeoutTransport) Unwrap() http.RoundTripper {
+	return t.Base
+}
+func (t *TimeoutTransport) SetTimeout(timeout time.Duration) {
+	t.timeout.Store(int64(timeout))
+}
+func (t *TimeoutTransport) Timeout() time.Duration {
+	return time.Duration(t.timeout.Load())
+}
+
+func (t *TimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timeout := t.Timeout()
+	if timeout <= 0 {
+		return t.Base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	res, err := t.Base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return res, err
+	}
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,215 @@
+package envconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/core-go/config"
+	"gopkg.in/yaml.v3"
+)
+
+const FileSuffix = "_FILE"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load reads the config files, merges the overlay selected by the envName variable (config.<env>.yml),
+// then applies the environment and secret-file overrides under prefix.
+func Load(prefix string, envName string, c interface{}, fileNames ...string) error {
+	if err := config.LoadConfig(envName, c, fileNames...); err != nil {
+		return err
+	}
+	return Override(prefix, c)
+}
+
+// Override sets every field of c that has a matching <PREFIX>_<PATH> variable, or a <PREFIX>_<PATH>_FILE variable
+// pointing at a file with the value. All parse and read failures are returned together.
+func Override(prefix string, c interface{}) error {
+	v := reflect.ValueOf(c)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("envconfig: %T is not a pointer to a struct", c)
+	}
+	var errs []error
+	override(v.Elem(), strings.ToUpper(prefix), &errs)
+	return errors.Join(errs...)
+}
+
+func override(v reflect.Value, name string, errs *[]error) bool {
+	t := v.Type()
+	changed := false
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		key, squash := Key(f)
+		if key == "-" {
+			continue
+		}
+		n := name
+		if !squash {
+			n = Name(name, key)
+		}
+		fv := v.Field(i)
+		switch {
+		case f.Type.Kind() == reflect.Struct:
+			if override(fv, n, errs) {
+				changed = true
+			}
+		case f.Type.Kind() == reflect.Ptr && f.Type.Elem().Kind() == reflect.Struct:
+			p := reflect.New(f.Type.Elem())
+			if !fv.IsNil() {
+				p.Elem().Set(fv.Elem())
+			}
+			if override(p.Elem(), n, errs) {
+				fv.Set(p)
+				changed = true
+			}
+		default:
+			s, ok, err := Lookup(n)
+			if err != nil {
+				*errs = append(*errs, err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			if err = Set(fv, s); err != nil {
+				*errs = append(*errs, fmt.Errorf("%s: %w", n, err))
+				continue
+			}
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Key returns the mapstructure key of the field, falling back to the lower-cased field name.
+func Key(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("mapstructure")
+	parts := strings.Split(tag, ",")
+	squash := false
+	for _, p := range parts[1:] {
+		if p == "squash" {
+			squash = true
+		}
+	}
+	if len(parts[0]) == 0 {
+		return strings.ToLower(f.Name), squash
+	}
+	return parts[0], squash
+}
+
+func Name(prefix string, key string) string {
+	key = strings.NewReplacer("-", "_", ".", "_").Replace(strings.ToUpper(key))
+	if len(prefix) == 0 {
+		return key
+	}
+	return prefix + "_" + key
+}
+
+// Lookup returns the value of the variable, or the trimmed content of the file named by <name>_FILE.
+func Lookup(name string) (string, bool, error) {
+	if s, ok := os.LookupEnv(name); ok {
+		return s, true, nil
+	}
+	file := os.Getenv(name + FileSuffix)
+	if len(file) == 0 {
+		return "", false, nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", false, fmt.Errorf("%s%s: %w", name, FileSuffix, err)
+	}
+	return strings.TrimSpace(string(b)), true, nil
+}
+
+// Set parses s into v. Lists of strings are comma separated, maps of strings are k=v pairs,
+// anything else (lists of structs) is parsed as YAML or JSON.
+func Set(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		p := reflect.New(v.Type().Elem())
+		if err := Set(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return unmarshal(v, s)
+		}
+		items := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); len(item) > 0 {
+				items = reflect.Append(items, reflect.ValueOf(item).Convert(v.Type().Elem()))
+			}
+		}
+		v.Set(items)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.String {
+			return unmarshal(v, s)
+		}
+		m := reflect.MakeMap(v.Type())
+		for _, pair := range strings.Split(s, ",") {
+			if pair = strings.TrimSpace(pair); len(pair) == 0 {
+				continue
+			}
+			k, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("invalid pair %q, expected key=value", pair)
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(k)).Convert(v.Type().Key()), reflect.ValueOf(strings.TrimSpace(val)).Convert(v.Type().Elem()))
+		}
+		v.Set(m)
+	default:
+		return unmarshal(v, s)
+	}
+	return nil
+}
+
+func unmarshal(v reflect.Value, s string) error {
+	p := reflect.New(v.Type())
+	if err := yaml.Unmarshal([]byte(s), p.Interface()); err != nil {
+		return err
+	}
+	v.Set(p.Elem())
+	return nil
+}
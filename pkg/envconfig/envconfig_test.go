@@ -0,0 +1,158 @@
+package envconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type endpoint struct {
+	Url     string         `mapstructure:"url"`
+	Timeout *time.Duration `mapstructure:"timeout"`
+}
+type rule struct {
+	Field    string `yaml:"field" mapstructure:"field"`
+	KeepLast int    `yaml:"keep_last" mapstructure:"keep_last"`
+}
+type Base struct {
+	Name string `mapstructure:"name"`
+}
+type settings struct {
+	Base     `mapstructure:",squash"`
+	Port     *int64            `mapstructure:"port"`
+	Debug    bool              `mapstructure:"debug"`
+	Ratio    float64           `mapstructure:"ratio"`
+	Dsn      string            `mapstructure:"data_source_name"`
+	Fields   []string          `mapstructure:"fields"`
+	Claims   map[string]string `mapstructure:"claims"`
+	Rules    []rule            `mapstructure:"rules"`
+	Endpoint endpoint          `mapstructure:"endpoint"`
+	Health   *endpoint         `mapstructure:"health"`
+	Ignored  string            `mapstructure:"-"`
+	Level    string
+}
+
+func TestOverride(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "dsn")
+	if err := os.WriteFile(secret, []byte("postgres://user:pass@db/users\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"APP_PORT":                  "8080",
+		"APP_DEBUG":                 "true",
+		"APP_RATIO":                 "0.5",
+		"APP_DATA_SOURCE_NAME_FILE": secret,
+		"APP_FIELDS":                "requestId, userId,",
+		"APP_CLAIMS":                "sub=userId, email=mail",
+		"APP_RULES":                 "[{field: phone, keep_last: 3}]",
+		"APP_ENDPOINT_URL":          "http://localhost:8081",
+		"APP_ENDPOINT_TIMEOUT":      "3s",
+		"APP_HEALTH_URL":            "http://localhost:8081/health",
+		"APP_NAME":                  "users",
+		"APP_LEVEL":                 "debug",
+		"APP_IGNORED":               "x",
+	}
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	c := settings{Dsn: "from the file"}
+	if err := Override("app", &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Port == nil || *c.Port != 8080 || !c.Debug || c.Ratio != 0.5 || c.Name != "users" || c.Level != "debug" || len(c.Ignored) > 0 {
+		t.Errorf("scalars = %+v", c)
+	}
+	if c.Dsn != "postgres://user:pass@db/users" {
+		t.Errorf("Dsn = %q, want the trimmed content of the file", c.Dsn)
+	}
+	if strings.Join(c.Fields, "|") != "requestId|userId" || c.Claims["sub"] != "userId" || c.Claims["email"] != "mail" {
+		t.Errorf("fields = %v, claims = %v", c.Fields, c.Claims)
+	}
+	if len(c.Rules) != 1 || c.Rules[0] != (rule{Field: "phone", KeepLast: 3}) {
+		t.Errorf("rules = %+v", c.Rules)
+	}
+	if c.Endpoint.Url != "http://localhost:8081" || c.Endpoint.Timeout == nil || *c.Endpoint.Timeout != 3*time.Second {
+		t.Errorf("endpoint = %+v", c.Endpoint)
+	}
+	if c.Health == nil || c.Health.Url != "http://localhost:8081/health" {
+		t.Errorf("health = %+v, want the pointer allocated", c.Health)
+	}
+}
+
+func TestOverridePlainWinsOverFile(t *testing.T) {
+	t.Setenv("APP_DATA_SOURCE_NAME", "plain")
+	t.Setenv("APP_DATA_SOURCE_NAME_FILE", filepath.Join(t.TempDir(), "missing"))
+	var c settings
+	if err := Override("app", &c); err != nil || c.Dsn != "plain" {
+		t.Errorf("Override() = %v, Dsn %q", err, c.Dsn)
+	}
+}
+
+func TestOverrideReportsEveryError(t *testing.T) {
+	t.Setenv("APP_PORT", "http")
+	t.Setenv("APP_DEBUG", "maybe")
+	t.Setenv("APP_CLAIMS", "sub")
+	t.Setenv("APP_DATA_SOURCE_NAME_FILE", filepath.Join(t.TempDir(), "missing"))
+	var c settings
+	err := Override("app", &c)
+	if err == nil {
+		t.Fatal("Override() must fail")
+	}
+	for _, name := range []string{"APP_PORT", "APP_DEBUG", "APP_CLAIMS", "APP_DATA_SOURCE_NAME_FILE"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not report %s", err, name)
+		}
+	}
+	if c.Health != nil {
+		t.Errorf("health = %+v, want nil when no variable is set", c.Health)
+	}
+	if err := Override("app", c); err == nil {
+		t.Error("Override() must reject a value which is not a pointer to a struct")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	port := int64(8080)
+	timeout := 3 * time.Second
+	c := settings{
+		Base:     Base{Name: "users"},
+		Port:     &port,
+		Dsn:      "postgres://user:pass@db/users",
+		Claims:   map[string]string{"Password": "x", "sub": "userId"},
+		Endpoint: endpoint{Url: "http://localhost:8081", Timeout: &timeout},
+	}
+	m := Redact(c)
+	if m["name"] != "users" || m["port"] != int64(8080) || m["health"] != nil {
+		t.Errorf("Redact() = %v", m)
+	}
+	if m["data_source_name"] != "postgres://user:xxxxx@db/users" {
+		t.Errorf("data_source_name = %v, want the password of the url redacted", m["data_source_name"])
+	}
+	claims := m["claims"].(map[string]interface{})
+	if claims["password"] != Redacted || claims["sub"] != "userId" {
+		t.Errorf("claims = %v", claims)
+	}
+	if ep := m["endpoint"].(map[string]interface{}); ep["timeout"] != "3s" {
+		t.Errorf("endpoint = %v", ep)
+	}
+}
+
+func TestIsSecret(t *testing.T) {
+	for key, want := range map[string]bool{"password": true, "client_secret": true, "API_KEY": true, "dsn": true, "secret_file": false, "key_files": false, "url": false} {
+		if IsSecret(key) != want {
+			t.Errorf("IsSecret(%q) = %v", key, !want)
+		}
+	}
+}
+
+func TestFiles(t *testing.T) {
+	t.Setenv("APP_SQL_DATA_SOURCE_NAME_FILE", "/run/secrets/dsn")
+	t.Setenv("APP_AUTH_SECRET_FILE", "")
+	t.Setenv("OTHER_TOKEN_FILE", "/run/secrets/token")
+	if files := Files("app"); len(files) != 1 || files[0] != "/run/secrets/dsn" {
+		t.Errorf("Files() = %v", files)
+	}
+}
@@ -0,0 +1,115 @@
+package envconfig
+
+import (
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const Redacted = "******"
+
+var secrets = []string{"password", "secret", "token", "api_key", "apikey", "private_key", "data_source_name", "dsn"}
+
+// Redact converts c to a map keyed by the mapstructure keys, replacing secret values. URLs keep everything but the password.
+func Redact(c interface{}) map[string]interface{} {
+	m, _ := redact(reflect.ValueOf(c), "").(map[string]interface{})
+	return m
+}
+
+func redact(v reflect.Value, key string) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return redact(v.Elem(), key)
+	case reflect.Struct:
+		m := make(map[string]interface{})
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			k, squash := Key(f)
+			if k == "-" {
+				continue
+			}
+			x := redact(v.Field(i), k)
+			if sub, ok := x.(map[string]interface{}); ok && squash {
+				for sk, sv := range sub {
+					m[sk] = sv
+				}
+				continue
+			}
+			m[k] = x
+		}
+		return m
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k := strings.ToLower(iter.Key().String())
+			m[k] = redact(iter.Value(), k)
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = redact(v.Index(i), key)
+		}
+		return items
+	case reflect.String:
+		s := v.String()
+		if len(s) > 0 && IsSecret(key) {
+			if u, err := url.Parse(s); err == nil && u.User != nil && len(u.Host) > 0 {
+				return u.Redacted()
+			}
+			return Redacted
+		}
+		return s
+	default:
+		return v.Interface()
+	}
+}
+
+// IsSecret reports whether the value of key must not be shown. Keys of file paths, such as secret_file, are not secrets.
+func IsSecret(key string) bool {
+	key = strings.ToLower(key)
+	if strings.HasSuffix(key, "_file") || strings.HasSuffix(key, "_files") {
+		return false
+	}
+	for _, s := range secrets {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Files returns the files named by the <PREFIX>_*_FILE variables, so that they can be watched for rotation.
+func Files(prefix string) []string {
+	var files []string
+	prefix = strings.ToUpper(prefix) + "_"
+	for _, e := range os.Environ() {
+		name, value, _ := strings.Cut(e, "=")
+		if strings.HasPrefix(name, prefix) && strings.HasSuffix(name, FileSuffix) && len(value) > 0 {
+			files = append(files, value)
+		}
+	}
+	return files
+}
@@ -0,0 +1,52 @@
+package feature
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"go-service/pkg/problem"
+)
+
+// Flags holds the feature flags of the features section. It is replaced as a whole on config reload.
+type Flags struct {
+	flags atomic.Pointer[map[string]bool]
+}
+
+func NewFlags(flags map[string]bool) *Flags {
+	f := &Flags{}
+	f.Set(flags)
+	return f
+}
+
+func (f *Flags) Set(flags map[string]bool) {
+	m := make(map[string]bool, len(flags))
+	for k, v := range flags {
+		m[strings.ToLower(k)] = v
+	}
+	f.flags.Store(&m)
+}
+func (f *Flags) Enabled(name string) bool {
+	return (*f.flags.Load())[strings.ToLower(name)]
+}
+func (f *Flags) All() map[string]bool {
+	m := *f.flags.Load()
+	all := make(map[string]bool, len(m))
+	for k, v := range m {
+		all[k] = v
+	}
+	return all
+}
+
+// Require responds 404 while the feature is off, so that a route can be released behind a flag.
+func (f *Flags) Require(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !f.Enabled(name) {
+				problem.Error(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package feature
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequire(t *testing.T) {
+	f := NewFlags(map[string]bool{"User_Export": true})
+	h := f.Require("user_export")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	serve := func() int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/export", nil))
+		return w.Code
+	}
+	if code := serve(); code != http.StatusNoContent {
+		t.Errorf("status %d, want the route served while the feature is on", code)
+	}
+	f.Set(map[string]bool{"user_export": false})
+	if code := serve(); code != http.StatusNotFound {
+		t.Errorf("status %d, want 404 once the feature is off", code)
+	}
+	if f.Enabled("unknown") || len(f.All()) != 1 {
+		t.Errorf("flags = %v", f.All())
+	}
+}
@@ -0,0 +1,8 @@
+package lifecycle
+
+import "time"
+
+type Config struct {
+	Delay   time.Duration `yaml:"delay" mapstructure:"delay" json:"delay,omitempty" gorm:"column:delay" bson:"delay,omitempty" dynamodbav:"delay,omitempty" firestore:"delay,omitempty"`
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout" json:"timeout,omitempty" gorm:"column:timeout" bson:"timeout,omitempty" dynamodbav:"timeout,omitempty" firestore:"timeout,omitempty"`
+}
@@ -0,0 +1,41 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+var ErrShuttingDown = errors.New("shutting down")
+
+type Readiness struct {
+	stopping atomic.Bool
+}
+
+func NewReadiness() *Readiness {
+	return &Readiness{}
+}
+
+func (r *Readiness) Shutdown() {
+	r.stopping.Store(true)
+}
+func (r *Readiness) Ready() bool {
+	return !r.stopping.Load()
+}
+
+func (r *Readiness) Name() string {
+	return "server"
+}
+func (r *Readiness) Check(ctx context.Context) (map[string]interface{}, error) {
+	if !r.Ready() {
+		return map[string]interface{}{"state": "stopping"}, ErrShuttingDown
+	}
+	return nil, nil
+}
+func (r *Readiness) Build(ctx context.Context, data map[string]interface{}, err error) map[string]interface{} {
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data["error"] = err.Error()
+	return data
+}
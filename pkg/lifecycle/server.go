@@ -0,0 +1,102 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// ListenAndServe runs the server until it fails or the process receives SIGINT or SIGTERM. A server with a TLSConfig serves TLS with its certificates.
+// On a signal it marks the service not ready, waits c.Delay so load balancers stop routing to it,
+// then drains in-flight requests for up to c.Timeout. A second signal closes all connections immediately.
+// The others, such as the metrics and admin servers, are served by the caller, and shut down with the server, within the same deadline.
+func ListenAndServe(ctx context.Context, server *http.Server, c Config, readiness *Readiness, logInfo func(context.Context, string, ...map[string]interface{}), others ...*http.Server) error {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	serve := server.ListenAndServe
+	if server.TLSConfig != nil {
+		serve = func() error {
+			return server.ListenAndServeTLS("", "")
+		}
+	}
+	return run(ctx, serve, append([]*http.Server{server}, others...), c, readiness, signals, logInfo)
+}
+
+// run serves by serve until it fails or a signal comes, then shuts down the servers, the first being the one of serve.
+func run(ctx context.Context, serve func() error, servers []*http.Server, c Config, readiness *Readiness, signals <-chan os.Signal, logInfo func(context.Context, string, ...map[string]interface{})) error {
+	closeAll := func() {
+		for _, s := range servers {
+			if s != nil {
+				s.Close()
+			}
+		}
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- serve()
+	}()
+	select {
+	case err := <-errs:
+		closeAll()
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case sig := <-signals:
+		logInfo(ctx, "received "+sig.String()+", shutting down")
+	}
+
+	if readiness != nil {
+		readiness.Shutdown()
+	}
+	if c.Delay > 0 {
+		select {
+		case <-time.After(c.Delay):
+		case <-signals:
+			closeAll()
+			return nil
+		}
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	sctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-signals:
+			logInfo(ctx, "received second signal, closing connections")
+			closeAll()
+		case <-sctx.Done():
+		}
+	}()
+	results := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, s := range servers {
+		if s == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, s *http.Server) {
+			defer wg.Done()
+			results[i] = s.Shutdown(sctx)
+		}(i, s)
+	}
+	wg.Wait()
+	err := errors.Join(results...)
+	if errors.Is(err, context.DeadlineExceeded) {
+		closeAll()
+	}
+	logInfo(ctx, "server stopped")
+	return err
+}
@@ -0,0 +1,176 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func logInfo(context.Context, string, ...map[string]interface{}) {}
+
+// start serves handler on a free port, and runs the shutdown of the server and the others on the signals.
+func start(t *testing.T, handler http.Handler, c Config, readiness *Readiness, others ...*http.Server) (string, chan<- os.Signal, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: handler}
+	signals := make(chan os.Signal, 2)
+	done := make(chan error, 1)
+	go func() {
+		done <- run(context.Background(), func() error { return server.Serve(ln) }, append([]*http.Server{server}, others...), c, readiness, signals, logInfo)
+	}()
+	return "http://" + ln.Addr().String(), signals, done
+}
+
+// get sends a request in the background, and returns its status, or 0 when it fails.
+func get(url string) <-chan int {
+	status := make(chan int, 1)
+	go func() {
+		res, err := http.Get(url)
+		if err != nil {
+			status <- 0
+			return
+		}
+		res.Body.Close()
+		status <- res.StatusCode
+	}()
+	return status
+}
+
+func wait(t *testing.T, done <-chan error, within time.Duration) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(within):
+		t.Fatalf("the server did not stop within %v", within)
+		return nil
+	}
+}
+
+// slow answers after d, and tells when a request has come.
+func slow(d time.Duration) (http.Handler, <-chan struct{}) {
+	started := make(chan struct{}, 1)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		select {
+		case <-time.After(d):
+		case <-r.Context().Done():
+		}
+	}), started
+}
+
+func TestDelayKeepsServingWhileNotReady(t *testing.T) {
+	readiness := NewReadiness()
+	url, signals, done := start(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), Config{Delay: 200 * time.Millisecond, Timeout: time.Second}, readiness)
+	if status := <-get(url); status != http.StatusOK || !readiness.Ready() {
+		t.Fatalf("before the signal: status %d, ready %v", status, readiness.Ready())
+	}
+	signals <- syscall.SIGTERM
+	time.Sleep(50 * time.Millisecond)
+	if readiness.Ready() {
+		t.Error("still ready after the signal")
+	}
+	if status := <-get(url); status != http.StatusOK {
+		t.Errorf("during the delay: status %d, want the requests still served", status)
+	}
+	if err := wait(t, done, time.Second); err != nil {
+		t.Errorf("run() = %v", err)
+	}
+	if status := <-get(url); status != 0 {
+		t.Errorf("after the shutdown: status %d, want the connection refused", status)
+	}
+}
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	handler, started := slow(200 * time.Millisecond)
+	url, signals, done := start(t, handler, Config{Timeout: 2 * time.Second}, nil)
+	status := get(url)
+	<-started
+	signals <- syscall.SIGTERM
+	if err := wait(t, done, 2*time.Second); err != nil {
+		t.Errorf("run() = %v", err)
+	}
+	if s := <-status; s != http.StatusOK {
+		t.Errorf("in-flight request: status %d, want 200", s)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	handler, started := slow(10 * time.Second)
+	url, signals, done := start(t, handler, Config{Timeout: 100 * time.Millisecond}, nil)
+	status := get(url)
+	<-started
+	signals <- syscall.SIGTERM
+	if err := wait(t, done, time.Second); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("run() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if s := <-status; s != 0 {
+		t.Errorf("request past the timeout: status %d, want its connection closed", s)
+	}
+}
+
+func TestSecondSignalClosesConnections(t *testing.T) {
+	handler, started := slow(10 * time.Second)
+	url, signals, done := start(t, handler, Config{Timeout: 10 * time.Second}, nil)
+	status := get(url)
+	<-started
+	signals <- syscall.SIGTERM
+	time.Sleep(50 * time.Millisecond)
+	signals <- syscall.SIGINT
+	wait(t, done, time.Second)
+	if s := <-status; s != 0 {
+		t.Errorf("request after the second signal: status %d, want its connection closed", s)
+	}
+
+	_, signals, done = start(t, http.NotFoundHandler(), Config{Delay: 10 * time.Second}, nil)
+	signals <- syscall.SIGTERM
+	signals <- syscall.SIGTERM
+	if err := wait(t, done, time.Second); err != nil {
+		t.Errorf("second signal during the delay: run() = %v", err)
+	}
+}
+
+func TestShutdownDrainsTheOthers(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, started := slow(200 * time.Millisecond)
+	metrics := &http.Server{Handler: handler}
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- metrics.Serve(ln)
+	}()
+	_, signals, done := start(t, http.NotFoundHandler(), Config{Timeout: 2 * time.Second}, nil, metrics, nil)
+	scrape := get("http://" + ln.Addr().String())
+	<-started
+	signals <- syscall.SIGTERM
+	if err := wait(t, done, 2*time.Second); err != nil {
+		t.Errorf("run() = %v", err)
+	}
+	if s := <-scrape; s != http.StatusOK {
+		t.Errorf("in-flight scrape: status %d, want 200", s)
+	}
+	if err := <-stopped; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("metrics server: %v", err)
+	}
+}
+
+func TestServeError(t *testing.T) {
+	failure := errors.New("address already in use")
+	err := run(context.Background(), func() error { return failure }, []*http.Server{{}}, Config{}, nil, make(chan os.Signal), logInfo)
+	if !errors.Is(err, failure) {
+		t.Errorf("run() = %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+package mask
+
+type Config struct {
+	Char       string `yaml:"char" mapstructure:"char" json:"char,omitempty" gorm:"column:char" bson:"char,omitempty" dynamodbav:"char,omitempty" firestore:"char,omitempty"`
+	HashSecret string `yaml:"hash_secret" mapstructure:"hash_secret" json:"hashSecret,omitempty" gorm:"column:hashsecret" bson:"hashSecret,omitempty" dynamodbav:"hashSecret,omitempty" firestore:"hashSecret,omitempty"`
+	Rules      []Rule `yaml:"rules" mapstructure:"rules" json:"rules,omitempty" gorm:"column:rules" bson:"rules,omitempty" dynamodbav:"rules,omitempty" firestore:"rules,omitempty"`
+}
+
+type Rule struct {
+	Field     string `yaml:"field" mapstructure:"field" json:"field,omitempty" gorm:"column:field" bson:"field,omitempty" dynamodbav:"field,omitempty" firestore:"field,omitempty"`
+	Strategy  string `yaml:"strategy" mapstructure:"strategy" json:"strategy,omitempty" gorm:"column:strategy" bson:"strategy,omitempty" dynamodbav:"strategy,omitempty" firestore:"strategy,omitempty"`
+	KeepFirst int    `yaml:"keep_first" mapstructure:"keep_first" json:"keepFirst,omitempty" gorm:"column:keepfirst" bson:"keepFirst,omitempty" dynamodbav:"keepFirst,omitempty" firestore:"keepFirst,omitempty"`
+	KeepLast  int    `yaml:"keep_last" mapstructure:"keep_last" json:"keepLast,omitempty" gorm:"column:keeplast" bson:"keepLast,omitempty" dynamodbav:"keepLast,omitempty" firestore:"keepLast,omitempty"`
+}
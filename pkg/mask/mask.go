@@ -0,0 +1,250 @@
+package mask
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StrategyMask    = "mask"
+	StrategyPartial = "partial"
+	StrategyHash    = "hash"
+	StrategyDrop    = "drop"
+	StrategyEmail   = "email"
+	StrategyDate    = "date"
+)
+
+var dateLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"}
+
+type Masker struct {
+	Char       string
+	HashSecret []byte
+	Rules      []Rule
+}
+
+func NewMasker(c Config) (*Masker, error) {
+	char := c.Char
+	if len(char) == 0 {
+		char = "*"
+	}
+	for _, rule := range c.Rules {
+		if len(rule.Field) == 0 {
+			return nil, errors.New("mask rule must have a field")
+		}
+		switch rule.Strategy {
+		case "", StrategyMask, StrategyPartial, StrategyHash, StrategyDrop, StrategyEmail, StrategyDate:
+		default:
+			return nil, fmt.Errorf("invalid mask strategy %q for field %s", rule.Strategy, rule.Field)
+		}
+		if rule.KeepFirst < 0 || rule.KeepLast < 0 {
+			return nil, fmt.Errorf("keep_first and keep_last of field %s must not be negative", rule.Field)
+		}
+		if rule.Strategy == StrategyHash && len(c.HashSecret) == 0 {
+			return nil, fmt.Errorf("mask.hash_secret is required by the hash strategy of field %s", rule.Field)
+		}
+	}
+	return &Masker{Char: char, HashSecret: []byte(c.HashSecret), Rules: c.Rules}, nil
+}
+
+// Mask applies every rule to obj in place. A field without dots matches that key at any depth;
+// a dotted field such as "user.phone" (optionally prefixed by "$.") is a path from the root, and arrays along the path are walked element by element.
+func (m *Masker) Mask(obj map[string]interface{}) {
+	if m == nil || obj == nil {
+		return
+	}
+	for _, rule := range m.Rules {
+		field := strings.TrimPrefix(rule.Field, "$.")
+		if strings.Contains(field, ".") {
+			m.applyPath(obj, strings.Split(field, "."), rule)
+		} else {
+			m.applyKey(obj, field, rule)
+		}
+	}
+}
+
+// MaskJSON masks a JSON object or array given as a string. An HTTP dump keeps its status line and headers, and only its body is masked.
+// Anything that is not JSON is returned unchanged.
+func (m *Masker) MaskJSON(s string) string {
+	if m == nil || len(m.Rules) == 0 {
+		return s
+	}
+	if strings.HasPrefix(s, "HTTP/") {
+		if i := strings.Index(s, "\r\n\r\n"); i >= 0 {
+			return s[:i+4] + m.MaskJSON(s[i+4:])
+		}
+		return s
+	}
+	trimmed := strings.TrimSpace(s)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return s
+	}
+	var v interface{}
+	decoder := json.NewDecoder(strings.NewReader(trimmed))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return s
+	}
+	m.walk(v)
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return s
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func (m *Masker) walk(v interface{}) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		m.Mask(x)
+	case []interface{}:
+		for _, e := range x {
+			m.walk(e)
+		}
+	}
+}
+
+func (m *Masker) applyKey(v interface{}, key string, rule Rule) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, e := range x {
+			if k == key {
+				m.apply(x, k, rule)
+			} else {
+				m.applyKey(e, key, rule)
+			}
+		}
+	case []interface{}:
+		for _, e := range x {
+			m.applyKey(e, key, rule)
+		}
+	}
+}
+func (m *Masker) applyPath(v interface{}, path []string, rule Rule) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		e, ok := x[path[0]]
+		if !ok {
+			return
+		}
+		if len(path) == 1 {
+			m.apply(x, path[0], rule)
+		} else {
+			m.applyPath(e, path[1:], rule)
+		}
+	case []interface{}:
+		for _, e := range x {
+			m.applyPath(e, path, rule)
+		}
+	}
+}
+func (m *Masker) apply(obj map[string]interface{}, key string, rule Rule) {
+	v := obj[key]
+	if v == nil {
+		return
+	}
+	if rule.Strategy == StrategyDrop {
+		delete(obj, key)
+		return
+	}
+	var s string
+	switch x := v.(type) {
+	case string:
+		s = x
+	case map[string]interface{}, []interface{}:
+		obj[key] = strings.Repeat(m.Char, 3)
+		return
+	default:
+		s = fmt.Sprint(x)
+	}
+	obj[key] = m.String(s, rule)
+}
+
+func (m *Masker) String(s string, rule Rule) string {
+	switch rule.Strategy {
+	case StrategyPartial:
+		return Partial(s, rule.KeepFirst, rule.KeepLast, m.Char)
+	case StrategyHash:
+		return Hash(s, m.HashSecret)
+	case StrategyEmail:
+		return Email(s, rule.KeepFirst, m.Char)
+	case StrategyDate:
+		return Date(s, m.Char)
+	default:
+		return strings.Repeat(m.Char, len([]rune(s)))
+	}
+}
+
+// Hash is "hmac:" and the first 16 hex characters of the HMAC-SHA256 of s, keyed by secret, so that the short values,
+// such as phone numbers or dates of birth, cannot be found again by hashing every candidate.
+func Hash(s string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(s))
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+func Partial(s string, first int, last int, char string) string {
+	r := []rune(s)
+	if first+last >= len(r) {
+		return strings.Repeat(char, len(r))
+	}
+	return string(r[:first]) + strings.Repeat(char, len(r)-first-last) + string(r[len(r)-last:])
+}
+
+// Email keeps the domain and the first characters of the local part: john.doe@gmail.com becomes j*******@gmail.com.
+func Email(s string, first int, char string) string {
+	i := strings.LastIndex(s, "@")
+	if i <= 0 {
+		return Partial(s, 0, 0, char)
+	}
+	if first <= 0 {
+		first = 1
+	}
+	return Partial(s[:i], first, 0, char) + s[i:]
+}
+
+// Date keeps only the year: 1963-03-25 becomes 1963-**-**.
+func Date(s string, char string) string {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return fmt.Sprintf("%04d-%s-%s", t.Year(), strings.Repeat(char, 2), strings.Repeat(char, 2))
+		}
+	}
+	return Partial(s, 0, 0, char)
+}
+
+// Reloadable is a Masker whose rules can be replaced while it is in use.
+type Reloadable struct {
+	masker atomic.Pointer[Masker]
+}
+
+func NewReloadable(c Config) (*Reloadable, error) {
+	r := &Reloadable{}
+	if err := r.Update(c); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+func (r *Reloadable) Update(c Config) error {
+	m, err := NewMasker(c)
+	if err != nil {
+		return err
+	}
+	r.masker.Store(m)
+	return nil
+}
+func (r *Reloadable) Mask(obj map[string]interface{}) {
+	r.masker.Load().Mask(obj)
+}
+func (r *Reloadable) MaskJSON(s string) string {
+	return r.masker.Load().MaskJSON(s)
+}
@@ -0,0 +1,129 @@
+package mask
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	m, err := NewMasker(Config{HashSecret: "s3cr3t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		value string
+		rule  Rule
+		want  string
+	}{
+		{"0987654321", Rule{}, "**********"},
+		{"0987654321", Rule{Strategy: StrategyMask}, "**********"},
+		{"0987654321", Rule{Strategy: StrategyPartial, KeepLast: 3}, "*******321"},
+		{"0987654321", Rule{Strategy: StrategyPartial, KeepFirst: 2, KeepLast: 2}, "09******21"},
+		{"0987", Rule{Strategy: StrategyPartial, KeepFirst: 2, KeepLast: 2}, "****"},
+		{"Nguyễn", Rule{Strategy: StrategyPartial, KeepFirst: 1}, "N*****"},
+		{"tony.stark@gmail.com", Rule{Strategy: StrategyEmail}, "t*********@gmail.com"},
+		{"tony.stark@gmail.com", Rule{Strategy: StrategyEmail, KeepFirst: 4}, "tony******@gmail.com"},
+		{"tony.stark", Rule{Strategy: StrategyEmail}, "**********"},
+		{"@gmail.com", Rule{Strategy: StrategyEmail}, "**********"},
+		{"1963-03-25", Rule{Strategy: StrategyDate}, "1963-**-**"},
+		{"1963-03-25T00:00:00+07:00", Rule{Strategy: StrategyDate}, "1963-**-**"},
+		{"1963-03-25T10:20:30", Rule{Strategy: StrategyDate}, "1963-**-**"},
+		{"25/03/1963", Rule{Strategy: StrategyDate}, "**********"},
+	}
+	for _, tt := range tests {
+		if got := m.String(tt.value, tt.rule); got != tt.want {
+			t.Errorf("String(%q, %+v) = %q, want %q", tt.value, tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	a := Hash("0987654321", []byte("s3cr3t"))
+	if !strings.HasPrefix(a, "hmac:") || len(a) != len("hmac:")+16 {
+		t.Errorf("Hash() = %q", a)
+	}
+	if a != Hash("0987654321", []byte("s3cr3t")) {
+		t.Error("Hash() must be stable, so that the logs of a value can be joined")
+	}
+	if a == Hash("0987654321", []byte("other")) || a == Hash("0987654322", []byte("s3cr3t")) {
+		t.Error("Hash() must depend on the secret and the value")
+	}
+}
+
+func TestNewMasker(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Config
+	}{
+		{"no field", Config{Rules: []Rule{{Strategy: StrategyMask}}}},
+		{"unknown strategy", Config{Rules: []Rule{{Field: "phone", Strategy: "encrypt"}}}},
+		{"negative keep", Config{Rules: []Rule{{Field: "phone", Strategy: StrategyPartial, KeepLast: -1}}}},
+		{"hash without secret", Config{Rules: []Rule{{Field: "phone", Strategy: StrategyHash}}}},
+	}
+	for _, tt := range tests {
+		if _, err := NewMasker(tt.c); err == nil {
+			t.Errorf("%s: NewMasker() must fail", tt.name)
+		}
+	}
+	m, err := NewMasker(Config{})
+	if err != nil || m.Char != "*" {
+		t.Errorf("NewMasker() = %+v, %v", m, err)
+	}
+}
+
+func TestMaskJSON(t *testing.T) {
+	m, err := NewMasker(Config{HashSecret: "s3cr3t", Rules: []Rule{
+		{Field: "phone", Strategy: StrategyPartial, KeepLast: 3},
+		{Field: "password", Strategy: StrategyDrop},
+		{Field: "$.user.email", Strategy: StrategyEmail},
+		{Field: "contacts.id", Strategy: StrategyHash},
+		{Field: "address"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := Hash("42", []byte("s3cr3t"))
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"key at any depth", `{"phone":"0987654321","user":{"phone":"0123456789"}}`, `{"phone":"*******321","user":{"phone":"*******789"}}`},
+		{"drop", `{"id":"ironman","password":"jarvis"}`, `{"id":"ironman"}`},
+		{"path from the root", `{"email":"tony@stark.com","user":{"email":"tony@stark.com"}}`, `{"email":"tony@stark.com","user":{"email":"t***@stark.com"}}`},
+		{"path through an array", `{"contacts":[{"id":42},{"id":"42"}],"id":42}`, `{"contacts":[{"id":"` + id + `"},{"id":"` + id + `"}],"id":42}`},
+		{"object value", `{"address":{"city":"New York"}}`, `{"address":"***"}`},
+		{"null value", `{"phone":null}`, `{"phone":null}`},
+		{"array", `[{"phone":"0987654321"}]`, `[{"phone":"*******321"}]`},
+		{"not escaped", `{"phone":"<0987654321>"}`, `{"phone":"*********21>"}`},
+		{"not JSON", `phone=0987654321`, `phone=0987654321`},
+		{"invalid JSON", `{"phone":"0987`, `{"phone":"0987`},
+		{"http dump", "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\n\r\n{\"phone\":\"0987654321\"}", "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\n\r\n{\"phone\":\"*******321\"}"},
+	}
+	for _, tt := range tests {
+		if got := m.MaskJSON(tt.in); got != tt.want {
+			t.Errorf("%s: MaskJSON() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReloadable(t *testing.T) {
+	r, err := NewReloadable(Config{Rules: []Rule{{Field: "phone"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := map[string]interface{}{"phone": "0987654321"}
+	r.Mask(obj)
+	if obj["phone"] != "**********" {
+		t.Errorf("Mask() = %v", obj)
+	}
+	if err = r.Update(Config{Rules: []Rule{{Field: "phone", Strategy: "encrypt"}}}); err == nil {
+		t.Error("Update() must reject an invalid config")
+	}
+	if err = r.Update(Config{Char: "#", Rules: []Rule{{Field: "phone", Strategy: StrategyPartial, KeepLast: 2}}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.MaskJSON(`{"phone":"0987654321"}`); got != `{"phone":"########21"}` {
+		t.Errorf("after Update: MaskJSON() = %s", got)
+	}
+}
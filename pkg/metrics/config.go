@@ -0,0 +1,10 @@
+package metrics
+
+type Config struct {
+	Enabled   bool   `yaml:"enabled" mapstructure:"enabled" json:"enabled,omitempty" gorm:"column:enabled" bson:"enabled,omitempty" dynamodbav:"enabled,omitempty" firestore:"enabled,omitempty"`
+	Path      string `yaml:"path" mapstructure:"path" json:"path,omitempty" gorm:"column:path" bson:"path,omitempty" dynamodbav:"path,omitempty" firestore:"path,omitempty"`
+	Host      string `yaml:"host" mapstructure:"host" json:"host,omitempty" gorm:"column:host" bson:"host,omitempty" dynamodbav:"host,omitempty" firestore:"host,omitempty"`
+	Port      *int64 `yaml:"port" mapstructure:"port" json:"port,omitempty" gorm:"column:port" bson:"port,omitempty" dynamodbav:"port,omitempty" firestore:"port,omitempty"`
+	Namespace string `yaml:"namespace" mapstructure:"namespace" json:"namespace,omitempty" gorm:"column:namespace" bson:"namespace,omitempty" dynamodbav:"namespace,omitempty" firestore:"namespace,omitempty"`
+	DB        string `yaml:"db" mapstructure:"db" json:"db,omitempty" gorm:"column:db" bson:"db,omitempty" dynamodbav:"db,omitempty" firestore:"db,omitempty"`
+}
@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type Metrics struct {
+	Config   Config
+	Registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	queries  *prometheus.HistogramVec
+	outbound *prometheus.HistogramVec
+	circuits *prometheus.GaugeVec
+	changes  *prometheus.CounterVec
+}
+
+func NewMetrics(c Config, db *sql.DB) (*Metrics, error) {
+	if len(c.Path) == 0 {
+		c.Path = "/metrics"
+	}
+	if len(c.DB) == 0 {
+		c.DB = "main"
+	}
+	m := &Metrics{
+		Config:   c,
+		Registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.Namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route template, method and status.",
+		}, []string{"route", "method", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: c.Namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route template, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		queries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: c.Namespace,
+			Name:      "repository_query_duration_seconds",
+			Help:      "Repository method latency by repository, method and result.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"repository", "method", "result"}),
+		outbound: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: c.Namespace,
+			Name:      "http_client_request_duration_seconds",
+			Help:      "Outbound HTTP call latency by host, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"host", "method", "status"}),
+		circuits: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: c.Namespace,
+			Name:      "http_client_circuit_state",
+			Help:      "State of the circuit breaker by host: 0 closed, 1 half open, 2 open.",
+		}, []string{"host"}),
+		changes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.Namespace,
+			Name:      "http_client_circuit_changes_total",
+			Help:      "State changes of the circuit breaker by host and new state.",
+		}, []string{"host", "state"}),
+	}
+	cs := []prometheus.Collector{
+		m.requests,
+		m.duration,
+		m.queries,
+		m.outbound,
+		m.circuits,
+		m.changes,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	}
+	if db != nil {
+		cs = append(cs, collectors.NewDBStatsCollector(db, c.DB))
+	}
+	for _, collector := range cs {
+		if err := m.Registry.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+// Server returns a server for the metrics path on its own port, listening on 127.0.0.1 unless a host is configured, or nil when metrics share the main port.
+func (m *Metrics) Server() *http.Server {
+	if m.Config.Port == nil {
+		return nil
+	}
+	host := m.Config.Host
+	if len(host) == 0 {
+		host = "127.0.0.1"
+	}
+	r := http.NewServeMux()
+	r.Handle(m.Config.Path, m.Handler())
+	return &http.Server{Addr: host + ":" + strconv.FormatInt(*m.Config.Port, 10), Handler: r, ReadHeaderTimeout: 5 * time.Second}
+}
+
+// Handle records every request matched by the router, labelled by its mux path template rather than the raw path.
+func (m *Metrics) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		if route == m.Config.Path {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		rw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+		status := strconv.Itoa(rw.status)
+		m.requests.WithLabelValues(route, r.Method, status).Inc()
+		m.duration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+func (m *Metrics) ObserveQuery(repository string, method string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.queries.WithLabelValues(repository, method, result).Observe(time.Since(start).Seconds())
+}
+
+func (m *Metrics) ObserveCall(method string, host string, status int, duration time.Duration) {
+	s := "error"
+	if status > 0 {
+		s = strconv.Itoa(status)
+	}
+	m.outbound.WithLabelValues(host, method, s).Observe(duration.Seconds())
+}
+
+var circuitStates = map[string]float64{"closed": 0, "half_open": 1, "open": 2}
+
+func (m *Metrics) ObserveCircuit(host string, from string, to string) {
+	m.circuits.WithLabelValues(host).Set(circuitStates[to])
+	m.changes.WithLabelValues(host, to).Inc()
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func scrape(t *testing.T, h http.Handler) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("scrape: status %d", w.Code)
+	}
+	b, _ := io.ReadAll(w.Body)
+	return string(b)
+}
+
+func TestHandler(t *testing.T) {
+	m, err := NewMetrics(Config{Namespace: "go_service"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := mux.NewRouter()
+	r.Use(m.Handle)
+	r.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "hulk" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}).Methods(http.MethodGet)
+	for _, path := range []string{"/users/ironman", "/users/spiderman", "/users/hulk"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	m.ObserveQuery("user", "Load", time.Now(), nil)
+	m.ObserveQuery("user", "Load", time.Now(), errors.New("connection refused"))
+	m.ObserveCall(http.MethodGet, "users.internal", http.StatusOK, 10*time.Millisecond)
+	m.ObserveCall(http.MethodGet, "users.internal", 0, time.Second)
+	m.ObserveCircuit("users.internal", "closed", "open")
+
+	body := scrape(t, m.Handler())
+	for _, line := range []string{
+		`go_service_http_requests_total{method="GET",route="/users/{id}",status="200"} 2`,
+		`go_service_http_requests_total{method="GET",route="/users/{id}",status="404"} 1`,
+		`go_service_http_request_duration_seconds_count{method="GET",route="/users/{id}",status="200"} 2`,
+		`go_service_repository_query_duration_seconds_count{method="Load",repository="user",result="ok"} 1`,
+		`go_service_repository_query_duration_seconds_count{method="Load",repository="user",result="error"} 1`,
+		`go_service_http_client_request_duration_seconds_count{host="users.internal",method="GET",status="200"} 1`,
+		`go_service_http_client_request_duration_seconds_count{host="users.internal",method="GET",status="error"} 1`,
+		`go_service_http_client_circuit_state{host="users.internal"} 2`,
+		`go_service_http_client_circuit_changes_total{host="users.internal",state="open"} 1`,
+		`go_goroutines `,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("the metrics have no %s", line)
+		}
+	}
+	if strings.Contains(body, "/users/ironman") {
+		t.Error("the route must be labelled by its template, not by its path")
+	}
+}
+
+func TestServer(t *testing.T) {
+	m, err := NewMetrics(Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Server() != nil {
+		t.Error("Server() must be nil when the metrics share the main port")
+	}
+	port := int64(9090)
+	m.Config.Port = &port
+	s := m.Server()
+	if s == nil || s.Addr != "127.0.0.1:9090" {
+		t.Fatalf("Server() = %+v, want it on the loopback by default", s)
+	}
+	if body := scrape(t, s.Handler); !strings.Contains(body, "go_goroutines ") {
+		t.Errorf("the server does not serve %s", m.Config.Path)
+	}
+	m.Config.Host = "0.0.0.0"
+	if s = m.Server(); s.Addr != "0.0.0.0:9090" {
+		t.Errorf("Addr = %q", s.Addr)
+	}
+}
@@ -0,0 +1,318 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const ContentTypeJSONPatch = "application/json-patch+json"
+
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+	OpMove    = "move"
+	OpCopy    = "copy"
+	OpTest    = "test"
+)
+
+var (
+	ErrInvalidOperation = errors.New("invalid patch operation")
+	ErrInvalidPointer   = errors.New("invalid json pointer")
+	ErrPathNotFound     = errors.New("path not found")
+	ErrTestFailed       = errors.New("test operation failed")
+)
+
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type OperationError struct {
+	Index int
+	Op    string
+	Path  string
+	Err   error
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("operation %d (%s %s): %s", e.Index, e.Op, e.Path, e.Err.Error())
+}
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+func Validate(ops []Operation) error {
+	for i, op := range ops {
+		if err := validate(op); err != nil {
+			return &OperationError{Index: i, Op: op.Op, Path: op.Path, Err: err}
+		}
+	}
+	return nil
+}
+func validate(op Operation) error {
+	if _, err := ParsePointer(op.Path); err != nil {
+		return err
+	}
+	switch op.Op {
+	case OpAdd, OpReplace, OpTest:
+		if len(op.Value) == 0 {
+			return ErrInvalidOperation
+		}
+	case OpMove, OpCopy:
+		if _, err := ParsePointer(op.From); err != nil {
+			return err
+		}
+	case OpRemove:
+	default:
+		return ErrInvalidOperation
+	}
+	return nil
+}
+
+func Apply(doc interface{}, ops []Operation) (interface{}, error) {
+	if err := Validate(ops); err != nil {
+		return nil, err
+	}
+	res := deepCopy(doc)
+	for i, op := range ops {
+		var err error
+		res, err = apply(res, op)
+		if err != nil {
+			return nil, &OperationError{Index: i, Op: op.Op, Path: op.Path, Err: err}
+		}
+	}
+	return res, nil
+}
+func apply(doc interface{}, op Operation) (interface{}, error) {
+	path, _ := ParsePointer(op.Path)
+	switch op.Op {
+	case OpAdd:
+		var value interface{}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, ErrInvalidOperation
+		}
+		return add(doc, path, value)
+	case OpRemove:
+		return remove(doc, path)
+	case OpReplace:
+		var value interface{}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, ErrInvalidOperation
+		}
+		return replace(doc, path, value)
+	case OpTest:
+		var value interface{}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, ErrInvalidOperation
+		}
+		v, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(v, value) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	case OpMove:
+		from, _ := ParsePointer(op.From)
+		if isPrefix(from, path) && len(from) < len(path) {
+			return nil, ErrInvalidOperation
+		}
+		v, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		doc, err = remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, v)
+	case OpCopy:
+		from, _ := ParsePointer(op.From)
+		v, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, deepCopy(v))
+	default:
+		return nil, ErrInvalidOperation
+	}
+}
+
+func ParsePointer(pointer string) ([]string, error) {
+	if len(pointer) == 0 {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, ErrInvalidPointer
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+func isPrefix(prefix []string, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func get(doc interface{}, path []string) (interface{}, error) {
+	c := doc
+	for _, key := range path {
+		switch v := c.(type) {
+		case map[string]interface{}:
+			x, ok := v[key]
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+			c = x
+		case []interface{}:
+			i, err := index(key, len(v))
+			if err != nil {
+				return nil, err
+			}
+			c = v[i]
+		default:
+			return nil, ErrPathNotFound
+		}
+	}
+	return c, nil
+}
+func set(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	key := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[key] = value
+		return doc, nil
+	case []interface{}:
+		i, err := index(key, len(p))
+		if err != nil {
+			return nil, err
+		}
+		p[i] = value
+		return doc, nil
+	default:
+		return nil, ErrPathNotFound
+	}
+}
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parentPath := path[:len(path)-1]
+	parent, err := get(doc, parentPath)
+	if err != nil {
+		return nil, err
+	}
+	key := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[key] = value
+		return doc, nil
+	case []interface{}:
+		i := len(p)
+		if key != "-" {
+			i, err = index(key, len(p)+1)
+			if err != nil {
+				return nil, err
+			}
+		}
+		s := make([]interface{}, 0, len(p)+1)
+		s = append(s, p[:i]...)
+		s = append(s, value)
+		s = append(s, p[i:]...)
+		return set(doc, parentPath, s)
+	default:
+		return nil, ErrPathNotFound
+	}
+}
+func remove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, ErrInvalidOperation
+	}
+	parentPath := path[:len(path)-1]
+	parent, err := get(doc, parentPath)
+	if err != nil {
+		return nil, err
+	}
+	key := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := p[key]; !ok {
+			return nil, ErrPathNotFound
+		}
+		delete(p, key)
+		return doc, nil
+	case []interface{}:
+		i, err := index(key, len(p))
+		if err != nil {
+			return nil, err
+		}
+		s := make([]interface{}, 0, len(p)-1)
+		s = append(s, p[:i]...)
+		s = append(s, p[i+1:]...)
+		return set(doc, parentPath, s)
+	default:
+		return nil, ErrPathNotFound
+	}
+}
+func replace(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if _, err := get(doc, path); err != nil {
+		return nil, err
+	}
+	return set(doc, path, value)
+}
+func index(key string, length int) (int, error) {
+	if len(key) == 0 || (len(key) > 1 && key[0] == '0') {
+		return -1, ErrInvalidPointer
+	}
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 {
+		return -1, ErrInvalidPointer
+	}
+	if i >= length {
+		return -1, ErrPathNotFound
+	}
+	return i, nil
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			c[k] = deepCopy(e)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(x))
+		for i, e := range x {
+			c[i] = deepCopy(e)
+		}
+		return c
+	default:
+		return v
+	}
+}
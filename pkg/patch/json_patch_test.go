@@ -0,0 +1,140 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("invalid json %s: %v", s, err)
+	}
+	return v
+}
+
+// The cases are those of RFC 6902, appendix A, and a few more.
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		err   error
+	}{
+		{"add object member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`, nil},
+		{"add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`, nil},
+		{"remove object member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`, nil},
+		{"remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`, nil},
+		{"replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`, nil},
+		{"move value", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`, nil},
+		{"move array element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`, nil},
+		{"test success", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`, nil},
+		{"test failure", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, ``, ErrTestFailed},
+		{"add nested member", `{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`, nil},
+		{"ignore unknown member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, `{"foo":"bar","baz":"qux"}`, nil},
+		{"add to nonexistent target", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, ``, ErrPathNotFound},
+		{"escape ordering", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`, nil},
+		{"escape slash", `{"/":9,"~1":10}`, `[{"op":"replace","path":"/~1","value":1}]`, `{"/":1,"~1":10}`, nil},
+		{"compare strings and numbers", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":"10"}]`, ``, ErrTestFailed},
+		{"add array value", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`, nil},
+		{"add null", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":null}]`, `{"foo":"bar","baz":null}`, nil},
+		{"test null", `{"foo":null}`, `[{"op":"test","path":"/foo","value":null}]`, `{"foo":null}`, nil},
+		{"replace root", `{"foo":"bar"}`, `[{"op":"replace","path":"","value":{"baz":1}}]`, `{"baz":1}`, nil},
+		{"copy", `{"foo":{"bar":1}}`, `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`, `{"foo":{"bar":1},"baz":{"bar":2}}`, nil},
+		{"add past end", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/2","value":1}]`, ``, ErrPathNotFound},
+		{"leading zero index", `{"foo":["bar","baz"]}`, `[{"op":"remove","path":"/foo/01"}]`, ``, ErrInvalidPointer},
+		{"negative index", `{"foo":["bar"]}`, `[{"op":"replace","path":"/foo/-1","value":1}]`, ``, ErrInvalidPointer},
+		{"remove missing", `{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, ``, ErrPathNotFound},
+		{"remove root", `{"foo":"bar"}`, `[{"op":"remove","path":""}]`, ``, ErrInvalidOperation},
+		{"replace missing", `{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`, ``, ErrPathNotFound},
+		{"move into child", `{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`, ``, ErrInvalidOperation},
+		{"pointer without slash", `{"foo":"bar"}`, `[{"op":"remove","path":"foo"}]`, ``, ErrInvalidPointer},
+		{"missing value", `{"foo":"bar"}`, `[{"op":"add","path":"/baz"}]`, ``, ErrInvalidOperation},
+		{"unknown op", `{"foo":"bar"}`, `[{"op":"merge","path":"/foo","value":1}]`, ``, ErrInvalidOperation},
+	}
+	for _, tt := range tests {
+		var ops []Operation
+		if err := json.Unmarshal([]byte(tt.patch), &ops); err != nil {
+			t.Fatalf("%s: invalid patch: %v", tt.name, err)
+		}
+		doc := decode(t, tt.doc)
+		got, err := Apply(doc, ops)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s: Apply() error = %v, want %v", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Apply() error = %v", tt.name, err)
+			continue
+		}
+		if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Apply() = %v, want %v", tt.name, got, want)
+		}
+		if original := decode(t, tt.doc); !reflect.DeepEqual(doc, original) {
+			t.Errorf("%s: Apply() changed the document to %v", tt.name, doc)
+		}
+	}
+}
+
+func TestApplyIsAtomic(t *testing.T) {
+	doc := decode(t, `{"foo":["bar"]}`)
+	ops := []Operation{
+		{Op: OpAdd, Path: "/foo/-", Value: json.RawMessage(`"baz"`)},
+		{Op: OpTest, Path: "/foo/0", Value: json.RawMessage(`"qux"`)},
+	}
+	_, err := Apply(doc, ops)
+	var opErr *OperationError
+	if !errors.As(err, &opErr) || opErr.Index != 1 || opErr.Op != OpTest {
+		t.Fatalf("Apply() error = %v, want an OperationError at 1", err)
+	}
+	if want := decode(t, `{"foo":["bar"]}`); !reflect.DeepEqual(doc, want) {
+		t.Errorf("document = %v after a failed patch, want %v", doc, want)
+	}
+}
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		pointer string
+		want    []string
+		err     error
+	}{
+		{"", []string{}, nil},
+		{"/", []string{""}, nil},
+		{"/foo/0", []string{"foo", "0"}, nil},
+		{"/a~1b/m~0n", []string{"a/b", "m~n"}, nil},
+		{"/~01", []string{"~1"}, nil},
+		{"foo", nil, ErrInvalidPointer},
+	}
+	for _, tt := range tests {
+		got, err := ParsePointer(tt.pointer)
+		if !errors.Is(err, tt.err) || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParsePointer(%q) = %q, %v; want %q, %v", tt.pointer, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		op  Operation
+		err error
+	}{
+		{Operation{Op: OpRemove, Path: "/foo"}, nil},
+		{Operation{Op: OpMove, Path: "/foo", From: "/bar"}, nil},
+		{Operation{Op: OpCopy, Path: "/foo", From: "bar"}, ErrInvalidPointer},
+		{Operation{Op: OpReplace, Path: "/foo"}, ErrInvalidOperation},
+		{Operation{Op: OpTest, Path: "/foo"}, ErrInvalidOperation},
+		{Operation{Op: "", Path: "/foo"}, ErrInvalidOperation},
+	}
+	for _, tt := range tests {
+		if err := Validate([]Operation{tt.op}); !errors.Is(err, tt.err) {
+			t.Errorf("Validate(%+v) = %v, want %v", tt.op, err, tt.err)
+		}
+	}
+}
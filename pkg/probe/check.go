@@ -0,0 +1,83 @@
+package probe
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/core-go/health"
+)
+
+type Result struct {
+	Status  string                 `json:"status"`
+	Latency int64                  `json:"latencyMs"`
+	Cached  bool                   `json:"cached,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+type Check struct {
+	Checker health.Checker
+	Timeout time.Duration
+	Cache   time.Duration
+	mu      sync.Mutex
+	last    *Result
+	checked time.Time
+}
+
+func NewCheck(checker health.Checker, timeout time.Duration, cache time.Duration) *Check {
+	return &Check{Checker: checker, Timeout: timeout, Cache: cache}
+}
+
+func (c *Check) Name() string {
+	return c.Checker.Name()
+}
+
+func (c *Check) Run(ctx context.Context) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.last != nil && c.Cache > 0 && now.Sub(c.checked) < c.Cache {
+		res := *c.last
+		res.Cached = true
+		return res
+	}
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	data, err := c.check(ctx)
+	res := Result{Status: health.StatusUp, Latency: time.Since(now).Milliseconds(), Data: data}
+	if err != nil {
+		res.Status = health.StatusDown
+		res.Data = c.Checker.Build(ctx, data, err)
+		if res.Data == nil {
+			res.Data = map[string]interface{}{"error": err.Error()}
+		}
+	}
+	if len(res.Data) == 0 {
+		res.Data = nil
+	}
+	c.last = &res
+	c.checked = now
+	return res
+}
+
+// check returns when the checker does or when ctx expires, so a checker that ignores ctx cannot hold up the probe.
+func (c *Check) check(ctx context.Context) (map[string]interface{}, error) {
+	type outcome struct {
+		data map[string]interface{}
+		err  error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		data, err := c.Checker.Check(ctx)
+		done <- outcome{data, err}
+	}()
+	select {
+	case o := <-done:
+		return o.data, o.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,83 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/core-go/health"
+)
+
+// checker counts its checks, and takes delay to answer, ignoring the context when stubborn.
+type checker struct {
+	name     string
+	delay    time.Duration
+	stubborn bool
+	err      error
+	calls    atomic.Int32
+}
+
+func (c *checker) Name() string {
+	return c.name
+}
+func (c *checker) Check(ctx context.Context) (map[string]interface{}, error) {
+	c.calls.Add(1)
+	if c.delay > 0 {
+		if c.stubborn {
+			time.Sleep(c.delay)
+		} else {
+			select {
+			case <-time.After(c.delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+	return map[string]interface{}{"calls": c.calls.Load()}, c.err
+}
+func (c *checker) Build(ctx context.Context, data map[string]interface{}, err error) map[string]interface{} {
+	return nil
+}
+
+func TestCheckTimeout(t *testing.T) {
+	for _, stubborn := range []bool{false, true} {
+		c := NewCheck(&checker{name: "slow", delay: time.Second, stubborn: stubborn}, 20*time.Millisecond, 0)
+		start := time.Now()
+		res := c.Run(context.Background())
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("stubborn %v: Run() took %v, want about the timeout", stubborn, elapsed)
+		}
+		if res.Status != health.StatusDown || res.Data["error"] != context.DeadlineExceeded.Error() {
+			t.Errorf("stubborn %v: Run() = %+v", stubborn, res)
+		}
+	}
+}
+
+func TestCheckCache(t *testing.T) {
+	ck := &checker{name: "db", err: errors.New("connection refused")}
+	c := NewCheck(ck, 0, time.Hour)
+	first := c.Run(context.Background())
+	second := c.Run(context.Background())
+	if ck.calls.Load() != 1 {
+		t.Errorf("checked %d times, want 1 within the cache duration", ck.calls.Load())
+	}
+	if first.Cached || !second.Cached || second.Status != health.StatusDown || second.Data["error"] != "connection refused" {
+		t.Errorf("first %+v, second %+v", first, second)
+	}
+
+	c.checked = c.checked.Add(-2 * time.Hour)
+	ck.err = nil
+	if res := c.Run(context.Background()); res.Cached || res.Status != health.StatusUp || ck.calls.Load() != 2 {
+		t.Errorf("after the cache duration: %+v, checked %d times", res, ck.calls.Load())
+	}
+
+	ck = &checker{name: "db"}
+	c = NewCheck(ck, 0, 0)
+	c.Run(context.Background())
+	c.Run(context.Background())
+	if ck.calls.Load() != 2 {
+		t.Errorf("checked %d times without cache, want 2", ck.calls.Load())
+	}
+}
@@ -0,0 +1,162 @@
+package probe
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"go-service/pkg/client"
+)
+
+type PoolChecker struct {
+	DB       *sql.DB
+	MaxInUse float64
+}
+
+func NewPoolChecker(db *sql.DB, maxInUse float64) *PoolChecker {
+	return &PoolChecker{DB: db, MaxInUse: maxInUse}
+}
+
+func (c *PoolChecker) Name() string {
+	return "sql_pool"
+}
+func (c *PoolChecker) Check(ctx context.Context) (map[string]interface{}, error) {
+	stats := c.DB.Stats()
+	data := map[string]interface{}{
+		"open":      stats.OpenConnections,
+		"inUse":     stats.InUse,
+		"idle":      stats.Idle,
+		"maxOpen":   stats.MaxOpenConnections,
+		"waitCount": stats.WaitCount,
+	}
+	if stats.MaxOpenConnections > 0 && c.MaxInUse > 0 {
+		usage := float64(stats.InUse) / float64(stats.MaxOpenConnections)
+		if usage >= c.MaxInUse {
+			return data, fmt.Errorf("pool saturated: %d of %d connections in use", stats.InUse, stats.MaxOpenConnections)
+		}
+	}
+	return data, nil
+}
+func (c *PoolChecker) Build(ctx context.Context, data map[string]interface{}, err error) map[string]interface{} {
+	return build(data, err)
+}
+
+type MigrationChecker struct {
+	DB      *sql.DB
+	Query   string
+	Version string
+}
+
+func NewMigrationChecker(db *sql.DB, c MigrationConfig) *MigrationChecker {
+	column := c.Column
+	if len(column) == 0 {
+		column = "version"
+	}
+	query := fmt.Sprintf("select %s from %s order by %s desc limit 1", column, c.Table, column)
+	return &MigrationChecker{DB: db, Query: query, Version: c.Version}
+}
+
+func (c *MigrationChecker) Name() string {
+	return "migration"
+}
+func (c *MigrationChecker) Check(ctx context.Context) (map[string]interface{}, error) {
+	var version string
+	if err := c.DB.QueryRowContext(ctx, c.Query).Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return map[string]interface{}{"expected": c.Version}, errors.New("no migration applied")
+		}
+		return nil, err
+	}
+	data := map[string]interface{}{"expected": c.Version, "actual": version}
+	if version != c.Version {
+		return data, errors.New("migration version mismatch")
+	}
+	return data, nil
+}
+func (c *MigrationChecker) Build(ctx context.Context, data map[string]interface{}, err error) map[string]interface{} {
+	return build(data, err)
+}
+
+type HttpChecker struct {
+	Service string
+	Url     string
+	Client  *http.Client
+	Header  map[string]string
+}
+
+func NewHttpChecker(name string, url string, client *http.Client, header map[string]string) *HttpChecker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HttpChecker{Service: name, Url: url, Client: client, Header: header}
+}
+
+func (c *HttpChecker) Name() string {
+	return c.Service
+}
+func (c *HttpChecker) Check(ctx context.Context) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.Header {
+		req.Header.Set(k, v)
+	}
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	data := map[string]interface{}{"status": res.StatusCode}
+	if res.StatusCode >= http.StatusInternalServerError {
+		return data, fmt.Errorf("%s returned %d", c.Url, res.StatusCode)
+	}
+	return data, nil
+}
+func (c *HttpChecker) Build(ctx context.Context, data map[string]interface{}, err error) map[string]interface{} {
+	return build(data, err)
+}
+
+// CircuitChecker reports the state of the circuit breaker of every host, and the hosts whose circuit is open.
+// It never fails: an open circuit is a downstream outage, and taking every replica out of service would not end it.
+type CircuitChecker struct {
+	Service string
+	States  func() map[string]string
+}
+
+func NewCircuitChecker(name string, states func() map[string]string) *CircuitChecker {
+	return &CircuitChecker{Service: name, States: states}
+}
+
+func (c *CircuitChecker) Name() string {
+	return c.Service
+}
+func (c *CircuitChecker) Check(ctx context.Context) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	var open []string
+	for host, state := range c.States() {
+		data[host] = state
+		if state == client.StateOpen {
+			open = append(open, host)
+		}
+	}
+	if len(open) > 0 {
+		sort.Strings(open)
+		data["open"] = open
+	}
+	return data, nil
+}
+func (c *CircuitChecker) Build(ctx context.Context, data map[string]interface{}, err error) map[string]interface{} {
+	return build(data, err)
+}
+
+func build(data map[string]interface{}, err error) map[string]interface{} {
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data["error"] = err.Error()
+	return data
+}
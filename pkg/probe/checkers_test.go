@@ -0,0 +1,22 @@
+package probe
+
+import (
+	"context"
+	"testing"
+
+	"go-service/pkg/client"
+)
+
+func TestCircuitCheckerIsInformational(t *testing.T) {
+	c := NewCircuitChecker("circuit", func() map[string]string {
+		return map[string]string{"b.internal": client.StateOpen, "a.internal": client.StateOpen, "c.internal": client.StateClosed}
+	})
+	data, err := c.Check(context.Background())
+	if err != nil {
+		t.Errorf("Check() = %v, want no error while a circuit is open", err)
+	}
+	open, _ := data["open"].([]string)
+	if len(open) != 2 || open[0] != "a.internal" || open[1] != "b.internal" || data["c.internal"] != client.StateClosed {
+		t.Errorf("data = %v", data)
+	}
+}
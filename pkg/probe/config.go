@@ -0,0 +1,16 @@
+package probe
+
+import "time"
+
+type Config struct {
+	Timeout   time.Duration   `yaml:"timeout" mapstructure:"timeout" json:"timeout,omitempty" gorm:"column:timeout" bson:"timeout,omitempty" dynamodbav:"timeout,omitempty" firestore:"timeout,omitempty"`
+	Cache     time.Duration   `yaml:"cache" mapstructure:"cache" json:"cache,omitempty" gorm:"column:cache" bson:"cache,omitempty" dynamodbav:"cache,omitempty" firestore:"cache,omitempty"`
+	MaxInUse  float64         `yaml:"max_in_use" mapstructure:"max_in_use" json:"maxInUse,omitempty" gorm:"column:maxinuse" bson:"maxInUse,omitempty" dynamodbav:"maxInUse,omitempty" firestore:"maxInUse,omitempty"`
+	Migration MigrationConfig `yaml:"migration" mapstructure:"migration" json:"migration,omitempty" gorm:"column:migration" bson:"migration,omitempty" dynamodbav:"migration,omitempty" firestore:"migration,omitempty"`
+}
+
+type MigrationConfig struct {
+	Table   string `yaml:"table" mapstructure:"table" json:"table,omitempty" gorm:"column:table" bson:"table,omitempty" dynamodbav:"table,omitempty" firestore:"table,omitempty"`
+	Column  string `yaml:"column" mapstructure:"column" json:"column,omitempty" gorm:"column:column" bson:"column,omitempty" dynamodbav:"column,omitempty" firestore:"column,omitempty"`
+	Version string `yaml:"version" mapstructure:"version" json:"version,omitempty" gorm:"column:version" bson:"version,omitempty" dynamodbav:"version,omitempty" firestore:"version,omitempty"`
+}
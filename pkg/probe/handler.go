@@ -0,0 +1,62 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/core-go/health"
+)
+
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+type Handler struct {
+	Checks []*Check
+}
+
+func NewHandler(checks ...*Check) *Handler {
+	return &Handler{Checks: checks}
+}
+
+func (h *Handler) Live(w http.ResponseWriter, r *http.Request) {
+	JSON(w, http.StatusOK, Report{Status: health.StatusUp})
+}
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
+	report := h.Run(r.Context())
+	if report.Status == health.StatusDown {
+		JSON(w, http.StatusServiceUnavailable, report)
+		return
+	}
+	JSON(w, http.StatusOK, report)
+}
+
+func (h *Handler) Run(ctx context.Context) Report {
+	results := make([]Result, len(h.Checks))
+	var wg sync.WaitGroup
+	for i, c := range h.Checks {
+		wg.Add(1)
+		go func(i int, c *Check) {
+			defer wg.Done()
+			results[i] = c.Run(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+	report := Report{Status: health.StatusUp, Checks: make(map[string]Result, len(h.Checks))}
+	for i, c := range h.Checks {
+		report.Checks[c.Name()] = results[i]
+		if results[i].Status == health.StatusDown {
+			report.Status = health.StatusDown
+		}
+	}
+	return report
+}
+
+func JSON(w http.ResponseWriter, code int, res interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	return json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,81 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/core-go/health"
+)
+
+func serve(t *testing.T, h http.HandlerFunc) (int, map[string]interface{}) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("body %q of type %q: %v", w.Body, w.Header().Get("Content-Type"), err)
+	}
+	return w.Code, body
+}
+
+func TestHandler(t *testing.T) {
+	db := &checker{name: "sql"}
+	client := &checker{name: "client", delay: 50 * time.Millisecond}
+	h := NewHandler(NewCheck(db, 0, 0), NewCheck(client, time.Second, 0))
+
+	start := time.Now()
+	status, body := serve(t, h.Ready)
+	if status != http.StatusOK || body["status"] != health.StatusUp {
+		t.Fatalf("ready: %d %v", status, body)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("ready took %v, want the checks run in parallel", elapsed)
+	}
+	checks, _ := body["checks"].(map[string]interface{})
+	sql, _ := checks["sql"].(map[string]interface{})
+	if len(checks) != 2 || sql["status"] != health.StatusUp || sql["latencyMs"] == nil || sql["data"] == nil {
+		t.Errorf("checks = %v", checks)
+	}
+
+	db.err = errors.New("connection refused")
+	status, body = serve(t, h.Ready)
+	checks, _ = body["checks"].(map[string]interface{})
+	sql, _ = checks["sql"].(map[string]interface{})
+	data, _ := sql["data"].(map[string]interface{})
+	if status != http.StatusServiceUnavailable || body["status"] != health.StatusDown || sql["status"] != health.StatusDown || data["error"] != "connection refused" {
+		t.Errorf("ready with a check down: %d %v", status, body)
+	}
+
+	calls := db.calls.Load()
+	status, body = serve(t, h.Live)
+	if status != http.StatusOK || body["status"] != health.StatusUp || body["checks"] != nil || db.calls.Load() != calls {
+		t.Errorf("live: %d %v, want UP without running the checks", status, body)
+	}
+}
+
+func TestHttpChecker(t *testing.T) {
+	code := http.StatusOK
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Api-Key")
+		w.WriteHeader(code)
+	}))
+	defer server.Close()
+	c := NewCheck(NewHttpChecker("client", server.URL, nil, map[string]string{"X-Api-Key": "gsk_health"}), time.Second, 0)
+	if res := c.Run(context.Background()); res.Status != health.StatusUp || res.Data["status"] != http.StatusOK || header != "gsk_health" {
+		t.Errorf("Run() = %+v, header %q", res, header)
+	}
+	code = http.StatusNotFound
+	if res := c.Run(context.Background()); res.Status != health.StatusUp {
+		t.Errorf("a 4xx answer means the service is up: %+v", res)
+	}
+	code = http.StatusServiceUnavailable
+	if res := c.Run(context.Background()); res.Status != health.StatusDown || res.Data["status"] != http.StatusServiceUnavailable || res.Data["error"] == nil {
+		t.Errorf("Run() = %+v", res)
+	}
+}
@@ -0,0 +1,38 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go-service/pkg/requestid"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem is the body of an error response, as defined by RFC 9457, with the id of the request, so a caller can quote it.
+type Problem struct {
+	Type      string `json:"type,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Status    int    `json:"status,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestId string `json:"requestId,omitempty"`
+}
+
+func New(r *http.Request, detail string, status int) *Problem {
+	p := &Problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Instance: r.URL.Path, RequestId: requestid.FromContext(r.Context())}
+	if detail != p.Title {
+		p.Detail = detail
+	}
+	return p
+}
+
+// Error replies to the request with a problem, in place of http.Error.
+func Error(w http.ResponseWriter, r *http.Request, detail string, status int) {
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", ContentType)
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(New(r, detail, status))
+}
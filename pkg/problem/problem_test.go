@@ -0,0 +1,39 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-service/pkg/requestid"
+)
+
+func TestError(t *testing.T) {
+	var got Problem
+	h := requestid.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Error(w, r, "Id cannot be empty", http.StatusBadRequest)
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/users/ironman", nil)
+	r.Header.Set(requestid.HeaderRequestId, "req-1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != ContentType {
+		t.Fatalf("status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := Problem{Type: "about:blank", Title: "Bad Request", Status: 400, Detail: "Id cannot be empty", Instance: "/users/ironman", RequestId: "req-1"}
+	if got != want {
+		t.Errorf("problem = %+v, want %+v", got, want)
+	}
+}
+
+func TestNewOmitsDetailSameAsTitle(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/users/ironman", nil)
+	p := New(r, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	if len(p.Detail) > 0 || p.Title != "Forbidden" || len(p.RequestId) > 0 {
+		t.Errorf("New() = %+v", p)
+	}
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseProxies(t *testing.T) {
+	nets, err := ParseProxies(" 127.0.0.1, ::1,10.0.0.0/8,,")
+	if err != nil || len(nets) != 3 {
+		t.Fatalf("ParseProxies() = %v, %v", nets, err)
+	}
+	if nets[0].String() != "127.0.0.1/32" || nets[1].String() != "::1/128" || nets[2].String() != "10.0.0.0/8" {
+		t.Errorf("ParseProxies() = %v", nets)
+	}
+	if nets, err := ParseProxies(""); err != nil || len(nets) != 0 {
+		t.Errorf("ParseProxies(\"\") = %v, %v", nets, err)
+	}
+	for _, s := range []string{"localhost", "10.0.0.0/33", "300.0.0.1"} {
+		if _, err := ParseProxies(s); err == nil {
+			t.Errorf("ParseProxies(%q) must fail", s)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, _ := ParseProxies("10.0.0.0/8,::1")
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded []string
+		want      string
+	}{
+		{"direct", "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"untrusted peer ignores the header", "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted peer", "10.0.0.2:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"right-most untrusted entry", "10.0.0.2:5000", []string{"6.6.6.6, 198.51.100.1, 10.0.0.3"}, "198.51.100.1"},
+		{"several headers", "10.0.0.2:5000", []string{"6.6.6.6", "198.51.100.1"}, "198.51.100.1"},
+		{"only proxies", "10.0.0.2:5000", []string{"10.0.0.4, 10.0.0.3"}, "10.0.0.4"},
+		{"trusted peer without header", "10.0.0.2:5000", nil, "10.0.0.2"},
+		{"invalid entry", "10.0.0.2:5000", []string{"198.51.100.1, not-an-ip"}, "10.0.0.2"},
+		{"ipv6 peer", "[::1]:5000", []string{"2001:db8::1"}, "2001:db8::1"},
+		{"no port", "203.0.113.7", nil, "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/users/ironman", nil)
+		r.RemoteAddr = tt.remote
+		for _, f := range tt.forwarded {
+			r.Header.Add("X-Forwarded-For", f)
+		}
+		if got := ClientIP(r, trusted); got != tt.want {
+			t.Errorf("%s: ClientIP() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package ratelimit
+
+import "time"
+
+type Config struct {
+	Enabled        bool    `yaml:"enabled" mapstructure:"enabled" json:"enabled,omitempty" gorm:"column:enabled" bson:"enabled,omitempty" dynamodbav:"enabled,omitempty" firestore:"enabled,omitempty"`
+	TrustedProxies string  `yaml:"trusted_proxies" mapstructure:"trusted_proxies" json:"trustedProxies,omitempty" gorm:"column:trustedproxies" bson:"trustedProxies,omitempty" dynamodbav:"trustedProxies,omitempty" firestore:"trustedProxies,omitempty"`
+	IP             Limit   `yaml:"ip" mapstructure:"ip" json:"ip,omitempty" gorm:"column:ip" bson:"ip,omitempty" dynamodbav:"ip,omitempty" firestore:"ip,omitempty"`
+	Default        Limit   `yaml:"default" mapstructure:"default" json:"default,omitempty" gorm:"column:default" bson:"default,omitempty" dynamodbav:"default,omitempty" firestore:"default,omitempty"`
+	Routes         []Route `yaml:"routes" mapstructure:"routes" json:"routes,omitempty" gorm:"column:routes" bson:"routes,omitempty" dynamodbav:"routes,omitempty" firestore:"routes,omitempty"`
+}
+
+type Limit struct {
+	Requests int           `yaml:"requests" mapstructure:"requests" json:"requests,omitempty" gorm:"column:requests" bson:"requests,omitempty" dynamodbav:"requests,omitempty" firestore:"requests,omitempty"`
+	Period   time.Duration `yaml:"period" mapstructure:"period" json:"period,omitempty" gorm:"column:period" bson:"period,omitempty" dynamodbav:"period,omitempty" firestore:"period,omitempty"`
+	Burst    int           `yaml:"burst" mapstructure:"burst" json:"burst,omitempty" gorm:"column:burst" bson:"burst,omitempty" dynamodbav:"burst,omitempty" firestore:"burst,omitempty"`
+}
+
+type Route struct {
+	Path    string `yaml:"path" mapstructure:"path" json:"path,omitempty" gorm:"column:path" bson:"path,omitempty" dynamodbav:"path,omitempty" firestore:"path,omitempty"`
+	Methods string `yaml:"methods" mapstructure:"methods" json:"methods,omitempty" gorm:"column:methods" bson:"methods,omitempty" dynamodbav:"methods,omitempty" firestore:"methods,omitempty"`
+	Limit   Limit  `yaml:"limit" mapstructure:"limit" json:"limit,omitempty" gorm:"column:limit" bson:"limit,omitempty" dynamodbav:"limit,omitempty" firestore:"limit,omitempty"`
+}
+
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+func (l Limit) Capacity() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+func (l Limit) Interval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"go-service/pkg/auth"
+	"go-service/pkg/problem"
+)
+
+type Limiter struct {
+	Store    Store
+	LogError func(context.Context, string, ...map[string]interface{})
+	state    atomic.Pointer[state]
+}
+
+type state struct {
+	config  Config
+	proxies []*net.IPNet
+}
+
+func NewLimiter(c Config, store Store, logError func(context.Context, string, ...map[string]interface{})) (*Limiter, error) {
+	if store == nil {
+		store = NewMemoryStore(0)
+	}
+	l := &Limiter{Store: store, LogError: logError}
+	if err := l.Update(c); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Update replaces the limits without resetting the buckets already taken.
+func (l *Limiter) Update(c Config) error {
+	proxies, err := ParseProxies(c.TrustedProxies)
+	if err != nil {
+		return err
+	}
+	l.state.Store(&state{config: c, proxies: proxies})
+	return nil
+}
+func (l *Limiter) Config() Config {
+	return l.state.Load().config
+}
+
+// HandleIP is placed before the authentication, so that the requests failing it are counted. It applies rate_limit.ip per client IP,
+// a coarse limit for the clients sharing an address, and leaves the limits of each client to Handle.
+func (l *Limiter) HandleIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := l.state.Load()
+		if !s.config.Enabled || !s.config.IP.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		l.serve(w, r, next, "ip|ip:"+ClientIP(r, s.proxies), s.config.IP)
+	})
+}
+
+// Handle is placed after the authentication, so that each API key or JWT subject has its own buckets, whatever its IPs.
+func (l *Limiter) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.state.Load().config.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		name, limit := l.Limit(r)
+		if !limit.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		l.serve(w, r, next, name+"|"+l.Key(r), limit)
+	})
+}
+
+func (l *Limiter) serve(w http.ResponseWriter, r *http.Request, next http.Handler, key string, limit Limit) {
+	res, err := l.Store.Take(r.Context(), key, limit, time.Now())
+	if err != nil {
+		l.LogError(r.Context(), err.Error())
+		next.ServeHTTP(w, r)
+		return
+	}
+	h := w.Header()
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", limit.Requests, seconds(limit.Period), limit.Capacity()))
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+	if !res.Allowed {
+		h.Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+		problem.Error(w, r, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+	next.ServeHTTP(w, r)
+}
+
+// Limit returns the bucket name and limit of the first configured route matching the mux route template and method, or the default.
+func (l *Limiter) Limit(r *http.Request) (string, Limit) {
+	c := l.state.Load().config
+	if route := mux.CurrentRoute(r); route != nil {
+		if path, err := route.GetPathTemplate(); err == nil {
+			for _, route := range c.Routes {
+				if route.Path == path && matchMethod(route.Methods, r.Method) {
+					return r.Method + " " + path, route.Limit
+				}
+			}
+		}
+	}
+	return "*", c.Default
+}
+
+// Key is the principal of the request, or the client IP when it is not authenticated, such as when auth is disabled.
+func (l *Limiter) Key(r *http.Request) string {
+	if principal, ok := auth.GetPrincipal(r.Context()); ok && len(principal.Subject) > 0 {
+		if principal.Type == auth.PrincipalApiKey {
+			return "apikey:" + principal.Subject
+		}
+		return "sub:" + principal.Subject
+	}
+	return "ip:" + ClientIP(r, l.state.Load().proxies)
+}
+
+func matchMethod(methods string, method string) bool {
+	if len(methods) == 0 {
+		return true
+	}
+	for _, m := range strings.Split(methods, ",") {
+		if strings.EqualFold(strings.TrimSpace(m), method) {
+			return true
+		}
+	}
+	return false
+}
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
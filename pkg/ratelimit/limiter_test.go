@@ -0,0 +1,145 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"go-service/pkg/auth"
+)
+
+func newRouter(t *testing.T, c Config) (*mux.Router, *Limiter) {
+	t.Helper()
+	l, err := NewLimiter(c, nil, func(context.Context, string, ...map[string]interface{}) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := mux.NewRouter()
+	user := r.PathPrefix("/users").Subrouter()
+	// As in internal/app, the IP limit comes before the authentication, which takes any X-API-Key here, and the limits of each client after.
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key := r.Header.Get(auth.HeaderApiKey); len(key) > 0 {
+				r = r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{Subject: key, Type: auth.PrincipalApiKey}))
+			}
+			auth.Require(next).ServeHTTP(w, r)
+		})
+	}
+	user.Use(l.HandleIP, authenticate, l.Handle)
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	user.HandleFunc("/search", ok).Methods(http.MethodGet, http.MethodPost)
+	user.HandleFunc("/{id}", ok).Methods(http.MethodGet)
+	return r, l
+}
+
+func serve(h http.Handler, method string, path string, remote string, apiKey ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	r.RemoteAddr = remote
+	if len(apiKey) > 0 {
+		r.Header.Set(auth.HeaderApiKey, apiKey[0])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandleCountsFailedAuthentication(t *testing.T) {
+	r, _ := newRouter(t, Config{Enabled: true, IP: Limit{Requests: 3, Period: time.Minute}, Default: Limit{Requests: 600, Period: time.Minute}})
+	for i := 0; i < 3; i++ {
+		if w := serve(r, http.MethodGet, "/users/ironman", "203.0.113.7:5000"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("request %d: status %d, want 401", i, w.Code)
+		}
+	}
+	w := serve(r, http.MethodGet, "/users/ironman", "203.0.113.7:5000")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "20" {
+		t.Errorf("status %d, Retry-After %q; want 429 after 3 failed attempts", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := serve(r, http.MethodGet, "/users/ironman", "203.0.113.8:5000"); w.Code != http.StatusUnauthorized {
+		t.Errorf("another client: status %d, want 401", w.Code)
+	}
+}
+
+func TestHandleHeaders(t *testing.T) {
+	r, _ := newRouter(t, Config{
+		Enabled: true,
+		Default: Limit{Requests: 600, Period: time.Minute},
+		Routes:  []Route{{Path: "/users/search", Methods: "GET,POST", Limit: Limit{Requests: 60, Period: time.Minute, Burst: 20}}},
+	})
+	tests := []struct {
+		method    string
+		path      string
+		policy    string
+		limit     string
+		remaining string
+	}{
+		{http.MethodGet, "/users/search", "60;w=60;burst=20", "60", "19"},
+		{http.MethodPost, "/users/search", "60;w=60;burst=20", "60", "19"},
+		{http.MethodGet, "/users/ironman", "600;w=60;burst=600", "600", "599"},
+	}
+	for _, tt := range tests {
+		h := serve(r, tt.method, tt.path, "203.0.113.7:5000", "billing").Header()
+		if h.Get("RateLimit-Policy") != tt.policy || h.Get("RateLimit-Limit") != tt.limit || h.Get("RateLimit-Remaining") != tt.remaining {
+			t.Errorf("%s %s: policy %q, limit %q, remaining %q; want %q, %q, %q", tt.method, tt.path,
+				h.Get("RateLimit-Policy"), h.Get("RateLimit-Limit"), h.Get("RateLimit-Remaining"), tt.policy, tt.limit, tt.remaining)
+		}
+	}
+}
+
+func TestHandleDisabled(t *testing.T) {
+	r, l := newRouter(t, Config{Enabled: false, Default: Limit{Requests: 1, Period: time.Minute}})
+	for i := 0; i < 3; i++ {
+		if w := serve(r, http.MethodGet, "/users/ironman", "203.0.113.7:5000"); w.Code != http.StatusUnauthorized || len(w.Header().Get("RateLimit-Limit")) > 0 {
+			t.Fatalf("disabled limiter: status %d, headers %v", w.Code, w.Header())
+		}
+	}
+	if err := l.Update(Config{Enabled: true, IP: Limit{Requests: 1, Period: time.Minute}}); err != nil {
+		t.Fatal(err)
+	}
+	serve(r, http.MethodGet, "/users/ironman", "203.0.113.7:5000")
+	if w := serve(r, http.MethodGet, "/users/ironman", "203.0.113.7:5000"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("after Update: status %d, want 429", w.Code)
+	}
+	if err := l.Update(Config{TrustedProxies: "localhost"}); err == nil {
+		t.Error("Update() must reject invalid proxies")
+	}
+}
+
+func TestHandleKeysByPrincipal(t *testing.T) {
+	r, _ := newRouter(t, Config{Enabled: true, IP: Limit{Requests: 100, Period: time.Minute}, Default: Limit{Requests: 2, Period: time.Minute}})
+	for i := 0; i < 2; i++ {
+		if w := serve(r, http.MethodGet, "/users/ironman", "203.0.113.7:5000", "billing"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i, w.Code)
+		}
+	}
+	if w := serve(r, http.MethodGet, "/users/ironman", "203.0.113.7:5000", "billing"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("status %d, want 429 once the key has spent its bucket", w.Code)
+	}
+	if w := serve(r, http.MethodGet, "/users/ironman", "198.51.100.1:5000", "billing"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("from another IP: status %d, want 429 as the bucket is the key's", w.Code)
+	}
+	if w := serve(r, http.MethodGet, "/users/ironman", "203.0.113.7:5000", "shipping"); w.Code != http.StatusOK {
+		t.Errorf("another key from the same IP: status %d, want 200", w.Code)
+	}
+}
+
+func TestKey(t *testing.T) {
+	l, _ := NewLimiter(Config{TrustedProxies: "10.0.0.0/8"}, nil, nil)
+	r := httptest.NewRequest(http.MethodGet, "/users/ironman", nil)
+	r.RemoteAddr = "10.0.0.2:5000"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if key := l.Key(r); key != "ip:198.51.100.1" {
+		t.Errorf("Key() = %q", key)
+	}
+	r = r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{Subject: "billing", Type: auth.PrincipalApiKey}))
+	if key := l.Key(r); key != "apikey:billing" {
+		t.Errorf("Key() = %q", key)
+	}
+	r = r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{Subject: "ironman", Type: "jwt"}))
+	if key := l.Key(r); key != "sub:ironman" {
+		t.Errorf("Key() = %q", key)
+	}
+}
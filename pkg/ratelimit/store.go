@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type Result struct {
+	Allowed    bool
+	Limit      int // the requests per period
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweep   time.Duration
+	swept   time.Time
+}
+
+func NewMemoryStore(sweep time.Duration) *MemoryStore {
+	if sweep <= 0 {
+		sweep = time.Minute
+	}
+	return &MemoryStore{buckets: make(map[string]*bucket), sweep: sweep}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	capacity := float64(limit.Capacity())
+	interval := limit.Interval()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeFull(now)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	} else if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+float64(elapsed)/float64(interval))
+		b.updated = now
+	}
+	res := Result{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - b.tokens) * float64(interval))
+	}
+	res.Remaining = int(math.Min(b.tokens, float64(limit.Requests)))
+	res.Reset = time.Duration((capacity - b.tokens) * float64(interval))
+	b.full = now.Add(res.Reset)
+	return res, nil
+}
+
+// removeFull drops buckets that have refilled completely, since a new bucket starts full anyway.
+func (s *MemoryStore) removeFull(now time.Time) {
+	if now.Sub(s.swept) < s.sweep {
+		return
+	}
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+	s.swept = now
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	s := NewMemoryStore(0)
+	ctx := context.Background()
+	limit := Limit{Requests: 60, Period: time.Minute, Burst: 3}
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < 3; i++ {
+		res, _ := s.Take(ctx, "ip:10.0.0.1", limit, now)
+		if !res.Allowed || res.Remaining != 2-i || res.Limit != 60 {
+			t.Fatalf("take %d = %+v, want allowed with %d remaining of 60", i, res, 2-i)
+		}
+	}
+	res, _ := s.Take(ctx, "ip:10.0.0.1", limit, now)
+	if res.Allowed || res.RetryAfter != time.Second || res.Reset != 3*time.Second {
+		t.Fatalf("take over the burst = %+v, want rejected, retry after 1s, reset in 3s", res)
+	}
+	if res, _ := s.Take(ctx, "ip:10.0.0.2", limit, now); !res.Allowed {
+		t.Error("the buckets of the clients must be separate")
+	}
+	if res, _ := s.Take(ctx, "ip:10.0.0.1", limit, now.Add(500*time.Millisecond)); res.Allowed {
+		t.Errorf("take after half a token = %+v, want rejected", res)
+	}
+	if res, _ := s.Take(ctx, "ip:10.0.0.1", limit, now.Add(time.Second)); !res.Allowed || res.Remaining != 0 {
+		t.Errorf("take after a refill of one token = %+v, want allowed", res)
+	}
+	res, _ = s.Take(ctx, "ip:10.0.0.1", limit, now.Add(time.Hour))
+	if !res.Allowed || res.Remaining != 2 {
+		t.Errorf("take after a long pause = %+v, want the bucket full, not over the burst", res)
+	}
+}
+
+func TestMemoryStoreRemainingIsNotOverLimit(t *testing.T) {
+	s := NewMemoryStore(0)
+	limit := Limit{Requests: 2, Period: time.Second, Burst: 10}
+	res, _ := s.Take(context.Background(), "ip:10.0.0.1", limit, time.Now())
+	if res.Limit != 2 || res.Remaining != 2 {
+		t.Errorf("Take() = %+v, want a limit of 2 and at most 2 remaining", res)
+	}
+}
+
+func TestMemoryStoreRemovesFullBuckets(t *testing.T) {
+	s := NewMemoryStore(time.Minute)
+	limit := Limit{Requests: 10, Period: time.Second}
+	now := time.Now()
+	s.Take(context.Background(), "a", limit, now)
+	s.Take(context.Background(), "b", limit, now.Add(2*time.Minute))
+	if _, ok := s.buckets["a"]; ok || len(s.buckets) != 1 {
+		t.Errorf("buckets = %v, want the full bucket a removed", s.buckets)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	l := Limit{Requests: 60, Period: time.Minute}
+	if !l.Enabled() || l.Capacity() != 60 || l.Interval() != time.Second {
+		t.Errorf("Limit %+v: enabled %v, capacity %d, interval %s", l, l.Enabled(), l.Capacity(), l.Interval())
+	}
+	l.Burst = 5
+	if l.Capacity() != 5 {
+		t.Errorf("Capacity() = %d, want the burst", l.Capacity())
+	}
+	if (Limit{Requests: 60}).Enabled() || (Limit{Period: time.Minute}).Enabled() {
+		t.Error("a limit without requests or period must be disabled")
+	}
+}
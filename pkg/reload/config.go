@@ -0,0 +1,9 @@
+package reload
+
+import "time"
+
+type Config struct {
+	Enabled  bool          `yaml:"enabled" mapstructure:"enabled" json:"enabled,omitempty" gorm:"column:enabled" bson:"enabled,omitempty" dynamodbav:"enabled,omitempty" firestore:"enabled,omitempty"`
+	Watch    bool          `yaml:"watch" mapstructure:"watch" json:"watch,omitempty" gorm:"column:watch" bson:"watch,omitempty" dynamodbav:"watch,omitempty" firestore:"watch,omitempty"`
+	Debounce time.Duration `yaml:"debounce" mapstructure:"debounce" json:"debounce,omitempty" gorm:"column:debounce" bson:"debounce,omitempty" dynamodbav:"debounce,omitempty" firestore:"debounce,omitempty"`
+}
@@ -0,0 +1,28 @@
+package reload
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Middleware delegates to a middleware that can be replaced at runtime. gorilla/mux builds the middleware chain per request,
+// so the replacement applies to the next request.
+type Middleware struct {
+	current atomic.Pointer[func(http.Handler) http.Handler]
+}
+
+func NewMiddleware(m func(http.Handler) http.Handler) *Middleware {
+	d := &Middleware{}
+	d.Set(m)
+	return d
+}
+
+func (d *Middleware) Set(m func(http.Handler) http.Handler) {
+	d.current.Store(&m)
+}
+
+func (d *Middleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		(*d.current.Load())(next).ServeHTTP(w, r)
+	})
+}
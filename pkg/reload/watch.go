@@ -0,0 +1,85 @@
+package reload
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch calls reload on SIGHUP and, when c.Watch is set, whenever one of the files is written, created, renamed or removed.
+// The parent directories are watched so that editors replacing the file and Kubernetes ConfigMap symlink swaps are seen.
+// Events are debounced, and Watch stops when ctx is done.
+func Watch(ctx context.Context, c Config, files []string, reload func(context.Context), logError func(context.Context, string, ...map[string]interface{})) error {
+	debounce := c.Debounce
+	if debounce <= 0 {
+		debounce = 500 * time.Millisecond
+	}
+	var events chan fsnotify.Event
+	var errors chan error
+	var watcher *fsnotify.Watcher
+	names := make(map[string]bool)
+	if c.Watch {
+		var err error
+		watcher, err = fsnotify.NewWatcher()
+		if err != nil {
+			return err
+		}
+		dirs := make(map[string]bool)
+		for _, file := range files {
+			abs, err := filepath.Abs(file)
+			if err != nil {
+				watcher.Close()
+				return err
+			}
+			names[abs] = true
+			dir := filepath.Dir(abs)
+			if !dirs[dir] {
+				if err = watcher.Add(dir); err != nil {
+					watcher.Close()
+					return err
+				}
+				dirs[dir] = true
+			}
+		}
+		events, errors = watcher.Events, watcher.Errors
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		if watcher != nil {
+			defer watcher.Close()
+		}
+		timer := time.NewTimer(debounce)
+		timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-hup:
+				timer.Stop()
+				reload(ctx)
+			case e := <-events:
+				if names[e.Name] || isDataLink(e.Name) {
+					timer.Reset(debounce)
+				}
+			case err := <-errors:
+				logError(ctx, "config watcher: "+err.Error())
+			case <-timer.C:
+				reload(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// isDataLink reports the ..data symlink swapped by Kubernetes when a mounted ConfigMap or Secret changes.
+func isDataLink(name string) bool {
+	return filepath.Base(name) == "..data"
+}
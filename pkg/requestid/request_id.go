@@ -0,0 +1,100 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	HeaderRequestId     = "X-Request-ID"
+	HeaderCorrelationId = "X-Correlation-ID"
+	Key                 = "requestId"
+	maxLength           = 128
+)
+
+// Handle takes the id from X-Request-ID or X-Correlation-ID, or generates one, stores it in the context under Key and echoes it in the response.
+func Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, header := FromRequest(r)
+		if len(id) == 0 {
+			id = Generate()
+		}
+		w.Header().Set(HeaderRequestId, id)
+		if header == HeaderCorrelationId {
+			w.Header().Set(HeaderCorrelationId, id)
+		}
+		next.ServeHTTP(w, r.WithContext(WithRequestId(r.Context(), id)))
+	})
+}
+
+func FromRequest(r *http.Request) (string, string) {
+	for _, header := range []string{HeaderRequestId, HeaderCorrelationId} {
+		if id := r.Header.Get(header); Valid(id) {
+			return id, header
+		}
+	}
+	return "", ""
+}
+
+// Valid accepts up to 128 letters, digits and the characters - _ . : so a client cannot inject arbitrary text into logs.
+func Valid(id string) bool {
+	if len(id) == 0 || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func Generate() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, Key, id)
+}
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(Key).(string)
+	return id
+}
+
+// Inject sets X-Request-ID on an outbound request from ctx unless the caller already set it.
+func Inject(ctx context.Context, header http.Header) {
+	if id := FromContext(ctx); len(id) > 0 && len(header.Get(HeaderRequestId)) == 0 {
+		header.Set(HeaderRequestId, id)
+	}
+}
+
+type Transport struct {
+	Base http.RoundTripper
+}
+
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Base: base}
+}
+
+func (t *Transport) Unwrap() http.RoundTripper {
+	return t.Base
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if id := FromContext(req.Context()); len(id) > 0 && len(req.Header.Get(HeaderRequestId)) == 0 {
+		req = req.Clone(req.Context())
+		req.Header.Set(HeaderRequestId, id)
+	}
+	return t.Base.RoundTrip(req)
+}
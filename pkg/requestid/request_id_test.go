@@ -0,0 +1,133 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandle(t *testing.T) {
+	var got string
+	h := Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	}))
+	tests := []struct {
+		name        string
+		header      string
+		value       string
+		want        string // empty when a new id must be generated
+		correlation bool   // whether X-Correlation-ID is echoed
+	}{
+		{"request id", HeaderRequestId, "req-1", "req-1", false},
+		{"correlation id", HeaderCorrelationId, "corr:2.a_b", "corr:2.a_b", true},
+		{"none", "", "", "", false},
+		{"injected text", HeaderRequestId, "req-1\nlevel=error", "", false},
+		{"spaces", HeaderRequestId, "req 1", "", false},
+		{"too long", HeaderRequestId, strings.Repeat("a", 129), "", false},
+		{"longest", HeaderRequestId, strings.Repeat("a", 128), strings.Repeat("a", 128), false},
+	}
+	for _, tt := range tests {
+		got = ""
+		r := httptest.NewRequest(http.MethodGet, "/users/ironman", nil)
+		if len(tt.header) > 0 {
+			r.Header.Set(tt.header, tt.value)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if len(tt.want) > 0 && got != tt.want {
+			t.Errorf("%s: id %q, want %q", tt.name, got, tt.want)
+		}
+		if len(tt.want) == 0 && (got == tt.value || len(got) != 32) {
+			t.Errorf("%s: id %q, want a generated one", tt.name, got)
+		}
+		if w.Header().Get(HeaderRequestId) != got {
+			t.Errorf("%s: X-Request-ID %q, want %q", tt.name, w.Header().Get(HeaderRequestId), got)
+		}
+		if correlation := w.Header().Get(HeaderCorrelationId); (len(correlation) > 0) != tt.correlation || (tt.correlation && correlation != got) {
+			t.Errorf("%s: X-Correlation-ID %q", tt.name, correlation)
+		}
+	}
+}
+
+func TestHandlePrefersRequestId(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/users/ironman", nil)
+	r.Header.Set(HeaderRequestId, "bad id")
+	r.Header.Set(HeaderCorrelationId, "corr-1")
+	if id, header := FromRequest(r); id != "corr-1" || header != HeaderCorrelationId {
+		t.Errorf("FromRequest() = %q, %q; want the valid correlation id", id, header)
+	}
+	r.Header.Set(HeaderRequestId, "req-1")
+	if id, header := FromRequest(r); id != "req-1" || header != HeaderRequestId {
+		t.Errorf("FromRequest() = %q, %q", id, header)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	a, b := Generate(), Generate()
+	if a == b || !Valid(a) || len(a) != 32 {
+		t.Errorf("Generate() = %q, %q", a, b)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Values(HeaderRequestId)
+	}))
+	defer server.Close()
+	c := &http.Client{Transport: NewTransport(nil)}
+	send := func(ctx context.Context, header string) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if len(header) > 0 {
+			req.Header.Set(HeaderRequestId, header)
+		}
+		res, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	ctx := WithRequestId(context.Background(), "req-1")
+
+	send(ctx, "")
+	if len(got) != 1 || got[0] != "req-1" {
+		t.Errorf("X-Request-ID %v, want the id of the context", got)
+	}
+	send(ctx, "caller")
+	if len(got) != 1 || got[0] != "caller" {
+		t.Errorf("X-Request-ID %v, want the one set by the caller", got)
+	}
+	send(context.Background(), "")
+	if len(got) != 0 {
+		t.Errorf("X-Request-ID %v, want none without an id in the context", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	NewTransport(RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})).RoundTrip(req.WithContext(ctx))
+	if len(req.Header.Get(HeaderRequestId)) > 0 {
+		t.Error("RoundTrip() must not change the request of the caller")
+	}
+}
+
+func TestInject(t *testing.T) {
+	header := http.Header{}
+	Inject(WithRequestId(context.Background(), "req-1"), header)
+	if header.Get(HeaderRequestId) != "req-1" {
+		t.Errorf("Inject() = %v", header)
+	}
+	header.Set(HeaderRequestId, "caller")
+	Inject(WithRequestId(context.Background(), "req-1"), header)
+	if header.Get(HeaderRequestId) != "caller" {
+		t.Errorf("Inject() replaced the header of the caller: %v", header)
+	}
+}
+
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
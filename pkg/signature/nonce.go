@@ -0,0 +1,51 @@
+package signature
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NonceStore remembers the nonces until they expire. Instances behind a load balancer need a shared store, such as Redis with SET NX and an expiry.
+type NonceStore interface {
+	// Add returns false when the nonce is already there and has not expired.
+	Add(ctx context.Context, nonce string, expires time.Time) (bool, error)
+}
+
+// MemoryNonceStore keeps the nonces of one instance. The expired ones are removed at most once a minute, when a nonce is added.
+type MemoryNonceStore struct {
+	Now       func() time.Time
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	nextSweep time.Time
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{Now: time.Now, nonces: make(map[string]time.Time)}
+}
+
+func (s *MemoryNonceStore) Add(ctx context.Context, nonce string, expires time.Time) (bool, error) {
+	now := s.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.After(s.nextSweep) {
+		for k, t := range s.nonces {
+			if now.After(t) {
+				delete(s.nonces, k)
+			}
+		}
+		s.nextSweep = now.Add(time.Minute)
+	}
+	if t, ok := s.nonces[nonce]; ok && !now.After(t) {
+		return false, nil
+	}
+	s.nonces[nonce] = expires
+	return true, nil
+}
+
+// Len returns the number of nonces kept, including the expired ones not yet removed.
+func (s *MemoryNonceStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.nonces)
+}
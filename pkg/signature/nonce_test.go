@@ -0,0 +1,49 @@
+package signature
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryNonceStore(t *testing.T) {
+	ctx := context.Background()
+	clock := now
+	s := NewMemoryNonceStore()
+	s.Now = func() time.Time { return clock }
+	if added, _ := s.Add(ctx, "a", clock.Add(2*time.Minute)); !added {
+		t.Fatal("first nonce not added")
+	}
+	if added, _ := s.Add(ctx, "a", clock.Add(2*time.Minute)); added {
+		t.Error("nonce added twice before it expires")
+	}
+	s.Add(ctx, "b", clock.Add(10*time.Minute))
+
+	clock = clock.Add(3 * time.Minute)
+	if added, _ := s.Add(ctx, "a", clock.Add(2*time.Minute)); !added {
+		t.Error("expired nonce not added again")
+	}
+	if added, _ := s.Add(ctx, "b", clock.Add(2*time.Minute)); added {
+		t.Error("nonce b added before it expires")
+	}
+}
+
+func TestMemoryNonceStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	clock := now
+	s := NewMemoryNonceStore()
+	s.Now = func() time.Time { return clock }
+	s.Add(ctx, "a", clock.Add(time.Second))
+	s.Add(ctx, "b", clock.Add(time.Hour))
+
+	clock = clock.Add(30 * time.Second)
+	s.Add(ctx, "c", clock.Add(time.Hour))
+	if s.Len() != 3 {
+		t.Errorf("Len() = %d before the next sweep, want 3", s.Len())
+	}
+	clock = clock.Add(time.Minute)
+	s.Add(ctx, "d", clock.Add(time.Hour))
+	if s.Len() != 3 {
+		t.Errorf("Len() = %d after the sweep, want 3", s.Len())
+	}
+}
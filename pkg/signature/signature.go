@@ -0,0 +1,143 @@
+package signature
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	HeaderKeyId     = "X-Key-Id"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature"
+
+	DefaultWindow  = 5 * time.Minute
+	minNonceLength = 16
+	maxNonceLength = 128
+)
+
+var (
+	ErrMissing    = errors.New("signature headers are missing")
+	ErrUnknownKey = errors.New("signature key id is unknown")
+	ErrExpired    = errors.New("signature timestamp is outside of the window")
+	ErrNonce      = errors.New("signature nonce is invalid")
+	ErrInvalid    = errors.New("signature does not match")
+	ErrReplayed   = errors.New("signature nonce has already been used")
+)
+
+// Canonical is the signed form of a request: the method, the path with its query, the timestamp, the nonce and the hex SHA-256 of the body, one per line.
+func Canonical(method string, uri string, timestamp string, nonce string, body []byte) string {
+	digest := sha256.Sum256(body)
+	return method + "\n" + uri + "\n" + timestamp + "\n" + nonce + "\n" + hex.EncodeToString(digest[:])
+}
+
+// Sign returns the base64 HMAC-SHA256 of the canonical form.
+func Sign(secret []byte, canonical string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(canonical))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NewNonce returns 16 random bytes, base64url encoded.
+func NewNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Request holds the signed parts of a request, and the values of its signature headers.
+type Request struct {
+	Method    string
+	URI       string
+	Body      []byte
+	KeyId     string
+	Timestamp string
+	Nonce     string
+	Signature string
+}
+
+// Verifier checks the signatures made with any of its keys, so that a key can be rotated by adding the new id before the clients use it,
+// and removing the old one after.
+type Verifier struct {
+	Window time.Duration
+	Nonces NonceStore
+	Now    func() time.Time
+	keys   atomic.Pointer[map[string][]byte]
+}
+
+// NewVerifier accepts the timestamps within window of its clock, DefaultWindow when it is 0, and remembers the nonces in a MemoryNonceStore when nonces is nil.
+func NewVerifier(keys map[string][]byte, window time.Duration, nonces NonceStore) *Verifier {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	if nonces == nil {
+		nonces = NewMemoryNonceStore()
+	}
+	v := &Verifier{Window: window, Nonces: nonces, Now: time.Now}
+	v.SetKeys(keys)
+	return v
+}
+
+// SetKeys replaces the keys, by key id.
+func (v *Verifier) SetKeys(keys map[string][]byte) {
+	m := make(map[string][]byte, len(keys))
+	for id, secret := range keys {
+		m[id] = secret
+	}
+	v.keys.Store(&m)
+}
+
+// KeyIds returns the ids of the active keys.
+func (v *Verifier) KeyIds() []string {
+	keys := *v.keys.Load()
+	ids := make([]string, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Verify checks the key, the timestamp and the signature, then records the nonce, so that a nonce is only spent by a valid signature.
+// A nonce is kept for twice the window, which covers the timestamps accepted on both sides of the clock.
+func (v *Verifier) Verify(ctx context.Context, r Request) error {
+	if len(r.KeyId) == 0 || len(r.Timestamp) == 0 || len(r.Nonce) == 0 || len(r.Signature) == 0 {
+		return ErrMissing
+	}
+	secret, ok := (*v.keys.Load())[r.KeyId]
+	if !ok {
+		return ErrUnknownKey
+	}
+	seconds, err := strconv.ParseInt(r.Timestamp, 10, 64)
+	if err != nil {
+		return ErrExpired
+	}
+	now := v.Now()
+	if d := now.Sub(time.Unix(seconds, 0)); d > v.Window || d < -v.Window {
+		return ErrExpired
+	}
+	if len(r.Nonce) < minNonceLength || len(r.Nonce) > maxNonceLength {
+		return ErrNonce
+	}
+	expected := Sign(secret, Canonical(r.Method, r.URI, r.Timestamp, r.Nonce, r.Body))
+	if !hmac.Equal([]byte(expected), []byte(r.Signature)) {
+		return ErrInvalid
+	}
+	added, err := v.Nonces.Add(ctx, r.KeyId+":"+r.Nonce, now.Add(2*v.Window))
+	if err != nil {
+		return err
+	}
+	if !added {
+		return ErrReplayed
+	}
+	return nil
+}
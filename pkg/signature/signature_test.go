@@ -0,0 +1,133 @@
+package signature
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var now = time.Unix(1700000000, 0)
+
+func newVerifier(keys map[string][]byte) *Verifier {
+	nonces := NewMemoryNonceStore()
+	nonces.Now = func() time.Time { return now }
+	v := NewVerifier(keys, time.Minute, nonces)
+	v.Now = nonces.Now
+	return v
+}
+
+func signed(keyId string, secret string, at time.Time, nonce string, body string) Request {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return Request{
+		Method:    "POST",
+		URI:       "/users?dry=true",
+		Body:      []byte(body),
+		KeyId:     keyId,
+		Timestamp: timestamp,
+		Nonce:     nonce,
+		Signature: Sign([]byte(secret), Canonical("POST", "/users?dry=true", timestamp, nonce, []byte(body))),
+	}
+}
+
+func TestVerify(t *testing.T) {
+	nonce := "0123456789abcdef"
+	tampered := signed("partner", "secret", now, nonce, `{"id":"ironman"}`)
+	tampered.Body = []byte(`{"id":"hulk"}`)
+	movedUri := signed("partner", "secret", now, nonce, "")
+	movedUri.URI = "/users"
+	badTimestamp := signed("partner", "secret", now, nonce, "")
+	badTimestamp.Timestamp = "yesterday"
+	tests := []struct {
+		name    string
+		request Request
+		err     error
+	}{
+		{"missing", Request{Method: "POST", URI: "/users"}, ErrMissing},
+		{"unknown key", signed("other", "secret", now, nonce, ""), ErrUnknownKey},
+		{"too old", signed("partner", "secret", now.Add(-61*time.Second), nonce, ""), ErrExpired},
+		{"too new", signed("partner", "secret", now.Add(61*time.Second), nonce, ""), ErrExpired},
+		{"bad timestamp", badTimestamp, ErrExpired},
+		{"short nonce", signed("partner", "secret", now, "0123456789abcde", ""), ErrNonce},
+		{"long nonce", signed("partner", "secret", now, string(make([]byte, 129)), ""), ErrNonce},
+		{"wrong secret", signed("partner", "guess", now, nonce, ""), ErrInvalid},
+		{"tampered body", tampered, ErrInvalid},
+		{"moved uri", movedUri, ErrInvalid},
+		{"edge of the window", signed("partner", "secret", now.Add(-time.Minute), nonce, ""), nil},
+	}
+	for _, tt := range tests {
+		v := newVerifier(map[string][]byte{"partner": []byte("secret")})
+		if err := v.Verify(context.Background(), tt.request); !errors.Is(err, tt.err) {
+			t.Errorf("%s: Verify() = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestVerifyReplay(t *testing.T) {
+	ctx := context.Background()
+	v := newVerifier(map[string][]byte{"partner": []byte("secret"), "backup": []byte("other")})
+	nonce := "0123456789abcdef"
+	forged := signed("partner", "guess", now, nonce, "")
+	if err := v.Verify(ctx, forged); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("forged: Verify() = %v", err)
+	}
+	if err := v.Verify(ctx, signed("partner", "secret", now, nonce, "")); err != nil {
+		t.Fatalf("an invalid signature spent the nonce: %v", err)
+	}
+	if err := v.Verify(ctx, signed("partner", "secret", now, nonce, "")); !errors.Is(err, ErrReplayed) {
+		t.Errorf("replay: Verify() = %v, want %v", err, ErrReplayed)
+	}
+	if err := v.Verify(ctx, signed("backup", "other", now, nonce, "")); err != nil {
+		t.Errorf("the nonces of another key id: Verify() = %v", err)
+	}
+}
+
+func TestVerifyRotation(t *testing.T) {
+	ctx := context.Background()
+	v := newVerifier(map[string][]byte{"k1": []byte("old")})
+	v.SetKeys(map[string][]byte{"k1": []byte("old"), "k2": []byte("new")})
+	if err := v.Verify(ctx, signed("k1", "old", now, "nonce-0000000001", "")); err != nil {
+		t.Errorf("old key during the rotation: %v", err)
+	}
+	if err := v.Verify(ctx, signed("k2", "new", now, "nonce-0000000002", "")); err != nil {
+		t.Errorf("new key during the rotation: %v", err)
+	}
+	v.SetKeys(map[string][]byte{"k2": []byte("new")})
+	if err := v.Verify(ctx, signed("k1", "old", now, "nonce-0000000003", "")); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("old key after the rotation: Verify() = %v, want %v", err, ErrUnknownKey)
+	}
+	if ids := v.KeyIds(); len(ids) != 1 || ids[0] != "k2" {
+		t.Errorf("KeyIds() = %v", ids)
+	}
+}
+
+func TestSetKeysCopies(t *testing.T) {
+	keys := map[string][]byte{"partner": []byte("secret")}
+	v := newVerifier(keys)
+	delete(keys, "partner")
+	if err := v.Verify(context.Background(), signed("partner", "secret", now, "0123456789abcdef", "")); err != nil {
+		t.Errorf("Verify() = %v", err)
+	}
+}
+
+func TestNewVerifierDefaults(t *testing.T) {
+	v := NewVerifier(nil, 0, nil)
+	if v.Window != DefaultWindow || v.Now == nil {
+		t.Errorf("window %v", v.Window)
+	}
+	if _, ok := v.Nonces.(*MemoryNonceStore); !ok {
+		t.Errorf("nonces %T", v.Nonces)
+	}
+}
+
+func TestNewNonce(t *testing.T) {
+	a, err := NewNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewNonce()
+	if a == b || len(a) < minNonceLength || len(a) > maxNonceLength {
+		t.Errorf("NewNonce() = %q, %q", a, b)
+	}
+}
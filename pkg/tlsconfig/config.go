@@ -0,0 +1,16 @@
+package tlsconfig
+
+import "time"
+
+type Config struct {
+	CertFile       string        `yaml:"cert_file" mapstructure:"cert_file" json:"certFile,omitempty" gorm:"column:certfile" bson:"certFile,omitempty" dynamodbav:"certFile,omitempty" firestore:"certFile,omitempty"`
+	KeyFile        string        `yaml:"key_file" mapstructure:"key_file" json:"keyFile,omitempty" gorm:"column:keyfile" bson:"keyFile,omitempty" dynamodbav:"keyFile,omitempty" firestore:"keyFile,omitempty"`
+	CAFiles        []string      `yaml:"ca_files" mapstructure:"ca_files" json:"caFiles,omitempty" gorm:"column:cafiles" bson:"caFiles,omitempty" dynamodbav:"caFiles,omitempty" firestore:"caFiles,omitempty"`
+	MinVersion     string        `yaml:"min_version" mapstructure:"min_version" json:"minVersion,omitempty" gorm:"column:minversion" bson:"minVersion,omitempty" dynamodbav:"minVersion,omitempty" firestore:"minVersion,omitempty"`
+	CipherSuites   []string      `yaml:"cipher_suites" mapstructure:"cipher_suites" json:"cipherSuites,omitempty" gorm:"column:ciphersuites" bson:"cipherSuites,omitempty" dynamodbav:"cipherSuites,omitempty" firestore:"cipherSuites,omitempty"`
+	ServerName     string        `yaml:"server_name" mapstructure:"server_name" json:"serverName,omitempty" gorm:"column:servername" bson:"serverName,omitempty" dynamodbav:"serverName,omitempty" firestore:"serverName,omitempty"`
+	Pins           []string      `yaml:"pins" mapstructure:"pins" json:"pins,omitempty" gorm:"column:pins" bson:"pins,omitempty" dynamodbav:"pins,omitempty" firestore:"pins,omitempty"`
+	ClientAuth     string        `yaml:"client_auth" mapstructure:"client_auth" json:"clientAuth,omitempty" gorm:"column:clientauth" bson:"clientAuth,omitempty" dynamodbav:"clientAuth,omitempty" firestore:"clientAuth,omitempty"`
+	Insecure       bool          `yaml:"insecure" mapstructure:"insecure" json:"insecure,omitempty" gorm:"column:insecure" bson:"insecure,omitempty" dynamodbav:"insecure,omitempty" firestore:"insecure,omitempty"`
+	ReloadInterval time.Duration `yaml:"reload_interval" mapstructure:"reload_interval" json:"reloadInterval,omitempty" gorm:"column:reloadinterval" bson:"reloadInterval,omitempty" dynamodbav:"reloadInterval,omitempty" firestore:"reloadInterval,omitempty"`
+}
@@ -0,0 +1,326 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ClientAuthNone             = "none"
+	ClientAuthRequest          = "request"
+	ClientAuthRequire          = "require"
+	ClientAuthVerifyIfGiven    = "verify_if_given"
+	ClientAuthRequireAndVerify = "require_and_verify"
+
+	PinPrefix = "sha256/"
+)
+
+var ErrPinMismatch = errors.New("tls: the certificate matches none of the pins")
+
+// Store holds the certificate and the CA pool of the config. When their files change, they are loaded again on the next handshake,
+// the files being checked at most every ReloadInterval. Files failing to load leave the previous certificate and pool in use.
+type Store struct {
+	Config       Config
+	LogError     func(context.Context, string, ...map[string]interface{})
+	minVersion   uint16
+	cipherSuites []uint16
+	clientAuth   tls.ClientAuthType
+	pins         map[string]bool
+	cert         atomic.Pointer[tls.Certificate]
+	pool         atomic.Pointer[x509.CertPool]
+	mu           sync.Mutex
+	checked      time.Time
+	stamps       map[string]stamp
+}
+
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func NewStore(c Config, logError func(context.Context, string, ...map[string]interface{})) (*Store, error) {
+	if (len(c.CertFile) > 0) != (len(c.KeyFile) > 0) {
+		return nil, errors.New("tls.cert_file and tls.key_file must be both set")
+	}
+	if c.ReloadInterval <= 0 {
+		c.ReloadInterval = 10 * time.Second
+	}
+	s := &Store{Config: c, LogError: logError, pins: make(map[string]bool)}
+	var err error
+	if s.minVersion, err = Version(c.MinVersion); err != nil {
+		return nil, err
+	}
+	if s.cipherSuites, err = CipherSuites(c.CipherSuites); err != nil {
+		return nil, err
+	}
+	if s.clientAuth, err = ClientAuth(c.ClientAuth); err != nil {
+		return nil, err
+	}
+	if s.clientAuth >= tls.VerifyClientCertIfGiven && len(c.CAFiles) == 0 {
+		return nil, errors.New("tls.ca_files is required to verify client certificates")
+	}
+	for _, pin := range c.Pins {
+		if b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, PinPrefix)); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("tls.pins: %q is not a base64 SHA-256 digest", pin)
+		}
+		s.pins[strings.TrimPrefix(pin, PinPrefix)] = true
+	}
+	if err = s.Load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Load reads the certificate and the CA files again.
+func (s *Store) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+func (s *Store) load() error {
+	stamps := make(map[string]stamp)
+	for _, file := range s.Files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		stamps[file] = stamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	var cert *tls.Certificate
+	if len(s.Config.CertFile) > 0 {
+		c, err := tls.LoadX509KeyPair(s.Config.CertFile, s.Config.KeyFile)
+		if err != nil {
+			return err
+		}
+		cert = &c
+	}
+	var pool *x509.CertPool
+	if len(s.Config.CAFiles) > 0 {
+		pool = x509.NewCertPool()
+		for _, file := range s.Config.CAFiles {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return fmt.Errorf("tls: no certificate found in %s", file)
+			}
+		}
+	}
+	s.cert.Store(cert)
+	s.pool.Store(pool)
+	s.stamps = stamps
+	s.checked = time.Now()
+	return nil
+}
+
+// Files returns the files of the certificate, its key and the CA bundles.
+func (s *Store) Files() []string {
+	var files []string
+	if len(s.Config.CertFile) > 0 {
+		files = append(files, s.Config.CertFile, s.Config.KeyFile)
+	}
+	return append(files, s.Config.CAFiles...)
+}
+
+func (s *Store) Certificate() *tls.Certificate {
+	return s.cert.Load()
+}
+
+// Pool returns the pool of the CA files, nil for the system roots.
+func (s *Store) Pool() *x509.CertPool {
+	return s.pool.Load()
+}
+
+// refresh loads the files again if one of them has changed since the last check.
+func (s *Store) refresh(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.checked) < s.Config.ReloadInterval {
+		return
+	}
+	s.checked = now
+	changed := false
+	for _, file := range s.Files() {
+		info, err := os.Stat(file)
+		if err != nil || s.stamps[file] != (stamp{modTime: info.ModTime(), size: info.Size()}) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return
+	}
+	if err := s.load(); err != nil && s.LogError != nil {
+		s.LogError(ctx, "cannot reload the certificates, the previous ones are kept: "+err.Error())
+	}
+}
+
+// ClientConfig returns the config of a client, with the CA pool loaded last. It presents the certificate, if any,
+// and verifies the server against the CA files, or the system roots without CA files, then against the pins.
+// With Insecure, the chain is not verified, but the pins still are, on the certificate of the server.
+func (s *Store) ClientConfig() *tls.Config {
+	s.refresh(context.Background())
+	c := &tls.Config{
+		MinVersion:         s.minVersion,
+		CipherSuites:       s.cipherSuites,
+		ServerName:         s.Config.ServerName,
+		RootCAs:            s.pool.Load(),
+		InsecureSkipVerify: s.Config.Insecure,
+		NextProtos:         []string{"h2", "http/1.1"},
+		VerifyConnection:   s.verifyPeer,
+	}
+	if len(s.Config.CertFile) > 0 {
+		c.GetClientCertificate = func(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			s.refresh(info.Context())
+			return s.cert.Load(), nil
+		}
+	}
+	return c
+}
+
+// DialTLSContext dials addr with the ClientConfig of the moment, so that a change of the CA files applies to the next connections.
+// The server name is the one of the config, else the host of addr.
+func (s *Store) DialTLSContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	c := s.ClientConfig()
+	if len(c.ServerName) == 0 {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		c.ServerName = host
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	tc := tls.Client(conn, c)
+	if err = tc.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tc, nil
+}
+
+// verifyPeer checks the pins against the verified chains, or against the certificate of the peer when the chain has not been verified.
+func (s *Store) verifyPeer(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return nil
+	}
+	if len(cs.VerifiedChains) > 0 {
+		return s.checkPins(cs.VerifiedChains)
+	}
+	return s.checkPins([][]*x509.Certificate{cs.PeerCertificates[:1]})
+}
+
+// ServerConfig returns the config of a server presenting the certificate. Client certificates are asked for as ClientAuth says,
+// and verified against the CA files, then against the pins.
+func (s *Store) ServerConfig() *tls.Config {
+	c := &tls.Config{
+		MinVersion:   s.minVersion,
+		CipherSuites: s.cipherSuites,
+		ClientAuth:   s.clientAuth,
+		// The config returned by GetConfigForClient is used as is, so it must offer HTTP/2 itself.
+		NextProtos: []string{"h2", "http/1.1"},
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return s.cert.Load(), nil
+		},
+	}
+	if len(s.pins) > 0 {
+		c.VerifyConnection = s.verifyPeer
+	}
+	server := c.Clone()
+	server.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		s.refresh(hello.Context())
+		conf := c.Clone()
+		conf.ClientCAs = s.pool.Load()
+		return conf, nil
+	}
+	return server
+}
+
+// checkPins accepts the chains when there is no pin, or when a certificate of one of them has the SHA-256 of its public key in the pins.
+func (s *Store) checkPins(chains [][]*x509.Certificate) error {
+	if len(s.pins) == 0 {
+		return nil
+	}
+	for _, chain := range chains {
+		for _, cert := range chain {
+			if s.pins[strings.TrimPrefix(Pin(cert), PinPrefix)] {
+				return nil
+			}
+		}
+	}
+	return ErrPinMismatch
+}
+
+// Pin returns the pin of the certificate: sha256/ and the base64 SHA-256 of its public key.
+func Pin(cert *x509.Certificate) string {
+	digest := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return PinPrefix + base64.StdEncoding.EncodeToString(digest[:])
+}
+
+// Version parses 1.0 to 1.3. The default is 1.2.
+func Version(s string) (uint16, error) {
+	switch s {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	}
+	return 0, fmt.Errorf("tls.min_version %q must be 1.0, 1.1, 1.2 or 1.3", s)
+}
+
+// CipherSuites parses the names of Go, as TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. Only the secure suites are accepted.
+// The suites of TLS 1.3 are not configurable, so they only apply to TLS 1.2 and below. No name means the defaults of Go.
+func CipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	suites := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		suites[suite.Name] = suite.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := suites[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("tls.cipher_suites: %q is not a secure cipher suite", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func ClientAuth(s string) (tls.ClientAuthType, error) {
+	switch s {
+	case "", ClientAuthNone:
+		return tls.NoClientCert, nil
+	case ClientAuthRequest:
+		return tls.RequestClientCert, nil
+	case ClientAuthRequire:
+		return tls.RequireAnyClientCert, nil
+	case ClientAuthVerifyIfGiven:
+		return tls.VerifyClientCertIfGiven, nil
+	case ClientAuthRequireAndVerify:
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return 0, fmt.Errorf("tls.client_auth %q must be none, request, require, verify_if_given or require_and_verify", s)
+}
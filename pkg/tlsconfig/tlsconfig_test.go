@@ -0,0 +1,347 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newAuthority(t *testing.T, name string) *authority {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &authority{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM of a certificate for localhost and 127.0.0.1, valid for servers and clients, and of its key.
+func (a *authority) issue(t *testing.T, name string) (*x509.Certificate, []byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b})
+}
+
+// write writes the file, with a modification time later than the previous one, so that a change is seen whatever the precision of the file system.
+func write(t *testing.T, file string, data []byte) string {
+	t.Helper()
+	modTime := time.Now()
+	if info, err := os.Stat(file); err == nil {
+		modTime = info.ModTime().Add(time.Second)
+	}
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(file, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// serve starts a TLS server with the ServerConfig of the store, answering the common name of the client certificate, if any.
+func serve(t *testing.T, store *Store) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+		}
+	}))
+	server.TLS = store.ServerConfig()
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// get calls the server with a client dialing through the store, as the clients of pkg/client do.
+func get(store *Store, url string) error {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialTLSContext = store.DialTLSContext
+	transport.TLSClientConfig = store.ClientConfig()
+	defer transport.CloseIdleConnections()
+	res, err := (&http.Client{Transport: transport, Timeout: 5 * time.Second}).Get(url)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// peer returns the common name of the certificate the server presents.
+func peer(t *testing.T, store *Store, addr string) string {
+	t.Helper()
+	conn, err := store.DialTLSContext(context.Background(), "tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	return conn.(*tls.Conn).ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func newServerStore(t *testing.T, c Config, logError func(context.Context, string, ...map[string]interface{})) (*Store, *authority) {
+	t.Helper()
+	dir := t.TempDir()
+	ca := newAuthority(t, "server-ca")
+	_, certPEM, keyPEM := ca.issue(t, "server")
+	c.CertFile = write(t, filepath.Join(dir, "server.crt"), certPEM)
+	c.KeyFile = write(t, filepath.Join(dir, "server.key"), keyPEM)
+	store, err := NewStore(c, logError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, ca
+}
+
+func newClientStore(t *testing.T, c Config) *Store {
+	t.Helper()
+	store, err := NewStore(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestNewStore(t *testing.T) {
+	tests := map[string]Config{
+		"cert without key":           {CertFile: "server.crt"},
+		"key without cert":           {KeyFile: "server.key"},
+		"bad min version":            {MinVersion: "1.4"},
+		"insecure cipher suite":      {CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+		"bad client auth":            {ClientAuth: "always"},
+		"verify without ca files":    {ClientAuth: ClientAuthRequireAndVerify},
+		"verify if given without ca": {ClientAuth: ClientAuthVerifyIfGiven},
+		"pin not base64":             {Pins: []string{"sha256/not base64"}},
+		"pin not sha256":             {Pins: []string{"sha256/AAAA"}},
+		"missing cert file":          {CertFile: "missing.crt", KeyFile: "missing.key"},
+		"missing ca file":            {CAFiles: []string{"missing.pem"}},
+	}
+	for name, c := range tests {
+		if _, err := NewStore(c, nil); err == nil {
+			t.Errorf("%s: NewStore succeeded, want an error", name)
+		}
+	}
+	dir := t.TempDir()
+	if _, err := NewStore(Config{CAFiles: []string{write(t, filepath.Join(dir, "ca.pem"), []byte("no certificate"))}}, nil); err == nil {
+		t.Error("NewStore succeeded with a CA file without certificate")
+	}
+}
+
+func TestParse(t *testing.T) {
+	versions := map[string]uint16{"": tls.VersionTLS12, "1.0": tls.VersionTLS10, "1.1": tls.VersionTLS11, "1.2": tls.VersionTLS12, "1.3": tls.VersionTLS13}
+	for s, want := range versions {
+		if got, err := Version(s); err != nil || got != want {
+			t.Errorf("Version(%q) = %x, %v, want %x", s, got, err, want)
+		}
+	}
+	modes := map[string]tls.ClientAuthType{
+		"":                         tls.NoClientCert,
+		ClientAuthNone:             tls.NoClientCert,
+		ClientAuthRequest:          tls.RequestClientCert,
+		ClientAuthRequire:          tls.RequireAnyClientCert,
+		ClientAuthVerifyIfGiven:    tls.VerifyClientCertIfGiven,
+		ClientAuthRequireAndVerify: tls.RequireAndVerifyClientCert,
+	}
+	for s, want := range modes {
+		if got, err := ClientAuth(s); err != nil || got != want {
+			t.Errorf("ClientAuth(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	suites, err := CipherSuites([]string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", " TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384 "})
+	if err != nil || len(suites) != 2 || suites[0] != tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 || suites[1] != tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384 {
+		t.Errorf("CipherSuites = %v, %v", suites, err)
+	}
+	if suites, err := CipherSuites(nil); err != nil || suites != nil {
+		t.Errorf("CipherSuites(nil) = %v, %v, want the defaults", suites, err)
+	}
+}
+
+func TestPins(t *testing.T) {
+	server, ca := newServerStore(t, Config{}, nil)
+	s := serve(t, server)
+	leaf := server.Certificate().Leaf
+	if leaf == nil {
+		var err error
+		if leaf, err = x509.ParseCertificate(server.Certificate().Certificate[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	caFile := write(t, filepath.Join(t.TempDir(), "ca.pem"), ca.pem)
+	other := newAuthority(t, "other-ca")
+
+	tests := []struct {
+		name   string
+		config Config
+		want   error
+	}{
+		{"no pin", Config{CAFiles: []string{caFile}}, nil},
+		{"pin of the CA", Config{CAFiles: []string{caFile}, Pins: []string{Pin(ca.cert)}}, nil},
+		{"pin of the server", Config{CAFiles: []string{caFile}, Pins: []string{Pin(other.cert), Pin(leaf)}}, nil},
+		{"pin of another CA", Config{CAFiles: []string{caFile}, Pins: []string{Pin(other.cert)}}, ErrPinMismatch},
+		{"insecure, pin of the server", Config{Insecure: true, Pins: []string{Pin(leaf)}}, nil},
+		{"insecure, pin of another CA", Config{Insecure: true, Pins: []string{Pin(other.cert)}}, ErrPinMismatch},
+	}
+	for _, tt := range tests {
+		err := get(newClientStore(t, tt.config), s.URL)
+		if tt.want == nil && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	var unknown x509.UnknownAuthorityError
+	if err := get(newClientStore(t, Config{}), s.URL); !errors.As(err, &unknown) {
+		t.Errorf("system roots: err = %v, want an unknown authority", err)
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	clientCA := newAuthority(t, "client-ca")
+	dir := t.TempDir()
+	clientCAFile := write(t, filepath.Join(dir, "client-ca.pem"), clientCA.pem)
+	server, ca := newServerStore(t, Config{ClientAuth: ClientAuthRequireAndVerify, CAFiles: []string{clientCAFile}}, nil)
+	s := serve(t, server)
+	caFile := write(t, filepath.Join(dir, "ca.pem"), ca.pem)
+
+	client := func(a *authority, name string) *Store {
+		_, certPEM, keyPEM := a.issue(t, name)
+		return newClientStore(t, Config{
+			CAFiles:  []string{caFile},
+			CertFile: write(t, filepath.Join(dir, name+".crt"), certPEM),
+			KeyFile:  write(t, filepath.Join(dir, name+".key"), keyPEM),
+		})
+	}
+	if err := get(client(clientCA, "verified"), s.URL); err != nil {
+		t.Errorf("verified client: %v", err)
+	}
+	if err := get(client(newAuthority(t, "other-ca"), "unverified"), s.URL); err == nil {
+		t.Error("a client certificate of another CA was accepted")
+	}
+	if err := get(newClientStore(t, Config{CAFiles: []string{caFile}}), s.URL); err == nil {
+		t.Error("a client without certificate was accepted")
+	}
+}
+
+func TestReload(t *testing.T) {
+	var mu sync.Mutex
+	var logged []string
+	server, ca := newServerStore(t, Config{ReloadInterval: time.Nanosecond}, func(ctx context.Context, msg string, fields ...map[string]interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		logged = append(logged, msg)
+	})
+	s := serve(t, server)
+	addr := strings.TrimPrefix(s.URL, "https://")
+	caFile := write(t, filepath.Join(t.TempDir(), "ca.pem"), ca.pem)
+	client := newClientStore(t, Config{CAFiles: []string{caFile}, ReloadInterval: time.Nanosecond})
+	if got := peer(t, client, addr); got != "server" {
+		t.Fatalf("peer = %q, want server", got)
+	}
+
+	// A certificate of a new CA, swapped on both sides.
+	next := newAuthority(t, "next-ca")
+	_, certPEM, keyPEM := next.issue(t, "next")
+	write(t, server.Config.CertFile, certPEM)
+	write(t, server.Config.KeyFile, keyPEM)
+	write(t, caFile, next.pem)
+	if got := peer(t, client, addr); got != "next" {
+		t.Errorf("peer = %q after the swap, want next", got)
+	}
+
+	// A bad certificate keeps the previous one.
+	write(t, server.Config.CertFile, []byte("not a certificate"))
+	if got := peer(t, client, addr); got != "next" {
+		t.Errorf("peer = %q after a bad certificate, want next", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(logged) == 0 || !strings.HasPrefix(logged[0], "cannot reload the certificates") {
+		t.Errorf("logged = %q, want the reload error", logged)
+	}
+}
+
+func TestReloadInterval(t *testing.T) {
+	server, _ := newServerStore(t, Config{ReloadInterval: time.Hour}, nil)
+	before := server.Certificate()
+	_, certPEM, keyPEM := newAuthority(t, "next-ca").issue(t, "next")
+	write(t, server.Config.CertFile, certPEM)
+	write(t, server.Config.KeyFile, keyPEM)
+	server.refresh(context.Background())
+	if server.Certificate() != before {
+		t.Error("the certificate was loaded again before the reload interval")
+	}
+	server.checked = time.Now().Add(-time.Hour)
+	server.refresh(context.Background())
+	if server.Certificate() == before {
+		t.Error("the certificate was not loaded again after the reload interval")
+	}
+}
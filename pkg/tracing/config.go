@@ -0,0 +1,9 @@
+package tracing
+
+type Config struct {
+	Enabled     bool    `yaml:"enabled" mapstructure:"enabled" json:"enabled,omitempty" gorm:"column:enabled" bson:"enabled,omitempty" dynamodbav:"enabled,omitempty" firestore:"enabled,omitempty"`
+	Service     string  `yaml:"service" mapstructure:"service" json:"service,omitempty" gorm:"column:service" bson:"service,omitempty" dynamodbav:"service,omitempty" firestore:"service,omitempty"`
+	Exporter    string  `yaml:"exporter" mapstructure:"exporter" json:"exporter,omitempty" gorm:"column:exporter" bson:"exporter,omitempty" dynamodbav:"exporter,omitempty" firestore:"exporter,omitempty"`
+	File        string  `yaml:"file" mapstructure:"file" json:"file,omitempty" gorm:"column:file" bson:"file,omitempty" dynamodbav:"file,omitempty" firestore:"file,omitempty"`
+	SampleRatio float64 `yaml:"sample_ratio" mapstructure:"sample_ratio" json:"sampleRatio,omitempty" gorm:"column:sampleratio" bson:"sampleRatio,omitempty" dynamodbav:"sampleRatio,omitempty" firestore:"sampleRatio,omitempty"`
+}
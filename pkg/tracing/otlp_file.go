@@ -0,0 +1,247 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// OtlpFileExporter writes each batch of spans as an OTLP/JSON ExportTraceServiceRequest on one line, the format of the
+// OpenTelemetry file exporter, which the otlpjsonfile receiver of the collector reads.
+type OtlpFileExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewOtlpFileExporter(w io.Writer) *OtlpFileExporter {
+	return &OtlpFileExporter{w: w}
+}
+
+func (e *OtlpFileExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(exportRequest(spans))
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.w.Write(append(b, '\n'))
+	return err
+}
+func (e *OtlpFileExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// The types below follow the JSON mapping of the OTLP protobuf messages: the ids are hex, the 64 bit integers are strings
+// and the enums are numbers.
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	SchemaUrl  string           `json:"schemaUrl,omitempty"`
+}
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+type otlpScopeSpans struct {
+	Scope     otlpScope  `json:"scope"`
+	Spans     []otlpSpan `json:"spans"`
+	SchemaUrl string     `json:"schemaUrl,omitempty"`
+}
+type otlpScope struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+type otlpSpan struct {
+	TraceId                string         `json:"traceId"`
+	SpanId                 string         `json:"spanId"`
+	TraceState             string         `json:"traceState,omitempty"`
+	ParentSpanId           string         `json:"parentSpanId,omitempty"`
+	Flags                  uint32         `json:"flags,omitempty"`
+	Name                   string         `json:"name"`
+	Kind                   int            `json:"kind"`
+	StartTimeUnixNano      string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano        string         `json:"endTimeUnixNano"`
+	Attributes             []otlpKeyValue `json:"attributes,omitempty"`
+	DroppedAttributesCount int            `json:"droppedAttributesCount,omitempty"`
+	Events                 []otlpEvent    `json:"events,omitempty"`
+	DroppedEventsCount     int            `json:"droppedEventsCount,omitempty"`
+	Links                  []otlpLink     `json:"links,omitempty"`
+	DroppedLinksCount      int            `json:"droppedLinksCount,omitempty"`
+	Status                 otlpStatus     `json:"status"`
+}
+type otlpEvent struct {
+	TimeUnixNano           string         `json:"timeUnixNano"`
+	Name                   string         `json:"name"`
+	Attributes             []otlpKeyValue `json:"attributes,omitempty"`
+	DroppedAttributesCount int            `json:"droppedAttributesCount,omitempty"`
+}
+type otlpLink struct {
+	TraceId                string         `json:"traceId"`
+	SpanId                 string         `json:"spanId"`
+	TraceState             string         `json:"traceState,omitempty"`
+	Attributes             []otlpKeyValue `json:"attributes,omitempty"`
+	DroppedAttributesCount int            `json:"droppedAttributesCount,omitempty"`
+}
+type otlpStatus struct {
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code,omitempty"`
+}
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+type otlpAnyValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *string         `json:"intValue,omitempty"`
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+}
+type otlpArrayValue struct {
+	Values []otlpAnyValue `json:"values"`
+}
+
+// exportRequest groups the spans by resource and instrumentation scope, in the order they come.
+func exportRequest(spans []sdktrace.ReadOnlySpan) otlpRequest {
+	var req otlpRequest
+	resources := make(map[*resource.Resource]int)
+	scopes := make(map[*resource.Resource]map[instrumentation.Scope]int)
+	for _, span := range spans {
+		res := span.Resource()
+		i, ok := resources[res]
+		if !ok {
+			i = len(req.ResourceSpans)
+			resources[res] = i
+			scopes[res] = make(map[instrumentation.Scope]int)
+			rs := otlpResourceSpans{}
+			if res != nil {
+				rs.Resource.Attributes = keyValues(res.Attributes())
+				rs.SchemaUrl = res.SchemaURL()
+			}
+			req.ResourceSpans = append(req.ResourceSpans, rs)
+		}
+		scope := span.InstrumentationScope()
+		j, ok := scopes[res][scope]
+		if !ok {
+			j = len(req.ResourceSpans[i].ScopeSpans)
+			scopes[res][scope] = j
+			req.ResourceSpans[i].ScopeSpans = append(req.ResourceSpans[i].ScopeSpans, otlpScopeSpans{
+				Scope:     otlpScope{Name: scope.Name, Version: scope.Version},
+				SchemaUrl: scope.SchemaURL,
+			})
+		}
+		req.ResourceSpans[i].ScopeSpans[j].Spans = append(req.ResourceSpans[i].ScopeSpans[j].Spans, toSpan(span))
+	}
+	return req
+}
+
+func toSpan(span sdktrace.ReadOnlySpan) otlpSpan {
+	sc := span.SpanContext()
+	s := otlpSpan{
+		TraceId:                sc.TraceID().String(),
+		SpanId:                 sc.SpanID().String(),
+		TraceState:             sc.TraceState().String(),
+		Flags:                  uint32(sc.TraceFlags()),
+		Name:                   span.Name(),
+		Kind:                   int(span.SpanKind()),
+		StartTimeUnixNano:      strconv.FormatInt(span.StartTime().UnixNano(), 10),
+		EndTimeUnixNano:        strconv.FormatInt(span.EndTime().UnixNano(), 10),
+		Attributes:             keyValues(span.Attributes()),
+		DroppedAttributesCount: span.DroppedAttributes(),
+		DroppedEventsCount:     span.DroppedEvents(),
+		DroppedLinksCount:      span.DroppedLinks(),
+		Status:                 otlpStatus{Message: span.Status().Description},
+	}
+	if parent := span.Parent(); parent.HasSpanID() {
+		s.ParentSpanId = parent.SpanID().String()
+	}
+	// The codes of the API are Unset, Error and Ok, and those of OTLP are UNSET, OK and ERROR.
+	switch span.Status().Code {
+	case codes.Ok:
+		s.Status.Code = 1
+	case codes.Error:
+		s.Status.Code = 2
+	}
+	for _, event := range span.Events() {
+		s.Events = append(s.Events, otlpEvent{
+			TimeUnixNano:           strconv.FormatInt(event.Time.UnixNano(), 10),
+			Name:                   event.Name,
+			Attributes:             keyValues(event.Attributes),
+			DroppedAttributesCount: event.DroppedAttributeCount,
+		})
+	}
+	for _, link := range span.Links() {
+		s.Links = append(s.Links, otlpLink{
+			TraceId:                link.SpanContext.TraceID().String(),
+			SpanId:                 link.SpanContext.SpanID().String(),
+			TraceState:             link.SpanContext.TraceState().String(),
+			Attributes:             keyValues(link.Attributes),
+			DroppedAttributesCount: link.DroppedAttributeCount,
+		})
+	}
+	return s
+}
+
+func keyValues(attrs []attribute.KeyValue) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	kvs := make([]otlpKeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		kvs = append(kvs, otlpKeyValue{Key: string(kv.Key), Value: anyValue(kv.Value)})
+	}
+	return kvs
+}
+func anyValue(v attribute.Value) otlpAnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		b := v.AsBool()
+		return otlpAnyValue{BoolValue: &b}
+	case attribute.INT64:
+		i := strconv.FormatInt(v.AsInt64(), 10)
+		return otlpAnyValue{IntValue: &i}
+	case attribute.FLOAT64:
+		f := v.AsFloat64()
+		return otlpAnyValue{DoubleValue: &f}
+	case attribute.BOOLSLICE:
+		values := make([]otlpAnyValue, 0)
+		for _, b := range v.AsBoolSlice() {
+			values = append(values, anyValue(attribute.BoolValue(b)))
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	case attribute.INT64SLICE:
+		values := make([]otlpAnyValue, 0)
+		for _, i := range v.AsInt64Slice() {
+			values = append(values, anyValue(attribute.Int64Value(i)))
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	case attribute.FLOAT64SLICE:
+		values := make([]otlpAnyValue, 0)
+		for _, f := range v.AsFloat64Slice() {
+			values = append(values, anyValue(attribute.Float64Value(f)))
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	case attribute.STRINGSLICE:
+		values := make([]otlpAnyValue, 0)
+		for _, s := range v.AsStringSlice() {
+			values = append(values, anyValue(attribute.StringValue(s)))
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	default:
+		s := v.Emit()
+		return otlpAnyValue{StringValue: &s}
+	}
+}
@@ -0,0 +1,134 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestOtlpFileExporter(t *testing.T) {
+	var b bytes.Buffer
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(NewOtlpFileExporter(&b)),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "go-service"))),
+	)
+	tracer := provider.Tracer("go-service")
+	ctx, parent := tracer.Start(context.Background(), "GET /users/{id}", trace.WithSpanKind(trace.SpanKindServer))
+	_, child := tracer.Start(ctx, "UserAdapter.Load", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.statement", "select * from users where id = $1"),
+		attribute.Int64("db.rows", 1),
+		attribute.Bool("db.transaction", false),
+		attribute.Float64("ratio", 0.5),
+		attribute.StringSlice("tags", []string{"a", "b"}),
+	))
+	Error(child, errors.New("connection refused"))
+	child.End()
+	parent.SetStatus(codes.Ok, "")
+	parent.End()
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want one request per exported batch:\n%s", len(lines), b.String())
+	}
+	var req struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []map[string]interface{} `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Scope struct {
+					Name string `json:"name"`
+				} `json:"scope"`
+				Spans []map[string]interface{} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &req); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans[0].Spans) != 1 {
+		t.Fatalf("request = %s", lines[0])
+	}
+	rs := req.ResourceSpans[0]
+	if attr := rs.Resource.Attributes[0]; attr["key"] != "service.name" || attr["value"].(map[string]interface{})["stringValue"] != "go-service" {
+		t.Errorf("resource attributes = %v", rs.Resource.Attributes)
+	}
+	if rs.ScopeSpans[0].Scope.Name != "go-service" {
+		t.Errorf("scope = %v", rs.ScopeSpans[0].Scope)
+	}
+	span := rs.ScopeSpans[0].Spans[0]
+	sc := child.SpanContext()
+	want := map[string]interface{}{
+		"traceId":      sc.TraceID().String(),
+		"spanId":       sc.SpanID().String(),
+		"parentSpanId": parent.SpanContext().SpanID().String(),
+		"name":         "UserAdapter.Load",
+		"kind":         float64(3),
+		"flags":        float64(1),
+	}
+	for k, v := range want {
+		if span[k] != v {
+			t.Errorf("span.%s = %v, want %v", k, span[k], v)
+		}
+	}
+	if _, ok := span["startTimeUnixNano"].(string); !ok {
+		t.Errorf("startTimeUnixNano = %v, want a string", span["startTimeUnixNano"])
+	}
+	if status := span["status"].(map[string]interface{}); status["code"] != float64(2) || status["message"] != "connection refused" {
+		t.Errorf("status = %v, want the code of ERROR", status)
+	}
+	attrs, _ := json.Marshal(span["attributes"])
+	for _, s := range []string{
+		`{"key":"db.rows","value":{"intValue":"1"}}`,
+		`{"key":"db.transaction","value":{"boolValue":false}}`,
+		`{"key":"ratio","value":{"doubleValue":0.5}}`,
+		`{"key":"tags","value":{"arrayValue":{"values":[{"stringValue":"a"},{"stringValue":"b"}]}}}`,
+	} {
+		if !strings.Contains(string(attrs), s) {
+			t.Errorf("attributes = %s, want %s", attrs, s)
+		}
+	}
+	events, _ := json.Marshal(span["events"])
+	if !strings.Contains(string(events), `"name":"exception"`) || !strings.Contains(string(events), `"timeUnixNano":"`) {
+		t.Errorf("events = %s, want the recorded error", events)
+	}
+
+	var root struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []map[string]interface{} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	json.Unmarshal([]byte(lines[1]), &root)
+	span = root.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if _, ok := span["parentSpanId"]; ok || span["kind"] != float64(2) || span["status"].(map[string]interface{})["code"] != float64(1) {
+		t.Errorf("root span = %v, want a server span without parent and the code of OK", span)
+	}
+}
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := map[string]string{
+		"select * from users where id = 'ironman'":             "select * from users where id = ?",
+		"select * from users where id = 'o''neil' limit 10":    "select * from users where id = ? limit ?",
+		"select *\n  from users\twhere id = $1 and age > 3.5":  "select * from users where id = $1 and age > ?",
+		"select u.col1, t2.x from users u where u.id in (1,2)": "select u.col1, t2.x from users u where u.id in (?,?)",
+	}
+	for query, want := range tests {
+		if got := SanitizeSQL(query); got != want {
+			t.Errorf("SanitizeSQL(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	name           = "go-service"
+)
+
+func init() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Initialize installs the global tracer provider. The returned function flushes pending spans and closes the exporter.
+func Initialize(c Config) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+	switch c.Exporter {
+	case "", ExporterStdout:
+		stdout, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, err
+		}
+		exporter = stdout
+	case ExporterFile:
+		if len(c.File) == 0 {
+			return nil, errors.New("tracing.file is required for the file exporter")
+		}
+		f, err := os.OpenFile(c.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		exporter, file = NewOtlpFileExporter(f), f
+	default:
+		return nil, errors.New("unsupported tracing exporter: " + c.Exporter)
+	}
+	service := c.Service
+	if len(service) == 0 {
+		service = name
+	}
+	sampler := sdktrace.AlwaysSample()
+	if c.SampleRatio > 0 && c.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(c.SampleRatio)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", service))),
+	)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			if er := file.Close(); err == nil {
+				err = er
+			}
+		}
+		return err
+	}, nil
+}
+
+func Start(ctx context.Context, span string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(name).Start(ctx, span, trace.WithAttributes(attrs...))
+}
+
+func StartQuery(ctx context.Context, span string, query string) (context.Context, trace.Span) {
+	return otel.Tracer(name).Start(ctx, span, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.statement", SanitizeSQL(query)),
+	))
+}
+
+// Error records err on span and returns it, so it can wrap a return value.
+func Error(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+var (
+	literals = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numbers  = regexp.MustCompile(`([^$\w.])\d+(?:\.\d+)?\b`)
+	spaces   = regexp.MustCompile(`\s+`)
+)
+
+// SanitizeSQL replaces inline string and number literals with ? and collapses whitespace; bind parameters such as $1 are kept.
+func SanitizeSQL(query string) string {
+	query = literals.ReplaceAllString(query, "?")
+	query = numbers.ReplaceAllString(query, "${1}?")
+	return strings.TrimSpace(spaces.ReplaceAllString(query, " "))
+}
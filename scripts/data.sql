@@ -7,6 +7,20 @@ create table if not exists users (
   primary key (id)
 );
 
+create table if not exists api_keys (
+  id varchar(40) not null,
+  name varchar(100) not null,
+  prefix varchar(20) not null,
+  hash char(64) not null,
+  scopes varchar(400),
+  created_at timestamptz not null,
+  expires_at timestamptz,
+  revoked_at timestamptz,
+  last_used_at timestamptz,
+  primary key (id)
+);
+create unique index if not exists api_keys_hash on api_keys (hash);
+
 insert into users (id, username, email, phone, date_of_birth) values ('ironman', 'tony.stark', 'tony.stark@gmail.com', '0987654321', '1963-03-25');
 insert into users (id, username, email, phone, date_of_birth) values ('spiderman', 'peter.parker', 'peter.parker@gmail.com', '0987654321', '1962-08-25');
 insert into users (id, username, email, phone, date_of_birth) values ('wolverine', 'james.howlett', 'james.howlett@gmail.com', '0987654321', '1974-11-16');